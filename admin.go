@@ -0,0 +1,76 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"wb-service/internal/apierror"
+	"wb-service/internal/auth"
+	"wb-service/internal/ratelimit"
+	"wb-service/kafka"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerAdminRoutes добавляет служебные маршруты управления кэшем
+func (a *App) registerAdminRoutes(r *gin.RouterGroup) {
+	// Состояние кэша видит support, менять его может только admin
+	admin := r.Group("/admin/cache", a.require(auth.RoleSupport), a.rateLimit(ratelimit.GroupAdmin))
+	admin.GET("/stats", a.getCacheStats)
+	admin.DELETE("/:order_uid", a.require(auth.RoleAdmin), a.invalidateCacheEntry)
+	admin.POST("/clear", a.require(auth.RoleAdmin), a.clearCache)
+	admin.POST("/reload", a.require(auth.RoleAdmin), a.reloadCache)
+	admin.GET("/warmup", a.getCacheWarmup)
+}
+
+// getCacheStats возвращает статистику кэша
+func (a *App) getCacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, a.cache.Stats())
+}
+
+// invalidateCacheEntry удаляет один заказ из кэша
+func (a *App) invalidateCacheEntry(c *gin.Context) {
+	orderUID := c.Param("order_uid")
+
+	if !a.cache.Delete(orderUID) {
+		apierror.AbortWithDetails(c, http.StatusNotFound, apierror.CodeNotFound, "cache entry not found",
+			gin.H{"order_uid": orderUID})
+		return
+	}
+
+	log.Printf("Заказ %s удален из кэша", orderUID)
+	c.JSON(http.StatusOK, gin.H{"status": "invalidated", "order_uid": orderUID})
+}
+
+// clearCache полностью очищает кэш
+func (a *App) clearCache(c *gin.Context) {
+	a.cache.Clear()
+
+	log.Println("Кэш очищен")
+	c.JSON(http.StatusOK, gin.H{"status": "cleared"})
+}
+
+// reloadCache очищает кэш и заново загружает его из БД
+func (a *App) reloadCache(c *gin.Context) {
+	if a.repo == nil {
+		apierror.Abort(c, http.StatusInternalServerError, apierror.CodeDatabaseUnavailable, "database not initialized")
+		return
+	}
+
+	a.cache.Clear()
+	if err := kafka.LoadCacheFromDB(a.cache, a.repo); err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, apierror.CodeInternal, "cache reload failed")
+		return
+	}
+
+	c.JSON(http.StatusOK, a.cache.Stats())
+}
+
+// getCacheWarmup возвращает состояние фонового прогрева кэша
+func (a *App) getCacheWarmup(c *gin.Context) {
+	if a.warmer == nil {
+		apierror.Abort(c, http.StatusNotFound, apierror.CodeNotFound, "cache warmup not started")
+		return
+	}
+
+	c.JSON(http.StatusOK, a.warmer.Progress())
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wb-service/internal/cache"
+	"wb-service/internal/interfaces"
+)
+
+func TestAdminCacheStats(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t, testConfig(), nil)
+	router := app.router
+
+	order := createTestOrderForCache()
+	app.cache.Set(order.OrderUID, order)
+	app.cache.Get(order.OrderUID)
+	app.cache.Get("missing")
+
+	req, _ := http.NewRequest("GET", "/admin/cache/stats", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var stats interfaces.CacheStats
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("Failed to unmarshal stats: %v", err)
+	}
+
+	if stats.Size != 1 || stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestAdminInvalidateCacheEntry(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t, testConfig(), nil)
+	router := app.router
+
+	order := createTestOrderForCache()
+	app.cache.Set(order.OrderUID, order)
+
+	t.Run("invalidate existing entry", func(t *testing.T) {
+		req, _ := http.NewRequest("DELETE", "/admin/cache/"+order.OrderUID, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+
+		if _, found := app.cache.Get(order.OrderUID); found {
+			t.Error("Expected order to be removed from cache")
+		}
+	})
+
+	t.Run("invalidate missing entry", func(t *testing.T) {
+		req, _ := http.NewRequest("DELETE", "/admin/cache/"+order.OrderUID, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+}
+
+func TestAdminClearCache(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t, testConfig(), nil)
+	router := app.router
+
+	order := createTestOrderForCache()
+	app.cache.Set(order.OrderUID, order)
+
+	req, _ := http.NewRequest("POST", "/admin/cache/clear", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	if app.cache.Size() != 0 {
+		t.Errorf("Expected empty cache after clear, got size %d", app.cache.Size())
+	}
+}
+
+func TestAdminReloadCache(t *testing.T) {
+	t.Parallel()
+	db := setupTestDatabase(t)
+	app := newTestApp(t, testConfig(), db)
+	router := app.router
+
+	order := createTestOrderForDB()
+	if err := db.Create(order).Error; err != nil {
+		t.Fatalf("Failed to create test order: %v", err)
+	}
+
+	// Запись, которой нет в БД, должна исчезнуть после перезагрузки
+	stale := createTestOrderForCache()
+	app.cache.Set(stale.OrderUID, stale)
+
+	req, _ := http.NewRequest("POST", "/admin/cache/reload", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Response: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var stats interfaces.CacheStats
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("Failed to unmarshal stats: %v", err)
+	}
+
+	if stats.LoadedEntries != 1 {
+		t.Errorf("Expected 1 loaded entry, got %d", stats.LoadedEntries)
+	}
+
+	if _, found := app.cache.Get(order.OrderUID); !found {
+		t.Error("Expected DB order to be in cache after reload")
+	}
+
+	if _, found := app.cache.Get(stale.OrderUID); found {
+		t.Error("Expected stale entry to be dropped by reload")
+	}
+}
+
+func TestAdminCacheWarmup(t *testing.T) {
+	t.Parallel()
+	db := setupTestDatabase(t)
+	app := newTestApp(t, testConfig(), db)
+	router := app.router
+
+	t.Run("warmup not started", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/admin/cache/warmup", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+
+	t.Run("warmup progress", func(t *testing.T) {
+		order := createTestOrderForDB()
+		if err := db.Create(order).Error; err != nil {
+			t.Fatalf("Failed to create test order: %v", err)
+		}
+
+		warmer := cache.NewWarmer(app.cache, app.repo, cache.WarmupOptions{})
+		if err := warmer.Run(context.Background()); err != nil {
+			t.Fatalf("Warmup failed: %v", err)
+		}
+		app.warmer = warmer
+
+		req, _ := http.NewRequest("GET", "/admin/cache/warmup", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+
+		var progress cache.WarmupProgress
+		if err := json.Unmarshal(w.Body.Bytes(), &progress); err != nil {
+			t.Fatalf("Failed to unmarshal progress: %v", err)
+		}
+
+		if progress.State != cache.WarmupDone || progress.Loaded != 1 {
+			t.Errorf("Unexpected progress: %+v", progress)
+		}
+	})
+}
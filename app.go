@@ -0,0 +1,410 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"wb-service/config"
+	"wb-service/database"
+	"wb-service/internal/apierror"
+	"wb-service/internal/auth"
+	"wb-service/internal/cache"
+	"wb-service/internal/interfaces"
+	"wb-service/internal/openapi"
+	"wb-service/internal/pii"
+	"wb-service/internal/ratelimit"
+	"wb-service/internal/repository"
+	"wb-service/internal/validator"
+	"wb-service/kafka"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Реализации репозитория (DB_REPOSITORY)
+const (
+	repositoryGorm = "gorm"
+	repositoryPgx  = "pgx"
+)
+
+// apiV1 - префикс маршрутов первой версии API
+const apiV1 = "/api/v1"
+
+// legacyDeprecatedAt - с какого момента маршруты без версии устарели
+var legacyDeprecatedAt = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+
+// App связывает компоненты сервиса: конфигурацию, БД, кэш, валидатор,
+// Kafka Consumer и HTTP сервер. Глобального состояния нет, поэтому
+// в одном процессе может работать несколько экземпляров (например, в тестах).
+type App struct {
+	cfg       *config.Config
+	db        *gorm.DB
+	repo      interfaces.Database
+	cache     interfaces.Cache
+	validator interfaces.OrderValidator
+	consumer  interfaces.MessageConsumer
+	policies  *pii.Policies
+	authn     *auth.Authenticator   // nil - аутентификация отключена
+	limits    *ratelimit.Middleware // nil - ограничение частоты отключено
+	router    *gin.Engine
+
+	warmer    *cache.Warmer
+	server    *http.Server
+	listener  net.Listener
+	cancel    context.CancelFunc
+	retention <-chan struct{}
+}
+
+// NewApp создает приложение поверх подключенной базы данных. db может быть
+// nil: тогда заказы из Kafka только попадают в кэш и отдаются из него. Кэш
+// создается по конфигурации, фоновые задачи и сервер не запускаются до
+// вызова Start.
+func NewApp(cfg *config.Config, db *gorm.DB) (*App, error) {
+	policies, err := pii.NewPolicies(cfg.PII)
+	if err != nil {
+		return nil, err
+	}
+
+	var authn *auth.Authenticator
+	if cfg.Auth.Enabled {
+		if authn, err = auth.New(cfg.Auth); err != nil {
+			return nil, err
+		}
+	} else {
+		log.Println("Аутентификация HTTP API отключена")
+	}
+
+	// Без доверенных прокси IP клиента берется из адреса соединения, иначе
+	// X-Forwarded-For позволил бы обойти ограничение частоты запросов
+	router := gin.Default()
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		return nil, err
+	}
+
+	a := &App{
+		cfg:       cfg,
+		db:        db,
+		cache:     kafka.NewCache(cfg),
+		validator: validator.NewOrderValidator(),
+		policies:  policies,
+		authn:     authn,
+	}
+	if db != nil {
+		repo, err := newRepository(cfg, db)
+		if err != nil {
+			a.cache.Close()
+			return nil, err
+		}
+		a.repo = repo
+	}
+
+	consumer, err := kafka.NewConsumer(cfg.Kafka, a.repo, a.cache, a.validator)
+	if err != nil {
+		if a.repo != nil {
+			a.repo.Close()
+		}
+		a.cache.Close()
+		return nil, err
+	}
+	a.consumer = consumer
+
+	if cfg.RateLimit.Enabled {
+		a.limits = newRateLimiter(cfg)
+	}
+
+	a.router = router
+	a.registerRoutes(a.router)
+
+	return a, nil
+}
+
+// newRepository создает репозиторий, выбранный в DB_REPOSITORY. pgx читает
+// заказ одним запросом, но работает только с PostgreSQL; для остальных баз
+// используется GORM. Если заданы реплики, чтение распределяется по ним.
+func newRepository(cfg *config.Config, db *gorm.DB) (interfaces.Database, error) {
+	primary, err := newPrimaryRepository(cfg, db)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cfg.Database.ReplicaDSNs) == 0 {
+		return primary, nil
+	}
+	if db.Dialector.Name() != database.DriverPostgres {
+		log.Println("Реплики поддерживаются только для PostgreSQL, чтение идет с основной базы")
+		return primary, nil
+	}
+
+	replicas, err := openReplicas(cfg, primary)
+	if err != nil {
+		primary.Close()
+		return nil, err
+	}
+	log.Printf("Чтение заказов распределяется по %d репликам", len(replicas))
+
+	return repository.NewReplicatedDatabase(context.Background(), primary, replicas, repository.ReplicaOptions{
+		CheckInterval:  time.Duration(cfg.Database.ReplicaCheckInterval) * time.Second,
+		ReadAfterWrite: time.Duration(cfg.Database.ReadAfterWrite) * time.Second,
+	}), nil
+}
+
+// newPrimaryRepository создает репозиторий основной базы
+func newPrimaryRepository(cfg *config.Config, db *gorm.DB) (interfaces.Database, error) {
+	switch strings.ToLower(cfg.Database.Repository) {
+	case repositoryPgx:
+		if db.Dialector.Name() != database.DriverPostgres {
+			log.Println("Репозиторий pgx работает только с PostgreSQL, используется GORM")
+			break
+		}
+		repo, err := repository.OpenPgx(context.Background(), cfg)
+		if err != nil {
+			return nil, err
+		}
+		log.Println("Заказы читаются через pgx")
+		return repo, nil
+	case repositoryGorm, "":
+	default:
+		log.Printf("Неизвестный репозиторий %q, используется GORM", cfg.Database.Repository)
+	}
+	return repository.NewGormDatabase(db), nil
+}
+
+// openReplicas подключается к репликам из DB_REPLICA_DSNS той же
+// реализацией репозитория, что и основная база
+func openReplicas(cfg *config.Config, primary interfaces.Database) ([]interfaces.Database, error) {
+	var replicas []interfaces.Database
+	closeAll := func() {
+		for _, replica := range replicas {
+			replica.Close()
+		}
+	}
+
+	for _, dsn := range cfg.Database.ReplicaDSNs {
+		var replica interfaces.Database
+		if _, ok := primary.(*repository.PgxDatabase); ok {
+			repo, err := repository.OpenPgxReplica(context.Background(), dsn, cfg.Database)
+			if err != nil {
+				closeAll()
+				return nil, fmt.Errorf("open replica: %w", err)
+			}
+			replica = repo
+		} else {
+			db, err := database.OpenReplica(cfg, dsn)
+			if err != nil {
+				closeAll()
+				return nil, fmt.Errorf("open replica: %w", err)
+			}
+			replica = repository.NewGormDatabase(db)
+		}
+		replicas = append(replicas, replica)
+	}
+	return replicas, nil
+}
+
+// registerRoutes добавляет маршруты сервиса. Маршруты API версионируются
+// префиксом /api/v1; прежние пути без версии остаются устаревшими
+// псевдонимами. Заказы доступны роли viewer (веб-интерфейс), служебные
+// маршруты - support и admin, health check, спецификация API и
+// веб-страницы - без аутентификации.
+func (a *App) registerRoutes(r *gin.Engine) {
+	// Идентификатор запроса попадает в ответ и в описание ошибки
+	r.Use(apierror.RequestID())
+
+	// Добавляем маршруты первой версии API
+	a.registerAPIRoutes(r.Group(apiV1), "/orders/:order_uid")
+
+	// Добавляем прежние маршруты без версии
+	a.registerAPIRoutes(r.Group("", deprecated()), "/order/:order_uid")
+
+	// Добавляем health check endpoint
+	r.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+
+	// Добавляем спецификацию OpenAPI и Swagger UI
+	openapi.Register(r)
+
+	// Добавляем маршрут для отдачи нашей веб-страницы
+	r.StaticFile("/", "./web/index.html")
+
+	r.NoRoute(func(c *gin.Context) {
+		apierror.Abort(c, http.StatusNotFound, apierror.CodeRouteNotFound, "route not found")
+	})
+}
+
+// registerAPIRoutes добавляет маршруты API в группу. orderPath - путь
+// получения заказа: в /api/v1 он во множественном числе, как и
+// остальные маршруты заказов.
+func (a *App) registerAPIRoutes(g *gin.RouterGroup, orderPath string) {
+	// Ограничиваем запросы с одного IP до проверки учетных данных
+	g.Use(a.authRateLimit())
+
+	// Добавляем маршрут для получения заказа
+	g.GET(orderPath, a.require(auth.RoleViewer), a.rateLimit(ratelimit.GroupOrders), a.getOrder)
+
+	// Добавляем служебные маршруты управления кэшем
+	a.registerAdminRoutes(g)
+
+	// Добавляем маршруты удаления заказов и обезличивания данных
+	a.registerPrivacyRoutes(g)
+
+	// Добавляем статистику ограничителя запросов
+	a.registerRateLimitRoutes(g)
+}
+
+// deprecated отмечает устаревшие маршруты без версии: добавляет заголовки
+// Deprecation (RFC 9745) и Link на маршрут /api/v1 и отдает ошибки в
+// прежнем виде {"error": "сообщение"}, чтобы старые клиенты продолжали работать
+func deprecated() gin.HandlerFunc {
+	deprecation := "@" + strconv.FormatInt(legacyDeprecatedAt.Unix(), 10)
+	return func(c *gin.Context) {
+		c.Set(apierror.LegacyContextKey, true)
+		c.Header("Deprecation", deprecation)
+		c.Header("Link", "<"+successorPath(c.Request.URL.EscapedPath())+`>; rel="successor-version"`)
+		c.Next()
+	}
+}
+
+// successorPath возвращает путь /api/v1, заменяющий устаревший путь
+func successorPath(path string) string {
+	if uid, ok := strings.CutPrefix(path, "/order/"); ok {
+		return apiV1 + "/orders/" + uid
+	}
+	return apiV1 + path
+}
+
+// require возвращает middleware проверки роли клиента. Если аутентификация
+// отключена (AUTH_ENABLED=false), пропускает все запросы.
+func (a *App) require(role auth.Role) gin.HandlerFunc {
+	if a.authn == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return a.authn.Require(role)
+}
+
+// Start запускает компоненты по порядку: восстановление или прогрев кэша,
+// периодические снимки, очистку по сроку хранения, Kafka Consumer и HTTP
+// сервер. Не блокирует вызывающего; остановка - через Stop.
+func (a *App) Start(ctx context.Context) error {
+	ctx, a.cancel = context.WithCancel(ctx)
+
+	// Восстанавливаем кэш из снимка, а если его нет или он устарел,
+	// прогреваем кэш из БД в фоне, не задерживая запуск HTTP сервера
+	if !kafka.RestoreCacheSnapshot(a.cache, a.cfg) && a.repo != nil {
+		a.warmer = kafka.StartCacheWarmup(ctx, a.cache, a.repo, a.cfg)
+	}
+
+	// Периодически сохраняем снимок кэша
+	kafka.StartCacheSnapshots(ctx, a.cache, a.cfg)
+
+	// Периодически удаляем заказы старше срока хранения
+	if a.repo != nil {
+		a.retention = a.startRetention(ctx)
+	}
+
+	if err := a.consumer.Start(ctx); err != nil {
+		a.cancel()
+		return err
+	}
+
+	// Порт занимаем сразу, чтобы ошибка вернулась вызывающему
+	addr := a.cfg.Server.Host + ":" + a.cfg.Server.Port
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		a.consumer.Stop()
+		a.cancel()
+		return err
+	}
+	a.listener = listener
+	a.server = &http.Server{Handler: a.router}
+
+	go func() {
+		log.Printf("Сервер запускается на %s", listener.Addr())
+		if err := a.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Ошибка HTTP сервера: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+// Addr возвращает адрес, на котором слушает HTTP сервер, или пустую
+// строку, если сервер не запущен
+func (a *App) Addr() string {
+	if a.listener == nil {
+		return ""
+	}
+	return a.listener.Addr().String()
+}
+
+// Stop останавливает компоненты в порядке, обратном запуску: HTTP сервер
+// (дожидаясь текущих запросов, пока не отменен ctx), Kafka Consumer,
+// фоновые задачи кэша и очистку заказов. Затем сохраняет снимок кэша
+// и горячие ключи, закрывает кэш и соединение с БД. Можно вызывать и без Start.
+func (a *App) Stop(ctx context.Context) error {
+	var errs []error
+
+	if a.server != nil {
+		if err := a.server.Shutdown(ctx); err != nil {
+			log.Printf("Ошибка graceful shutdown сервера: %v", err)
+			errs = append(errs, err)
+		} else {
+			log.Println("HTTP сервер успешно остановлен")
+		}
+	}
+
+	if err := a.consumer.Stop(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if a.cancel != nil {
+		a.cancel()
+	}
+
+	// Дожидаемся текущей очистки, чтобы не закрыть БД посреди транзакции
+	if a.retention != nil {
+		<-a.retention
+	}
+
+	// Сохраняем снимок кэша и горячие ключи для быстрого запуска
+	kafka.SaveCacheSnapshot(a.cache, a.cfg)
+	kafka.SaveHotKeys(a.cache, a.cfg)
+
+	// Останавливаем фоновую очистку кэша
+	if err := a.cache.Close(); err != nil {
+		log.Printf("Ошибка при закрытии кэша: %v", err)
+		errs = append(errs, err)
+	} else {
+		log.Println("Кэш остановлен")
+	}
+
+	if a.limits != nil {
+		if err := a.limits.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// Закрываем репозиторий (у pgx собственный пул) и соединение с базой данных
+	if a.repo != nil {
+		if err := a.repo.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if a.db != nil {
+		if sqlDB, err := a.db.DB(); err == nil {
+			if err := sqlDB.Close(); err != nil {
+				errs = append(errs, err)
+			} else {
+				log.Println("Соединение с базой данных закрыто")
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"wb-service/config"
+	"wb-service/internal/apierror"
+	"wb-service/internal/openapi"
+	"wb-service/internal/repository"
+	"wb-service/kafka"
+
+	kafkago "github.com/segmentio/kafka-go"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// lifecycleConfig возвращает конфигурацию для запуска приложения
+// на свободном порту без доступной Kafka
+func lifecycleConfig(t *testing.T) *config.Config {
+	cfg := testConfig()
+	cfg.Server = config.ServerConfig{Host: "127.0.0.1", Port: "0"}
+	cfg.Kafka = config.KafkaConfig{Brokers: []string{"127.0.0.1:1"}, Topic: "orders", GroupID: "test"}
+	cfg.Cache.SnapshotFile = filepath.Join(t.TempDir(), "cache.snapshot")
+	return cfg
+}
+
+func TestAppStartStop(t *testing.T) {
+	// Экземпляры не делят состояние и могут работать одновременно
+	for _, name := range []string{"first", "second"} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			db := setupTestDatabase(t)
+			order := createTestOrderForDB()
+			order.OrderUID = name + "_order"
+			if err := db.Create(order).Error; err != nil {
+				t.Fatalf("Failed to create test order: %v", err)
+			}
+
+			cfg := lifecycleConfig(t)
+			app, err := NewApp(cfg, db)
+			if err != nil {
+				t.Fatalf("NewApp failed: %v", err)
+			}
+
+			if err := app.Start(context.Background()); err != nil {
+				t.Fatalf("Start failed: %v", err)
+			}
+
+			resp, err := http.Get("http://" + app.Addr() + "/order/" + order.OrderUID)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("Expected status %d, got %d", http.StatusOK, resp.StatusCode)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := app.Stop(ctx); err != nil {
+				t.Fatalf("Stop failed: %v", err)
+			}
+
+			// При остановке сохраняется снимок кэша, а сервер больше не принимает запросы
+			if _, err := os.Stat(cfg.Cache.SnapshotFile); err != nil {
+				t.Errorf("Expected cache snapshot to be saved: %v", err)
+			}
+			if _, err := http.Get("http://" + app.Addr() + "/health"); err == nil {
+				t.Error("Expected server to be stopped")
+			}
+		})
+	}
+}
+
+func TestAppStartPortInUse(t *testing.T) {
+	first := newTestApp(t, lifecycleConfig(t), nil)
+	if err := first.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	cfg := lifecycleConfig(t)
+	host, port, _ := strings.Cut(first.Addr(), ":")
+	cfg.Server = config.ServerConfig{Host: host, Port: port}
+
+	second := newTestApp(t, cfg, nil)
+	if err := second.Start(context.Background()); err == nil {
+		t.Error("Expected Start to fail when the port is in use")
+	}
+}
+
+func TestNewRepository(t *testing.T) {
+	tests := []struct {
+		repository string
+		expected   string
+	}{
+		{"gorm", "*repository.GormDatabase"},
+		{"", "*repository.GormDatabase"},
+		// pgx работает только с PostgreSQL, для SQLite используется GORM
+		{"pgx", "*repository.GormDatabase"},
+		{"unknown", "*repository.GormDatabase"},
+	}
+
+	for _, tt := range tests {
+		cfg := testConfig()
+		cfg.Database.Repository = tt.repository
+
+		repo, err := newRepository(cfg, setupTestDatabase(t))
+		if err != nil {
+			t.Fatalf("Repository %q: unexpected error %v", tt.repository, err)
+		}
+		if got := fmt.Sprintf("%T", repo); got != tt.expected {
+			t.Errorf("Repository %q: expected %s, got %s", tt.repository, tt.expected, got)
+		}
+	}
+}
+
+func TestNewRepositoryReplicas(t *testing.T) {
+	cfg := testConfig()
+	cfg.Database.ReplicaDSNs = []string{"host=127.0.0.1 port=1 user=test dbname=test sslmode=disable"}
+
+	// Для SQLite реплики игнорируются
+	repo, err := newRepository(cfg, setupTestDatabase(t))
+	if err != nil {
+		t.Fatalf("newRepository failed: %v", err)
+	}
+	if _, ok := repo.(*repository.GormDatabase); !ok {
+		t.Errorf("Expected GORM repository for SQLite, got %T", repo)
+	}
+
+	// Диалект PostgreSQL поверх готового соединения, без подключения к серверу
+	conn, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open connection: %v", err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+
+	// Недоступная при запуске реплика не мешает созданию репозитория
+	repo, err = newRepository(cfg, db)
+	if err != nil {
+		t.Fatalf("newRepository failed: %v", err)
+	}
+	defer repo.Close()
+
+	if _, ok := repo.(*repository.ReplicatedDatabase); !ok {
+		t.Errorf("Expected replicated repository, got %T", repo)
+	}
+}
+
+func TestRouteAccess(t *testing.T) {
+	cfg := testConfig()
+	cfg.Auth = config.AuthConfig{
+		Enabled:       true,
+		APIKeys:       []string{"viewer-key=viewer", "support-key=support", "admin-key=admin"},
+		AnonymousRole: "viewer",
+	}
+	app := newTestApp(t, cfg, nil)
+
+	// allowed - запрос прошел проверку роли (ответ обработчика не важен)
+	const allowed = 0
+	tests := []struct {
+		method   string
+		path     string
+		apiKey   string
+		expected int
+	}{
+		{"GET", "/health", "", allowed},
+		{"GET", "/order/missing", "", allowed},
+		{"GET", "/order/missing", "viewer-key", allowed},
+		{"GET", "/order/missing", "wrong-key", http.StatusUnauthorized},
+		{"GET", "/admin/cache/stats", "", http.StatusForbidden},
+		{"GET", "/admin/cache/stats", "viewer-key", http.StatusForbidden},
+		{"GET", "/admin/cache/stats", "support-key", allowed},
+		{"POST", "/admin/cache/clear", "support-key", http.StatusForbidden},
+		{"POST", "/admin/cache/clear", "admin-key", allowed},
+		{"DELETE", "/orders/missing", "viewer-key", http.StatusForbidden},
+		{"DELETE", "/orders/missing", "support-key", allowed},
+		{"POST", "/admin/customers/c1/erase", "support-key", http.StatusForbidden},
+		{"POST", "/admin/customers/c1/erase", "admin-key", allowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path+" "+tt.apiKey, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.path, nil)
+			if tt.apiKey != "" {
+				req.Header.Set("X-API-Key", tt.apiKey)
+			}
+			w := httptest.NewRecorder()
+			app.router.ServeHTTP(w, req)
+
+			denied := w.Code == http.StatusUnauthorized || w.Code == http.StatusForbidden
+			if tt.expected == allowed && denied {
+				t.Errorf("Expected access, got %d: %s", w.Code, w.Body.String())
+			}
+			if tt.expected != allowed && w.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+
+	t.Run("v1 error envelope", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/api/v1/admin/cache/clear", nil)
+		req.Header.Set("X-API-Key", "support-key")
+		w := httptest.NewRecorder()
+		app.router.ServeHTTP(w, req)
+
+		var envelope apierror.Envelope
+		if err := json.Unmarshal(w.Body.Bytes(), &envelope); err != nil {
+			t.Fatalf("Failed to unmarshal envelope: %v", err)
+		}
+		if w.Code != http.StatusForbidden || envelope.Error.Code != apierror.CodeForbidden {
+			t.Errorf("Expected forbidden envelope, got %d %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("closed without anonymous role", func(t *testing.T) {
+		cfg := testConfig()
+		cfg.Auth = config.AuthConfig{Enabled: true}
+		app := newTestApp(t, cfg, nil)
+
+		req, _ := http.NewRequest("GET", "/order/missing", nil)
+		w := httptest.NewRecorder()
+		app.router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401, got %d", w.Code)
+		}
+	})
+}
+
+func TestNewAppInvalidAuthConfig(t *testing.T) {
+	cfg := testConfig()
+	cfg.Auth = config.AuthConfig{Enabled: true, APIKeys: []string{"key=owner"}}
+
+	if _, err := NewApp(cfg, nil); err == nil {
+		t.Error("Expected NewApp to fail for unknown role")
+	}
+}
+
+func TestNewAppWithoutDatabaseCachesMessages(t *testing.T) {
+	app := newTestApp(t, testConfig(), nil)
+
+	order := createTestOrderForDB()
+	data, err := json.Marshal(order)
+	if err != nil {
+		t.Fatalf("Failed to marshal order: %v", err)
+	}
+
+	consumer := app.consumer.(*kafka.Consumer)
+	if !consumer.Handle(context.Background(), kafkago.Message{Value: data}) {
+		t.Fatal("Expected message to be committed")
+	}
+	if _, found := app.cache.Get(order.OrderUID); !found {
+		t.Error("Expected order in cache")
+	}
+}
+
+func TestAPIVersioning(t *testing.T) {
+	db := setupTestDatabase(t)
+	app := newTestApp(t, testConfig(), db)
+
+	order := createTestOrderForDB()
+	if err := db.Create(order).Error; err != nil {
+		t.Fatalf("Failed to create test order in DB: %v", err)
+	}
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		w := httptest.NewRecorder()
+		app.router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("v1 order", func(t *testing.T) {
+		w := serve("GET", "/api/v1/orders/"+order.OrderUID)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+		if w.Header().Get("Deprecation") != "" {
+			t.Error("Expected no Deprecation header on /api/v1")
+		}
+		if w.Header().Get(apierror.RequestIDHeader) == "" {
+			t.Error("Expected request ID header")
+		}
+	})
+
+	t.Run("v1 error envelope", func(t *testing.T) {
+		w := serve("GET", "/api/v1/orders/missing")
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("Expected status 404, got %d", w.Code)
+		}
+
+		var envelope apierror.Envelope
+		if err := json.Unmarshal(w.Body.Bytes(), &envelope); err != nil {
+			t.Fatalf("Failed to unmarshal envelope: %v", err)
+		}
+		if envelope.Error.Code != apierror.CodeNotFound || envelope.Error.Message != "record not found" {
+			t.Errorf("Unexpected error: %+v", envelope.Error)
+		}
+		if envelope.Error.RequestID == "" || envelope.Error.RequestID != w.Header().Get(apierror.RequestIDHeader) {
+			t.Errorf("Expected request ID %q in envelope, got %q", w.Header().Get(apierror.RequestIDHeader), envelope.Error.RequestID)
+		}
+	})
+
+	t.Run("legacy aliases", func(t *testing.T) {
+		tests := []struct {
+			method    string
+			path      string
+			successor string
+		}{
+			{"GET", "/order/" + order.OrderUID, "/api/v1/orders/" + order.OrderUID},
+			{"GET", "/admin/cache/stats", "/api/v1/admin/cache/stats"},
+			{"GET", "/admin/ratelimit/stats", "/api/v1/admin/ratelimit/stats"},
+		}
+
+		for _, tt := range tests {
+			w := serve(tt.method, tt.path)
+			if w.Code != http.StatusOK {
+				t.Errorf("%s: expected status 200, got %d", tt.path, w.Code)
+			}
+			if !strings.HasPrefix(w.Header().Get("Deprecation"), "@") {
+				t.Errorf("%s: expected Deprecation header, got %q", tt.path, w.Header().Get("Deprecation"))
+			}
+			if link := w.Header().Get("Link"); link != "<"+tt.successor+`>; rel="successor-version"` {
+				t.Errorf("%s: unexpected Link header %q", tt.path, link)
+			}
+		}
+	})
+
+	t.Run("legacy error format", func(t *testing.T) {
+		w := serve("GET", "/order/missing")
+		if w.Body.String() != `{"error":"record not found"}` {
+			t.Errorf("Expected legacy error body, got %s", w.Body.String())
+		}
+	})
+
+	t.Run("unknown route", func(t *testing.T) {
+		w := serve("GET", "/api/v2/orders/"+order.OrderUID)
+
+		var envelope apierror.Envelope
+		if err := json.Unmarshal(w.Body.Bytes(), &envelope); err != nil {
+			t.Fatalf("Failed to unmarshal envelope: %v", err)
+		}
+		if w.Code != http.StatusNotFound || envelope.Error.Code != apierror.CodeRouteNotFound {
+			t.Errorf("Expected route_not_found, got %d %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("health not deprecated", func(t *testing.T) {
+		if w := serve("GET", "/health"); w.Header().Get("Deprecation") != "" {
+			t.Error("Expected no Deprecation header on /health")
+		}
+	})
+}
+
+// TestOpenAPIRoutes сверяет маршруты gin со спецификацией OpenAPI: новый
+// маршрут без описания или описание удаленного маршрута ломают тест
+func TestOpenAPIRoutes(t *testing.T) {
+	app := newTestApp(t, testConfig(), nil)
+
+	// Веб-страница и файлы Swagger UI не входят в API
+	skip := map[string]bool{
+		"GET /": true,
+		"GET " + openapi.SwaggerPath + "/*filepath": true,
+	}
+
+	routes := make(map[string]bool)
+	for _, route := range app.router.Routes() {
+		key := route.Method + " " + route.Path
+		if route.Method == http.MethodHead || skip[key] {
+			continue
+		}
+		routes[route.Method+" "+openAPIPath(route.Path)] = true
+	}
+
+	ops, err := openapi.Operations()
+	if err != nil {
+		t.Fatalf("Failed to read spec operations: %v", err)
+	}
+
+	documented := make(map[string]bool, len(ops))
+	for _, op := range ops {
+		documented[op.String()] = true
+		if !routes[op.String()] {
+			t.Errorf("Spec describes %s, but no such route is registered", op)
+		}
+	}
+	for route := range routes {
+		if !documented[route] {
+			t.Errorf("Route %s is missing from the spec", route)
+		}
+	}
+}
+
+// openAPIPath переводит параметры пути gin (:order_uid) в нотацию
+// OpenAPI ({order_uid})
+func openAPIPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") {
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
@@ -10,6 +9,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"wb-service/internal/codec"
+	"wb-service/internal/interfaces"
 	"wb-service/models"
 
 	"github.com/segmentio/kafka-go"
@@ -196,17 +197,39 @@ func (g *FakeDataGenerator) generateStatus() int {
 	return statuses[g.rand.Intn(len(statuses))]
 }
 
+// buildMessage сериализует заказ в выбранном формате и проставляет content-type
+func buildMessage(order *models.Order, c interfaces.OrderCodec) (kafka.Message, error) {
+	value, err := c.Encode(order)
+	if err != nil {
+		return kafka.Message{}, err
+	}
+
+	return kafka.Message{
+		Key:   []byte(order.OrderUID),
+		Value: value,
+		Headers: []kafka.Header{
+			{Key: codec.HeaderContentType, Value: []byte(c.ContentType())},
+		},
+	}, nil
+}
+
 func main() {
 	var (
 		brokers = flag.String("brokers", "localhost:9092", "Kafka brokers")
 		topic   = flag.String("topic", "orders", "Kafka topic")
 		count   = flag.Int("count", 10, "Number of orders to generate")
 		delay   = flag.Duration("delay", time.Second, "Delay between messages")
+		format  = flag.String("format", codec.FormatJSON, "Message format: json, protobuf, avro")
 	)
 	flag.Parse()
 
+	orderCodec, err := codec.New(*format)
+	if err != nil {
+		log.Fatalf("Некорректный формат сообщений: %v", err)
+	}
+
 	log.Printf("Запуск генератора данных...")
-	log.Printf("Brokers: %s, Topic: %s, Count: %d, Delay: %s", *brokers, *topic, *count, *delay)
+	log.Printf("Brokers: %s, Topic: %s, Count: %d, Delay: %s, Format: %s", *brokers, *topic, *count, *delay, *format)
 
 	// Создаем Kafka writer
 	w := &kafka.Writer{
@@ -228,20 +251,15 @@ func main() {
 		// Генерируем заказ
 		order := generator.GenerateOrder()
 
-		// Сериализуем в JSON
-		orderJSON, err := json.Marshal(order)
+		// Сериализуем в выбранном формате
+		msg, err := buildMessage(order, orderCodec)
 		if err != nil {
 			log.Printf("Ошибка сериализации заказа: %v", err)
 			continue
 		}
 
 		// Отправляем в Kafka
-		err = w.WriteMessages(context.Background(),
-			kafka.Message{
-				Key:   []byte(order.OrderUID),
-				Value: orderJSON,
-			},
-		)
+		err = w.WriteMessages(context.Background(), msg)
 
 		if err != nil {
 			log.Printf("Ошибка отправки заказа: %v", err)
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"testing"
 	"time"
+	"wb-service/internal/codec"
 	"wb-service/models"
 )
 
@@ -399,6 +400,46 @@ func TestFakeDataGenerator_JSONSerialization(t *testing.T) {
 	})
 }
 
+func TestBuildMessage(t *testing.T) {
+	generator := NewFakeDataGenerator()
+
+	for _, format := range []string{codec.FormatJSON, codec.FormatProtobuf, codec.FormatAvro} {
+		t.Run(format, func(t *testing.T) {
+			orderCodec, err := codec.New(format)
+			if err != nil {
+				t.Fatalf("Failed to create codec: %v", err)
+			}
+
+			order := generator.GenerateOrder()
+			msg, err := buildMessage(order, orderCodec)
+			if err != nil {
+				t.Fatalf("Failed to build message: %v", err)
+			}
+
+			if string(msg.Key) != order.OrderUID {
+				t.Errorf("Expected key %s, got %s", order.OrderUID, msg.Key)
+			}
+
+			if len(msg.Headers) != 1 || string(msg.Headers[0].Value) != orderCodec.ContentType() {
+				t.Errorf("Expected content-type header %s, got %v", orderCodec.ContentType(), msg.Headers)
+			}
+
+			decoded, err := orderCodec.Decode(msg.Value)
+			if err != nil {
+				t.Fatalf("Failed to decode message: %v", err)
+			}
+
+			if decoded.OrderUID != order.OrderUID {
+				t.Errorf("Round-trip failed for OrderUID")
+			}
+
+			if len(decoded.Items) != len(order.Items) {
+				t.Errorf("Round-trip failed for Items count")
+			}
+		})
+	}
+}
+
 func TestNewFakeDataGenerator(t *testing.T) {
 	t.Run("create new generator", func(t *testing.T) {
 		generator := NewFakeDataGenerator()
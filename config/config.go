@@ -3,77 +3,261 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 )
 
 type Config struct {
-	Database DatabaseConfig
-	Kafka    KafkaConfig
-	Server   ServerConfig
-	Cache    CacheConfig
+	Database  DatabaseConfig
+	Kafka     KafkaConfig
+	Server    ServerConfig
+	Cache     CacheConfig
+	Redis     RedisConfig
+	PII       PIIConfig
+	Auth      AuthConfig
+	RateLimit RateLimitConfig
 }
 
 type DatabaseConfig struct {
+	Driver     string // postgres или sqlite
+	SQLitePath string // файл базы SQLite, ":memory:" - база в памяти процесса
+	Repository string // реализация репозитория: gorm или pgx (только PostgreSQL)
+
 	Host     string
 	Port     string
 	User     string
 	Password string
 	DBName   string
 	SSLMode  string
+
+	ReplicaDSNs          []string // DSN реплик PostgreSQL для чтения, пустой список - читать с primary
+	ReplicaCheckInterval int      // период проверки доступности реплик в секундах
+	ReadAfterWrite       int      // сколько секунд после записи читать заказ с primary, 0 - не читать
+
+	AutoMigrate bool // применять миграции при запуске, иначе только проверять версию схемы
+
+	RetentionDays      int // сколько дней хранить заказы, 0 - хранить бессрочно
+	RetentionInterval  int // период очистки устаревших заказов в секундах
+	RetentionBatchSize int // сколько заказов удалять за одну транзакцию
+
+	ConnectTimeout   int    // сколько секунд повторять подключение при запуске, 0 - одна попытка
+	MaxOpenConns     int    // максимум открытых соединений, 0 - без ограничения
+	MaxIdleConns     int    // максимум простаивающих соединений
+	ConnMaxLifetime  int    // время жизни соединения в секундах, 0 - без ограничения
+	StatementTimeout int    // таймаут одного запроса в секундах, 0 - без ограничения
+	LogLevel         string // уровень логирования GORM: silent, error, warn, info
 }
 
 type KafkaConfig struct {
-	Brokers   []string
-	Topic     string
-	GroupID   string
-	MinBytes  int
-	MaxBytes  int
+	Brokers       []string
+	Topic         string
+	GroupID       string
+	MinBytes      int
+	MaxBytes      int
+	MessageFormat string // формат сообщений без заголовка content-type: json, protobuf, avro
 }
 
 type ServerConfig struct {
 	Port string
 	Host string
+
+	TrustedProxies []string // адреса и сети прокси, которым доверяется X-Forwarded-For
+	OrderMaxAge    int      // сколько секунд клиент может не перепроверять заказ, 0 - проверять ETag всегда
 }
 
+// CacheConfig задает лимиты кэша: по количеству заказов (MaxSize)
+// и/или по оценочному объему памяти (MaxBytes). 0 отключает лимит.
 type CacheConfig struct {
-	MaxSize int
-	TTL     int // в секундах
+	Backend  string // memory - кэш в памяти, redis - только Redis, tiered - память перед Redis
+	MaxSize  int
+	MaxBytes int64
+	TTL      int    // в секундах
+	Shards   int    // количество шардов, 1 - обычный LRU с одной блокировкой
+	Policy   string // политика вытеснения: lru, lfu, arc или tinylfu
+
+	Expiration      string // режим истечения: absolute - от записи, sliding - продлевается чтением
+	CleanupInterval int    // средний период фоновой очистки в секундах, 0 - TTL/2
+
+	NegativeTTL int // сколько секунд помнить ненайденные заказы, 0 - не помнить
+
+	WarmupLimit    int    // сколько свежих заказов прогревать, 0 - до заполнения кэша
+	WarmupPageSize int    // размер страницы при чтении заказов для прогрева
+	HotKeysFile    string // файл горячих ключей, пустая строка - не сохранять
+
+	SnapshotFile     string // файл снимка кэша, пустая строка - снимки отключены
+	SnapshotInterval int    // период сохранения снимка в секундах, 0 - только при остановке
+	SnapshotMaxAge   int    // максимальный возраст снимка при запуске в секундах, 0 - без ограничения
+}
+
+// RedisConfig задает подключение к Redis для CACHE_BACKEND=redis/tiered
+type RedisConfig struct {
+	Addr      string
+	Password  string
+	DB        int
+	KeyPrefix string // префикс ключей заказов
+	Channel   string // канал pub/sub для инвалидации локальных кэшей
+	Format    string // формат сериализации заказов: json, protobuf, avro
+}
+
+// PIIConfig задает политики маскирования персональных данных в ответах API:
+// plain - без изменений, partial - частично, redacted - полностью
+type PIIConfig struct {
+	DefaultPolicy string   // политика для запросов без роли и API ключа
+	RolePolicies  []string // пары роль=политика
+	KeyPolicies   []string // пары API ключ=политика, важнее политики роли
+}
+
+// AuthConfig задает аутентификацию HTTP API: статические API ключи и JWT,
+// подписи которых проверяются ключами из локального JWKS файла
+type AuthConfig struct {
+	Enabled       bool
+	APIKeys       []string // пары API ключ=роль
+	JWKSFile      string   // пустой - JWT не принимаются
+	JWTIssuer     string   // ожидаемый iss, пустой - не проверяется
+	JWTAudience   string   // ожидаемый aud, пустой - не проверяется
+	RoleClaim     string   // claim с ролью: строка или список строк
+	AnonymousRole string   // роль запросов без учетных данных, пустая - доступ закрыт
+}
+
+// RateLimitConfig задает ограничение частоты запросов к HTTP API
+// по алгоритму token bucket для каждого клиента
+type RateLimitConfig struct {
+	Enabled   bool
+	Backend   string // хранилище корзин: memory или redis (общее для реплик)
+	KeyPrefix string // префикс ключей корзин в Redis
+
+	Orders RateLimit // чтение заказов
+	Admin  RateLimit // служебные маршруты, удаление и обезличивание
+	Auth   RateLimit // все запросы к API с одного IP до проверки учетных данных
+}
+
+// RateLimit - лимит группы маршрутов
+type RateLimit struct {
+	PerMinute int // запросов в минуту, 0 - без ограничения
+	Burst     int // сколько запросов можно сделать подряд
 }
 
 func Load() *Config {
 	return &Config{
 		Database: DatabaseConfig{
+			Driver:     getEnv("DB_DRIVER", "postgres"),
+			SQLitePath: getEnv("DB_SQLITE_PATH", "wb-service.db"),
+			Repository: getEnv("DB_REPOSITORY", "gorm"),
+
 			Host:     getEnv("DB_HOST", "127.0.0.1"),
 			Port:     getEnv("DB_PORT", "5434"),
 			User:     getEnv("DB_USER", "wb_user"),
 			Password: getEnv("DB_PASSWORD", "wb_password"),
 			DBName:   getEnv("DB_NAME", "wb_db"),
 			SSLMode:  getEnv("DB_SSL_MODE", "disable"),
+
+			ReplicaDSNs:          getEnvAsList("DB_REPLICA_DSNS", nil),
+			ReplicaCheckInterval: getEnvAsInt("DB_REPLICA_CHECK_INTERVAL", 5),
+			ReadAfterWrite:       getEnvAsInt("DB_READ_AFTER_WRITE", 0),
+
+			AutoMigrate: getEnvAsBool("DB_AUTO_MIGRATE", false),
+
+			RetentionDays:      getEnvAsInt("DB_RETENTION_DAYS", 0),
+			RetentionInterval:  getEnvAsInt("DB_RETENTION_INTERVAL", 3600), // 1 час
+			RetentionBatchSize: getEnvAsInt("DB_RETENTION_BATCH_SIZE", 1000),
+
+			ConnectTimeout:   getEnvAsInt("DB_CONNECT_TIMEOUT", 30),
+			MaxOpenConns:     getEnvAsInt("DB_MAX_OPEN_CONNS", 25),
+			MaxIdleConns:     getEnvAsInt("DB_MAX_IDLE_CONNS", 5),
+			ConnMaxLifetime:  getEnvAsInt("DB_CONN_MAX_LIFETIME", 300), // 5 минут
+			StatementTimeout: getEnvAsInt("DB_STATEMENT_TIMEOUT", 30),
+			LogLevel:         getEnv("DB_LOG_LEVEL", "warn"),
 		},
 		Kafka: KafkaConfig{
-			Brokers:  []string{getEnv("KAFKA_BROKERS", "localhost:9092")},
-			Topic:    getEnv("KAFKA_TOPIC", "orders"),
-			GroupID:  getEnv("KAFKA_GROUP_ID", "order-group"),
-			MinBytes: getEnvAsInt("KAFKA_MIN_BYTES", 10000),    // 10KB
-			MaxBytes: getEnvAsInt("KAFKA_MAX_BYTES", 10000000), // 10MB
+			Brokers:       []string{getEnv("KAFKA_BROKERS", "localhost:9092")},
+			Topic:         getEnv("KAFKA_TOPIC", "orders"),
+			GroupID:       getEnv("KAFKA_GROUP_ID", "order-group"),
+			MinBytes:      getEnvAsInt("KAFKA_MIN_BYTES", 10000),    // 10KB
+			MaxBytes:      getEnvAsInt("KAFKA_MAX_BYTES", 10000000), // 10MB
+			MessageFormat: getEnv("KAFKA_MESSAGE_FORMAT", "json"),
 		},
 		Server: ServerConfig{
 			Port: getEnv("SERVER_PORT", "8080"),
 			Host: getEnv("SERVER_HOST", ""),
+
+			TrustedProxies: getEnvAsList("SERVER_TRUSTED_PROXIES", nil),
+			OrderMaxAge:    getEnvAsInt("SERVER_ORDER_MAX_AGE", 60),
 		},
 		Cache: CacheConfig{
-			MaxSize: getEnvAsInt("CACHE_MAX_SIZE", 1000),
-			TTL:     getEnvAsInt("CACHE_TTL", 3600), // 1 час
+			Backend:  getEnv("CACHE_BACKEND", "memory"),
+			MaxSize:  getEnvAsInt("CACHE_MAX_SIZE", 1000),
+			MaxBytes: getEnvAsInt64("CACHE_MAX_BYTES", 0),
+			TTL:      getEnvAsInt("CACHE_TTL", 3600), // 1 час
+			Shards:   getEnvAsInt("CACHE_SHARDS", 1),
+			Policy:   getEnv("CACHE_POLICY", "lru"),
+
+			Expiration:      getEnv("CACHE_EXPIRATION", "absolute"),
+			CleanupInterval: getEnvAsInt("CACHE_CLEANUP_INTERVAL", 0),
+
+			NegativeTTL: getEnvAsInt("CACHE_NEGATIVE_TTL", 30),
+
+			WarmupLimit:    getEnvAsInt("CACHE_WARMUP_LIMIT", 0),
+			WarmupPageSize: getEnvAsInt("CACHE_WARMUP_PAGE_SIZE", 500),
+			HotKeysFile:    getEnv("CACHE_HOT_KEYS_FILE", ""),
+
+			SnapshotFile:     getEnv("CACHE_SNAPSHOT_FILE", ""),
+			SnapshotInterval: getEnvAsInt("CACHE_SNAPSHOT_INTERVAL", 300), // 5 минут
+			SnapshotMaxAge:   getEnvAsInt("CACHE_SNAPSHOT_MAX_AGE", 3600), // 1 час
+		},
+		Redis: RedisConfig{
+			Addr:      getEnv("REDIS_ADDR", "localhost:6379"),
+			Password:  getEnv("REDIS_PASSWORD", ""),
+			DB:        getEnvAsInt("REDIS_DB", 0),
+			KeyPrefix: getEnv("REDIS_KEY_PREFIX", "order:"),
+			Channel:   getEnv("REDIS_CHANNEL", "wb-service:cache:invalidate"),
+			Format:    getEnv("REDIS_FORMAT", "json"),
+		},
+		PII: PIIConfig{
+			DefaultPolicy: getEnv("PII_DEFAULT_POLICY", "partial"),
+			RolePolicies:  getEnvAsList("PII_ROLE_POLICIES", []string{"admin=plain", "support=partial", "viewer=partial"}),
+			KeyPolicies:   getEnvAsList("PII_KEY_POLICIES", nil),
+		},
+		Auth: AuthConfig{
+			Enabled:       getEnvAsBool("AUTH_ENABLED", true),
+			APIKeys:       getEnvAsList("AUTH_API_KEYS", nil),
+			JWKSFile:      getEnv("AUTH_JWKS_FILE", ""),
+			JWTIssuer:     getEnv("AUTH_JWT_ISSUER", ""),
+			JWTAudience:   getEnv("AUTH_JWT_AUDIENCE", ""),
+			RoleClaim:     getEnv("AUTH_JWT_ROLE_CLAIM", "role"),
+			AnonymousRole: getEnv("AUTH_ANONYMOUS_ROLE", "viewer"),
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:   getEnvAsBool("RATE_LIMIT_ENABLED", true),
+			Backend:   getEnv("RATE_LIMIT_BACKEND", "memory"),
+			KeyPrefix: getEnv("RATE_LIMIT_KEY_PREFIX", "ratelimit:"),
+			Orders: RateLimit{
+				PerMinute: getEnvAsInt("RATE_LIMIT_ORDERS_PER_MINUTE", 300),
+				Burst:     getEnvAsInt("RATE_LIMIT_ORDERS_BURST", 30),
+			},
+			Admin: RateLimit{
+				PerMinute: getEnvAsInt("RATE_LIMIT_ADMIN_PER_MINUTE", 60),
+				Burst:     getEnvAsInt("RATE_LIMIT_ADMIN_BURST", 10),
+			},
+			Auth: RateLimit{
+				PerMinute: getEnvAsInt("RATE_LIMIT_AUTH_PER_MINUTE", 600),
+				Burst:     getEnvAsInt("RATE_LIMIT_AUTH_BURST", 60),
+			},
 		},
 	}
 }
 
 func (c *Config) DatabaseDSN() string {
-	return "host=" + c.Database.Host +
+	dsn := "host=" + c.Database.Host +
 		" user=" + c.Database.User +
 		" password=" + c.Database.Password +
 		" dbname=" + c.Database.DBName +
 		" port=" + c.Database.Port +
 		" sslmode=" + c.Database.SSLMode
+
+	// Таймаут запроса передается серверу как параметр сессии, в миллисекундах
+	if c.Database.StatementTimeout > 0 {
+		dsn += " statement_timeout=" + strconv.Itoa(c.Database.StatementTimeout*1000)
+	}
+	return dsn
 }
 
 func getEnv(key, defaultVal string) string {
@@ -89,4 +273,36 @@ func getEnvAsInt(key string, defaultVal int) int {
 		return value
 	}
 	return defaultVal
-}
\ No newline at end of file
+}
+
+func getEnvAsBool(key string, defaultVal bool) bool {
+	valueStr := getEnv(key, "")
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	return defaultVal
+}
+
+func getEnvAsInt64(key string, defaultVal int64) int64 {
+	valueStr := getEnv(key, "")
+	if value, err := strconv.ParseInt(valueStr, 10, 64); err == nil {
+		return value
+	}
+	return defaultVal
+}
+
+// getEnvAsList разбирает список значений, разделенных запятыми.
+// Пустые элементы пропускаются.
+func getEnvAsList(key string, defaultVal []string) []string {
+	if getEnv(key, "") == "" {
+		return defaultVal
+	}
+
+	var values []string
+	for _, value := range strings.Split(getEnv(key, ""), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
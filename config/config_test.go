@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"testing"
 )
@@ -21,6 +22,10 @@ func TestLoad(t *testing.T) {
 		t.Errorf("Expected default server port 8080, got %s", cfg.Server.Port)
 	}
 
+	if cfg.Server.OrderMaxAge != 60 || cfg.Server.TrustedProxies != nil {
+		t.Errorf("Unexpected server defaults: %+v", cfg.Server)
+	}
+
 	if cfg.Cache.MaxSize != 1000 {
 		t.Errorf("Expected default cache max size 1000, got %d", cfg.Cache.MaxSize)
 	}
@@ -28,6 +33,84 @@ func TestLoad(t *testing.T) {
 	if cfg.Cache.TTL != 3600 {
 		t.Errorf("Expected default cache TTL 3600, got %d", cfg.Cache.TTL)
 	}
+
+	if cfg.Cache.MaxBytes != 0 {
+		t.Errorf("Expected byte budget disabled by default, got %d", cfg.Cache.MaxBytes)
+	}
+
+	if cfg.Cache.Shards != 1 {
+		t.Errorf("Expected 1 cache shard by default, got %d", cfg.Cache.Shards)
+	}
+
+	if cfg.Cache.Policy != "lru" {
+		t.Errorf("Expected lru cache policy by default, got %s", cfg.Cache.Policy)
+	}
+
+	if cfg.Cache.Backend != "memory" {
+		t.Errorf("Expected memory cache backend by default, got %s", cfg.Cache.Backend)
+	}
+
+	if cfg.Redis.Addr != "localhost:6379" || cfg.Redis.KeyPrefix != "order:" || cfg.Redis.Format != "json" {
+		t.Errorf("Unexpected Redis defaults: %+v", cfg.Redis)
+	}
+
+	if cfg.Cache.Expiration != "absolute" || cfg.Cache.CleanupInterval != 0 {
+		t.Errorf("Unexpected expiration defaults: %+v", cfg.Cache)
+	}
+
+	if cfg.Cache.NegativeTTL != 30 {
+		t.Errorf("Expected negative TTL 30 by default, got %d", cfg.Cache.NegativeTTL)
+	}
+
+	if cfg.Cache.WarmupLimit != 0 || cfg.Cache.WarmupPageSize != 500 || cfg.Cache.HotKeysFile != "" {
+		t.Errorf("Unexpected warmup defaults: %+v", cfg.Cache)
+	}
+
+	if cfg.Cache.SnapshotFile != "" || cfg.Cache.SnapshotInterval != 300 || cfg.Cache.SnapshotMaxAge != 3600 {
+		t.Errorf("Unexpected snapshot defaults: %+v", cfg.Cache)
+	}
+
+	if cfg.Database.AutoMigrate {
+		t.Error("Expected auto migrate disabled by default")
+	}
+
+	if cfg.Database.Driver != "postgres" || cfg.Database.SQLitePath != "wb-service.db" || cfg.Database.Repository != "gorm" {
+		t.Errorf("Unexpected database driver defaults: %+v", cfg.Database)
+	}
+
+	if cfg.Database.ReplicaDSNs != nil || cfg.Database.ReplicaCheckInterval != 5 || cfg.Database.ReadAfterWrite != 0 {
+		t.Errorf("Unexpected replica defaults: %+v", cfg.Database)
+	}
+
+	if cfg.PII.DefaultPolicy != "partial" || len(cfg.PII.RolePolicies) != 3 || cfg.PII.RolePolicies[2] != "viewer=partial" ||
+		cfg.PII.KeyPolicies != nil {
+		t.Errorf("Unexpected PII defaults: %+v", cfg.PII)
+	}
+
+	if auth := cfg.Auth; !auth.Enabled || auth.APIKeys != nil || auth.JWKSFile != "" ||
+		auth.RoleClaim != "role" || auth.AnonymousRole != "viewer" {
+		t.Errorf("Unexpected auth defaults: %+v", auth)
+	}
+
+	if rl := cfg.RateLimit; !rl.Enabled || rl.Backend != "memory" || rl.Orders.PerMinute != 300 ||
+		rl.Orders.Burst != 30 || rl.Admin.PerMinute != 60 || rl.Admin.Burst != 10 ||
+		rl.Auth.PerMinute != 600 || rl.Auth.Burst != 60 {
+		t.Errorf("Unexpected rate limit defaults: %+v", rl)
+	}
+
+	if db := cfg.Database; db.RetentionDays != 0 || db.RetentionInterval != 3600 || db.RetentionBatchSize != 1000 {
+		t.Errorf("Unexpected retention defaults: %+v", db)
+	}
+
+	db := cfg.Database
+	if db.ConnectTimeout != 30 || db.MaxOpenConns != 25 || db.MaxIdleConns != 5 ||
+		db.ConnMaxLifetime != 300 || db.StatementTimeout != 30 || db.LogLevel != "warn" {
+		t.Errorf("Unexpected database pool defaults: %+v", db)
+	}
+
+	if cfg.Kafka.MessageFormat != "json" {
+		t.Errorf("Expected default message format json, got %s", cfg.Kafka.MessageFormat)
+	}
 }
 
 func TestLoadWithEnvironmentVariables(t *testing.T) {
@@ -37,6 +120,13 @@ func TestLoadWithEnvironmentVariables(t *testing.T) {
 	os.Setenv("SERVER_PORT", "9000")
 	os.Setenv("CACHE_MAX_SIZE", "500")
 	os.Setenv("CACHE_TTL", "1800")
+	os.Setenv("CACHE_MAX_BYTES", "67108864")
+	os.Setenv("CACHE_POLICY", "tinylfu")
+	os.Setenv("DB_AUTO_MIGRATE", "true")
+	os.Setenv("CACHE_EXPIRATION", "sliding")
+	os.Setenv("CACHE_CLEANUP_INTERVAL", "45")
+	os.Setenv("DB_DRIVER", "sqlite")
+	os.Setenv("DB_SQLITE_PATH", ":memory:")
 
 	defer func() {
 		// Clean up environment variables
@@ -45,6 +135,13 @@ func TestLoadWithEnvironmentVariables(t *testing.T) {
 		os.Unsetenv("SERVER_PORT")
 		os.Unsetenv("CACHE_MAX_SIZE")
 		os.Unsetenv("CACHE_TTL")
+		os.Unsetenv("CACHE_MAX_BYTES")
+		os.Unsetenv("CACHE_POLICY")
+		os.Unsetenv("DB_AUTO_MIGRATE")
+		os.Unsetenv("CACHE_EXPIRATION")
+		os.Unsetenv("CACHE_CLEANUP_INTERVAL")
+		os.Unsetenv("DB_DRIVER")
+		os.Unsetenv("DB_SQLITE_PATH")
 	}()
 
 	cfg := Load()
@@ -68,6 +165,30 @@ func TestLoadWithEnvironmentVariables(t *testing.T) {
 	if cfg.Cache.TTL != 1800 {
 		t.Errorf("Expected cache TTL 1800, got %d", cfg.Cache.TTL)
 	}
+
+	if cfg.Cache.MaxBytes != 67108864 {
+		t.Errorf("Expected cache max bytes 67108864, got %d", cfg.Cache.MaxBytes)
+	}
+
+	if cfg.Cache.Policy != "tinylfu" {
+		t.Errorf("Expected cache policy tinylfu, got %s", cfg.Cache.Policy)
+	}
+
+	if !cfg.Database.AutoMigrate {
+		t.Error("Expected auto migrate enabled")
+	}
+
+	if cfg.Cache.Expiration != "sliding" {
+		t.Errorf("Expected cache expiration sliding, got %s", cfg.Cache.Expiration)
+	}
+
+	if cfg.Cache.CleanupInterval != 45 {
+		t.Errorf("Expected cache cleanup interval 45, got %d", cfg.Cache.CleanupInterval)
+	}
+
+	if cfg.Database.Driver != "sqlite" || cfg.Database.SQLitePath != ":memory:" {
+		t.Errorf("Expected sqlite in memory, got %s %s", cfg.Database.Driver, cfg.Database.SQLitePath)
+	}
 }
 
 func TestDatabaseDSN(t *testing.T) {
@@ -86,6 +207,12 @@ func TestDatabaseDSN(t *testing.T) {
 	if dsn := cfg.DatabaseDSN(); dsn != expected {
 		t.Errorf("Expected DSN %s, got %s", expected, dsn)
 	}
+
+	cfg.Database.StatementTimeout = 5
+	expected += " statement_timeout=5000"
+	if dsn := cfg.DatabaseDSN(); dsn != expected {
+		t.Errorf("Expected DSN %s, got %s", expected, dsn)
+	}
 }
 
 func TestGetEnvAsInt(t *testing.T) {
@@ -142,4 +269,28 @@ func TestGetEnv(t *testing.T) {
 			}
 		})
 	}
+}
+
+func TestGetEnvAsList(t *testing.T) {
+	tests := []struct {
+		envValue    string
+		expected    []string
+		description string
+	}{
+		{"", nil, "empty environment variable should return nil"},
+		{"host=a", []string{"host=a"}, "single value"},
+		{"host=a port=1, host=b ,,", []string{"host=a port=1", "host=b"}, "values are trimmed and empty ones skipped"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			os.Setenv("TEST_VAR", tt.envValue)
+			defer os.Unsetenv("TEST_VAR")
+
+			result := getEnvAsList("TEST_VAR", nil)
+			if fmt.Sprint(result) != fmt.Sprint(tt.expected) || len(result) != len(tt.expected) {
+				t.Errorf("Expected %q, got %q for env value '%s'", tt.expected, result, tt.envValue)
+			}
+		})
+	}
 }
\ No newline at end of file
@@ -1,25 +1,195 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"strings"
+	"time"
 	"wb-service/config"
 
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
-var DB *gorm.DB
+// Поддерживаемые драйверы базы данных (DB_DRIVER). Совпадают с именами
+// диалектов GORM, по которым выбираются миграции.
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// Задержки между попытками подключения: первая initialBackoff,
+// затем удваивается, но не больше maxBackoff
+const (
+	initialBackoff = 250 * time.Millisecond
+	maxBackoff     = 5 * time.Second
+)
 
-func Init(cfg *config.Config) {
-	dsn := cfg.DatabaseDSN()
+// Open подключается к базе данных, выбранной в DB_DRIVER, и настраивает пул
+// соединений. Пока база недоступна, подключение повторяется с растущей
+// задержкой в течение DB_CONNECT_TIMEOUT. Ожидание прерывается отменой ctx.
+func Open(ctx context.Context, cfg *config.Config) (*gorm.DB, error) {
+	dialector, err := Dialector(cfg)
+	if err != nil {
+		return nil, err
+	}
 
-	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	level, err := ParseLogLevel(cfg.Database.LogLevel)
+	if err != nil {
+		log.Printf("Неизвестный уровень логирования БД %q, используется warn", cfg.Database.LogLevel)
+	}
+	gormConfig := &gorm.Config{Logger: logger.Default.LogMode(level)}
 
+	var db *gorm.DB
+	timeout := time.Duration(cfg.Database.ConnectTimeout) * time.Second
+	err = retry(ctx, timeout, func() error {
+		var err error
+		db, err = gorm.Open(dialector, gormConfig)
+		if err != nil && db != nil {
+			// При ошибке проверки соединения gorm.Open возвращает открытый
+			// пул: без закрытия каждая попытка оставляла бы его и горутину
+			closeDB(db)
+			db = nil
+		}
+		return err
+	})
 	if err != nil {
-		log.Fatalf("Не удалось подключиться к базе данных: %v", err)
+		return nil, fmt.Errorf("connect to database: %w", err)
+	}
+
+	if err := ConfigurePool(db, cfg.Database); err != nil {
+		closeDB(db)
+		return nil, err
 	}
 
 	fmt.Println("Успешное подключение к базе данных!")
+	return db, nil
+}
+
+// OpenReplica подключается к реплике PostgreSQL по DSN с теми же настройками
+// пула и логирования, что и у основной базы. Подключение не проверяется:
+// реплика может быть недоступна при запуске, ее состояние отслеживает
+// репозиторий.
+func OpenReplica(cfg *config.Config, dsn string) (*gorm.DB, error) {
+	level, _ := ParseLogLevel(cfg.Database.LogLevel)
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger:               logger.Default.LogMode(level),
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ConfigurePool(db, cfg.Database); err != nil {
+		closeDB(db)
+		return nil, err
+	}
+	return db, nil
+}
+
+// closeDB закрывает соединения базы, которую не удалось настроить
+func closeDB(db *gorm.DB) {
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
+
+// Dialector возвращает диалект GORM для DB_DRIVER
+func Dialector(cfg *config.Config) (gorm.Dialector, error) {
+	switch strings.ToLower(cfg.Database.Driver) {
+	case DriverPostgres, "":
+		return postgres.Open(cfg.DatabaseDSN()), nil
+	case DriverSQLite:
+		return sqlite.Open(sqliteDSN(cfg.Database.SQLitePath)), nil
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", cfg.Database.Driver)
+	}
+}
+
+// sqliteDSN включает внешние ключи (ON DELETE CASCADE) и ожидание
+// блокировки вместо немедленной ошибки "database is locked"
+func sqliteDSN(path string) string {
+	if path == "" {
+		path = ":memory:"
+	}
+	return path + "?_foreign_keys=1&_busy_timeout=5000"
+}
+
+// ConfigurePool применяет настройки пула соединений из конфигурации.
+// Нулевые значения оставляют настройки database/sql по умолчанию.
+// Для SQLite настройки игнорируются: используется одно постоянное
+// соединение, потому что база в памяти живет, пока открыто ее соединение,
+// а писатель в SQLite все равно только один.
+func ConfigurePool(db *gorm.DB, cfg config.DatabaseConfig) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	if db.Dialector.Name() == DriverSQLite {
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetMaxIdleConns(1)
+		sqlDB.SetConnMaxLifetime(0)
+		return nil
+	}
+
+	if cfg.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetime) * time.Second)
+	}
+	return nil
+}
+
+// ParseLogLevel переводит DB_LOG_LEVEL в уровень логирования GORM.
+// Для пустой строки и неизвестного значения возвращается Warn.
+func ParseLogLevel(level string) (logger.LogLevel, error) {
+	switch strings.ToLower(level) {
+	case "silent":
+		return logger.Silent, nil
+	case "error":
+		return logger.Error, nil
+	case "warn", "":
+		return logger.Warn, nil
+	case "info":
+		return logger.Info, nil
+	default:
+		return logger.Warn, fmt.Errorf("unknown database log level %q", level)
+	}
+}
+
+// retry вызывает attempt, пока он не завершится успешно, не истечет
+// timeout или не будет отменен ctx. Между попытками задержка растет
+// от initialBackoff до maxBackoff. timeout <= 0 - одна попытка.
+func retry(ctx context.Context, timeout time.Duration, attempt func() error) error {
+	deadline := time.Now().Add(timeout)
+	backoff := initialBackoff
+
+	for n := 1; ; n++ {
+		err := attempt()
+		if err == nil {
+			return nil
+		}
+
+		if time.Now().Add(backoff).After(deadline) {
+			return fmt.Errorf("%d attempts: %w", n, err)
+		}
+
+		log.Printf("База данных недоступна (попытка %d): %v, повтор через %v", n, err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d attempts: %w", n, ctx.Err())
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, maxBackoff)
+	}
 }
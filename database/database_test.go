@@ -1,12 +1,21 @@
 package database
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
+	"time"
+	"wb-service/config"
 	"wb-service/models"
 
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 func TestInit(t *testing.T) {
@@ -26,14 +35,14 @@ func TestInit(t *testing.T) {
 		os.Unsetenv("DB_SSLMODE")
 	}()
 
+	var DB *gorm.DB
+
 	t.Run("init with sqlite for testing", func(t *testing.T) {
 		// Use SQLite for testing since we can't guarantee PostgreSQL is available
 		db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 		if err != nil {
 			t.Fatalf("Failed to open test database: %v", err)
 		}
-
-		// Set the global DB variable to test the Init function behavior
 		DB = db
 
 		// Test auto-migration
@@ -88,13 +97,11 @@ func TestInit(t *testing.T) {
 
 func TestDatabaseOperationsAfterInit(t *testing.T) {
 	// Setup test database
-	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	DB, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	if err != nil {
 		t.Fatalf("Failed to open test database: %v", err)
 	}
 
-	DB = db
-
 	// Auto-migrate
 	err = DB.AutoMigrate(&models.Order{}, &models.Delivery{}, &models.Payment{}, &models.Item{})
 	if err != nil {
@@ -202,4 +209,215 @@ func TestDatabaseOperationsAfterInit(t *testing.T) {
 			t.Errorf("Expected 1 order, got %d", count)
 		}
 	})
-}
\ No newline at end of file
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("succeeds after failures", func(t *testing.T) {
+		attempts := 0
+		err := retry(ctx, 5*time.Second, func() error {
+			attempts++
+			if attempts < 3 {
+				return errors.New("connection refused")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("Expected success, got %v", err)
+		}
+		if attempts != 3 {
+			t.Errorf("Expected 3 attempts, got %d", attempts)
+		}
+	})
+
+	t.Run("gives up after timeout", func(t *testing.T) {
+		errRefused := errors.New("connection refused")
+		attempts := 0
+		start := time.Now()
+
+		err := retry(ctx, 500*time.Millisecond, func() error {
+			attempts++
+			return errRefused
+		})
+		if !errors.Is(err, errRefused) {
+			t.Errorf("Expected last attempt error, got %v", err)
+		}
+		if attempts < 2 {
+			t.Errorf("Expected several attempts, got %d", attempts)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Expected retry to respect timeout, took %v", elapsed)
+		}
+	})
+
+	t.Run("zero timeout makes one attempt", func(t *testing.T) {
+		attempts := 0
+		retry(ctx, 0, func() error {
+			attempts++
+			return errors.New("connection refused")
+		})
+		if attempts != 1 {
+			t.Errorf("Expected 1 attempt, got %d", attempts)
+		}
+	})
+
+	t.Run("stops on context cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := retry(ctx, time.Minute, func() error {
+			return errors.New("connection refused")
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+}
+
+func TestOpenReturnsError(t *testing.T) {
+	cfg := config.Load()
+	cfg.Database.Host = "127.0.0.1"
+	cfg.Database.Port = "1" // на этом порту никто не слушает
+	cfg.Database.ConnectTimeout = 0
+
+	if _, err := Open(context.Background(), cfg); err == nil {
+		t.Error("Expected Open to fail for unreachable database")
+	}
+}
+
+func TestOpenClosesFailedAttempts(t *testing.T) {
+	cfg := config.Load()
+	cfg.Database.Host = "127.0.0.1"
+	cfg.Database.Port = "1"
+	cfg.Database.ConnectTimeout = 1 // несколько попыток с задержкой
+
+	before := runtime.NumGoroutine()
+	if _, err := Open(context.Background(), cfg); err == nil {
+		t.Fatal("Expected Open to fail for unreachable database")
+	}
+
+	// Каждый открытый sql.DB держит горутину, открывающую соединения;
+	// после закрытия пула она завершается не мгновенно
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("Expected failed attempts to close their pools: %d goroutines before, %d after", before, after)
+	}
+}
+
+func TestConfigurePool(t *testing.T) {
+	cfg := config.DatabaseConfig{MaxOpenConns: 7, MaxIdleConns: 3, ConnMaxLifetime: 60}
+
+	t.Run("postgres uses configured pool", func(t *testing.T) {
+		// Диалект PostgreSQL поверх готового соединения, без подключения к серверу
+		conn, err := sql.Open("sqlite3", ":memory:")
+		if err != nil {
+			t.Fatalf("Failed to open connection: %v", err)
+		}
+		defer conn.Close()
+
+		db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+		if err != nil {
+			t.Fatalf("Failed to open test database: %v", err)
+		}
+
+		if err := ConfigurePool(db, cfg); err != nil {
+			t.Fatalf("ConfigurePool failed: %v", err)
+		}
+
+		if max := conn.Stats().MaxOpenConnections; max != 7 {
+			t.Errorf("Expected max open connections 7, got %d", max)
+		}
+	})
+
+	t.Run("sqlite uses a single connection", func(t *testing.T) {
+		db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+		if err != nil {
+			t.Fatalf("Failed to open test database: %v", err)
+		}
+
+		if err := ConfigurePool(db, cfg); err != nil {
+			t.Fatalf("ConfigurePool failed: %v", err)
+		}
+
+		sqlDB, _ := db.DB()
+		defer sqlDB.Close()
+		if max := sqlDB.Stats().MaxOpenConnections; max != 1 {
+			t.Errorf("Expected max open connections 1, got %d", max)
+		}
+	})
+}
+
+func TestOpenSQLite(t *testing.T) {
+	cfg := config.Load()
+	cfg.Database.Driver = DriverSQLite
+	cfg.Database.SQLitePath = filepath.Join(t.TempDir(), "orders.db")
+
+	db, err := Open(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	defer sqlDB.Close()
+
+	if name := db.Dialector.Name(); name != DriverSQLite {
+		t.Errorf("Expected sqlite dialect, got %s", name)
+	}
+
+	// Внешние ключи включены: удаление заказа удаляет связанные записи
+	if err := db.Exec("CREATE TABLE parent (id INTEGER PRIMARY KEY)").Error; err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	if err := db.Exec("CREATE TABLE child (parent_id INTEGER REFERENCES parent(id))").Error; err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	if err := db.Exec("INSERT INTO child (parent_id) VALUES (42)").Error; err == nil {
+		t.Error("Expected foreign key violation")
+	}
+}
+
+func TestDialector(t *testing.T) {
+	cfg := config.Load()
+
+	for driver, want := range map[string]string{"": "postgres", "postgres": "postgres", "SQLite": "sqlite"} {
+		cfg.Database.Driver = driver
+		dialector, err := Dialector(cfg)
+		if err != nil {
+			t.Errorf("Driver %q: unexpected error %v", driver, err)
+			continue
+		}
+		if name := dialector.Name(); name != want {
+			t.Errorf("Driver %q: expected %s, got %s", driver, want, name)
+		}
+	}
+
+	cfg.Database.Driver = "mysql"
+	if _, err := Dialector(cfg); err == nil {
+		t.Error("Expected error for unknown driver")
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		input string
+		want  logger.LogLevel
+		err   bool
+	}{
+		{"silent", logger.Silent, false},
+		{"error", logger.Error, false},
+		{"WARN", logger.Warn, false},
+		{"", logger.Warn, false},
+		{"info", logger.Info, false},
+		{"verbose", logger.Warn, true},
+	}
+
+	for _, tt := range tests {
+		level, err := ParseLogLevel(tt.input)
+		if level != tt.want || (err != nil) != tt.err {
+			t.Errorf("ParseLogLevel(%q) = %v, %v; want %v, error %v", tt.input, level, err, tt.want, tt.err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"wb-service/internal/apierror"
+	"wb-service/internal/auth"
+	"wb-service/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// varyOrder - заголовки запроса, от которых зависит ответ с заказом:
+// персональные данные маскируются по роли и API ключу клиента
+var varyOrder = "Authorization, " + auth.APIKeyHeader
+
+// writeOrder отдает заказ с ETag, Last-Modified и Cache-Control. Если ETag
+// совпадает с одним из If-None-Match, отвечает 304 без тела.
+func (a *App) writeOrder(c *gin.Context, order *models.Order) {
+	body, err := json.Marshal(order)
+	if err != nil {
+		log.Printf("Ошибка сериализации заказа %s: %v", order.OrderUID, err)
+		apierror.Abort(c, http.StatusInternalServerError, apierror.CodeInternal, "encoding error")
+		return
+	}
+
+	etag := orderETag(body)
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", orderCacheControl(a.cfg.Server.OrderMaxAge))
+	c.Header("Vary", varyOrder)
+	if modified := orderLastModified(order); !modified.IsZero() {
+		c.Header("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// orderETag возвращает сильный ETag - хэш тела ответа. Тело уже
+// замаскировано, поэтому у разных политик маскирования разные ETag, а
+// обезличивание заказа меняет ETag, хотя даты заказа остаются прежними.
+func orderETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// orderLastModified возвращает время последнего события заказа: создания
+// или оплаты. Отдельного времени смены статуса в модели нет.
+func orderLastModified(order *models.Order) time.Time {
+	modified := order.DateCreated
+	if order.Payment.PaymentDt > 0 {
+		if paid := time.Unix(order.Payment.PaymentDt, 0); paid.After(modified) {
+			modified = paid
+		}
+	}
+	return modified
+}
+
+// orderCacheControl разрешает хранить ответ только клиенту: в нем
+// персональные данные. Без max-age клиент проверяет ETag при каждом запросе.
+func orderCacheControl(maxAge int) string {
+	if maxAge <= 0 {
+		return "private, no-cache"
+	}
+	return "private, max-age=" + strconv.Itoa(maxAge)
+}
+
+// etagMatches сравнивает ETag со списком из If-None-Match. Для
+// If-None-Match используется слабое сравнение (RFC 9110): префикс W/
+// не учитывается.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetOrderConditional(t *testing.T) {
+	cfg := testConfig()
+	cfg.Server.OrderMaxAge = 120
+	cfg.PII.KeyPolicies = []string{"partner-key=redacted"}
+	app := newTestApp(t, cfg, nil)
+
+	order := createTestOrderForCache()
+	order.DateCreated = time.Date(2024, 11, 26, 6, 22, 19, 0, time.UTC)
+	order.Payment.PaymentDt = order.DateCreated.Add(time.Hour).Unix()
+	app.cache.Set(order.OrderUID, order)
+
+	get := func(header map[string]string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/order/"+order.OrderUID, nil)
+		for name, value := range header {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		app.router.ServeHTTP(w, req)
+		return w
+	}
+
+	first := get(nil)
+	if first.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", first.Code)
+	}
+	etag := first.Header().Get("ETag")
+	if len(etag) != 34 || etag[0] != '"' {
+		t.Fatalf("Expected quoted strong ETag, got %q", etag)
+	}
+	if got := first.Header().Get("Cache-Control"); got != "private, max-age=120" {
+		t.Errorf("Unexpected Cache-Control: %q", got)
+	}
+	if got := first.Header().Get("Last-Modified"); got != "Tue, 26 Nov 2024 07:22:19 GMT" {
+		t.Errorf("Expected Last-Modified from payment time, got %q", got)
+	}
+	if first.Header().Get("Vary") == "" {
+		t.Error("Expected Vary header")
+	}
+
+	// Повторный запрос дает тот же ETag
+	if second := get(nil); second.Header().Get("ETag") != etag {
+		t.Errorf("Expected stable ETag, got %q and %q", etag, second.Header().Get("ETag"))
+	}
+
+	t.Run("not modified", func(t *testing.T) {
+		w := get(map[string]string{"If-None-Match": `"other", W/` + etag})
+		if w.Code != http.StatusNotModified {
+			t.Fatalf("Expected status 304, got %d", w.Code)
+		}
+		if w.Body.Len() != 0 {
+			t.Errorf("Expected empty body, got %q", w.Body.String())
+		}
+		if w.Header().Get("ETag") != etag {
+			t.Errorf("Expected ETag on 304, got %q", w.Header().Get("ETag"))
+		}
+	})
+
+	t.Run("stale etag", func(t *testing.T) {
+		if w := get(map[string]string{"If-None-Match": `"stale"`}); w.Code != http.StatusOK {
+			t.Errorf("Expected status 200, got %d", w.Code)
+		}
+	})
+
+	// Другая политика маскирования - другое тело и другой ETag
+	t.Run("masked response", func(t *testing.T) {
+		w := get(map[string]string{"X-API-Key": "partner-key", "If-None-Match": etag})
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 for masked response, got %d", w.Code)
+		}
+		if w.Header().Get("ETag") == etag {
+			t.Error("Expected masked response to have its own ETag")
+		}
+	})
+
+	// Изменение заказа меняет ETag
+	t.Run("changed order", func(t *testing.T) {
+		changed := order.Clone()
+		changed.TrackNumber = "NEW_TRACK"
+		app.cache.Set(order.OrderUID, changed)
+
+		if w := get(map[string]string{"If-None-Match": etag}); w.Code != http.StatusOK {
+			t.Errorf("Expected status 200 after change, got %d", w.Code)
+		}
+	})
+}
+
+func TestEtagMatches(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected bool
+	}{
+		{"", false},
+		{`"abc"`, true},
+		{`W/"abc"`, true},
+		{`"x", "abc"`, true},
+		{`*`, true},
+		{`"abcd"`, false},
+		{`abc`, false},
+	}
+
+	for _, tt := range tests {
+		if got := etagMatches(tt.header, `"abc"`); got != tt.expected {
+			t.Errorf("etagMatches(%q) = %v, expected %v", tt.header, got, tt.expected)
+		}
+	}
+}
+
+func TestOrderCacheControl(t *testing.T) {
+	if got := orderCacheControl(0); got != "private, no-cache" {
+		t.Errorf("Expected no-cache without max age, got %q", got)
+	}
+}
@@ -0,0 +1,112 @@
+// Package apierror описывает ошибки HTTP API: единый конверт
+// {"error": {"code", "message", "details", "request_id"}} и идентификатор
+// запроса, по которому ошибку можно найти в логах
+package apierror
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Заголовок с идентификатором запроса и ключи контекста запроса gin
+const (
+	RequestIDHeader     = "X-Request-ID"
+	RequestIDContextKey = "request_id"
+	// LegacyContextKey отмечает запросы к устаревшим маршрутам без /api/v1:
+	// им ошибки отдаются в прежнем виде {"error": "сообщение"}
+	LegacyContextKey = "legacy_errors"
+)
+
+// maxRequestIDLength - максимальная длина идентификатора запроса клиента
+const maxRequestIDLength = 128
+
+// Коды ошибок API. Код не меняется между версиями сервиса, сообщение
+// предназначено для человека и может уточняться.
+const (
+	CodeNotFound            = "not_found"
+	CodeRouteNotFound       = "route_not_found"
+	CodeUnauthorized        = "unauthorized"
+	CodeForbidden           = "forbidden"
+	CodeRateLimited         = "rate_limited"
+	CodeDatabaseUnavailable = "database_unavailable"
+	CodeDatabaseError       = "database_error"
+	CodeNotImplemented      = "not_implemented"
+	CodeInternal            = "internal_error"
+)
+
+// Error - описание ошибки
+type Error struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Details   any    `json:"details,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Envelope - тело ответа с ошибкой
+type Envelope struct {
+	Error Error `json:"error"`
+}
+
+// Abort прерывает обработку запроса и отвечает ошибкой
+func Abort(c *gin.Context, status int, code, message string) {
+	AbortWithDetails(c, status, code, message, nil)
+}
+
+// AbortWithDetails прерывает обработку запроса и отвечает ошибкой с
+// дополнительными сведениями для клиента
+func AbortWithDetails(c *gin.Context, status int, code, message string, details any) {
+	if c.GetBool(LegacyContextKey) {
+		c.AbortWithStatusJSON(status, gin.H{"error": message})
+		return
+	}
+
+	c.AbortWithStatusJSON(status, Envelope{Error: Error{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestID: c.GetString(RequestIDContextKey),
+	}})
+}
+
+// RequestID возвращает middleware, который берет идентификатор запроса из
+// заголовка X-Request-ID или создает новый, записывает его в контекст
+// запроса и возвращает клиенту в том же заголовке
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		c.Set(RequestIDContextKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// validRequestID проверяет идентификатор клиента: он попадает в логи и
+// заголовки ответа, поэтому допускаются только короткие строки из букв,
+// цифр и символов - _ . :
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-' || r == '_' || r == '.' || r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID создает случайный идентификатор запроса
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b) // crypto/rand.Read не возвращает ошибок
+	return hex.EncodeToString(b)
+}
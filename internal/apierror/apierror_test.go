@@ -0,0 +1,83 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+func newTestRouter() *gin.Engine {
+	r := gin.New()
+	r.Use(RequestID())
+	r.GET("/missing", func(c *gin.Context) {
+		AbortWithDetails(c, http.StatusNotFound, CodeNotFound, "record not found", gin.H{"order_uid": "x"})
+	})
+	r.GET("/legacy", func(c *gin.Context) {
+		c.Set(LegacyContextKey, true)
+		Abort(c, http.StatusNotFound, CodeNotFound, "record not found")
+	})
+	return r
+}
+
+func TestAbortEnvelope(t *testing.T) {
+	r := newTestRouter()
+
+	req := httptest.NewRequest("GET", "/missing", nil)
+	req.Header.Set(RequestIDHeader, "trace-42")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status 404, got %d", w.Code)
+	}
+	if w.Header().Get(RequestIDHeader) != "trace-42" {
+		t.Errorf("Expected request ID to be echoed, got %q", w.Header().Get(RequestIDHeader))
+	}
+
+	var envelope Envelope
+	if err := json.Unmarshal(w.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("Failed to unmarshal envelope: %v", err)
+	}
+	got := envelope.Error
+	if got.Code != CodeNotFound || got.Message != "record not found" || got.RequestID != "trace-42" {
+		t.Errorf("Unexpected error: %+v", got)
+	}
+	if details, ok := got.Details.(map[string]any); !ok || details["order_uid"] != "x" {
+		t.Errorf("Unexpected details: %#v", got.Details)
+	}
+}
+
+func TestAbortLegacy(t *testing.T) {
+	w := httptest.NewRecorder()
+	newTestRouter().ServeHTTP(w, httptest.NewRequest("GET", "/legacy", nil))
+
+	if w.Body.String() != `{"error":"record not found"}` {
+		t.Errorf("Expected legacy error body, got %s", w.Body.String())
+	}
+}
+
+func TestRequestIDGenerated(t *testing.T) {
+	r := newTestRouter()
+
+	for _, id := range []string{"", "bad id\twith spaces", strings.Repeat("a", maxRequestIDLength+1)} {
+		req := httptest.NewRequest("GET", "/missing", nil)
+		req.Header.Set(RequestIDHeader, id)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		got := w.Header().Get(RequestIDHeader)
+		if got == id || len(got) != 32 {
+			t.Errorf("Expected generated request ID for %q, got %q", id, got)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+// Package auth проверяет клиентов HTTP API: статические API ключи из
+// конфигурации и JWT, подписанные ключами из локального JWKS файла, - и
+// открывает маршруты по ролям viewer, support и admin
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"wb-service/config"
+	"wb-service/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Заголовок API ключа и ключи контекста запроса gin, в которые middleware
+// записывает роль и идентификатор клиента
+const (
+	APIKeyHeader      = "X-API-Key"
+	RoleContextKey    = "role"
+	SubjectContextKey = "subject"
+)
+
+// AnonymousSubject - идентификатор клиента без учетных данных
+const AnonymousSubject = "anonymous"
+
+// leeway - допустимое расхождение часов при проверке времени жизни токена
+const leeway = 30 * time.Second
+
+// Ошибки аутентификации. Подробности (какой ключ, почему не прошла
+// подпись) клиенту не отдаются.
+var (
+	ErrNoCredentials      = errors.New("no credentials")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Identity - проверенный клиент API
+type Identity struct {
+	Subject string // "api-key:<префикс хэша>", sub из JWT или AnonymousSubject
+	Role    Role   // пустая, если в токене нет известной роли
+}
+
+// Authenticator проверяет учетные данные запроса
+type Authenticator struct {
+	keys      map[[sha256.Size]byte]Identity
+	jwks      *JWKS // nil - JWT не принимаются
+	parser    *jwt.Parser
+	roleClaim string
+	anonymous Role // роль запросов без учетных данных, "" - доступ закрыт
+}
+
+// New создает Authenticator из конфигурации. API ключи задаются парами
+// "ключ=роль" и хранятся только в виде хэшей. Ошибка в конфигурации
+// возвращается сразу, чтобы опечатка не открыла маршруты.
+func New(cfg config.AuthConfig) (*Authenticator, error) {
+	a := &Authenticator{
+		keys:      make(map[[sha256.Size]byte]Identity, len(cfg.APIKeys)),
+		roleClaim: cfg.RoleClaim,
+	}
+	if a.roleClaim == "" {
+		a.roleClaim = "role"
+	}
+
+	for i, pair := range cfg.APIKeys {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			// Сама пара в ошибку не попадает: в ней ключ
+			return nil, fmt.Errorf("API key %d: expected key=role", i+1)
+		}
+		role, err := ParseRole(value)
+		if err != nil {
+			return nil, fmt.Errorf("API key %d: %w", i+1, err)
+		}
+
+		sum := sha256.Sum256([]byte(key))
+		a.keys[sum] = Identity{Subject: "api-key:" + hex.EncodeToString(sum[:4]), Role: role}
+	}
+
+	if cfg.AnonymousRole != "" {
+		role, err := ParseRole(cfg.AnonymousRole)
+		if err != nil {
+			return nil, fmt.Errorf("anonymous role: %w", err)
+		}
+		a.anonymous = role
+	}
+
+	if cfg.JWKSFile != "" {
+		jwks, err := LoadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("load JWKS: %w", err)
+		}
+		a.jwks = jwks
+
+		opts := []jwt.ParserOption{
+			jwt.WithValidMethods(signingMethods),
+			jwt.WithExpirationRequired(),
+			jwt.WithLeeway(leeway),
+		}
+		if cfg.JWTIssuer != "" {
+			opts = append(opts, jwt.WithIssuer(cfg.JWTIssuer))
+		}
+		if cfg.JWTAudience != "" {
+			opts = append(opts, jwt.WithAudience(cfg.JWTAudience))
+		}
+		a.parser = jwt.NewParser(opts...)
+	}
+
+	return a, nil
+}
+
+// Authenticate проверяет API ключ из заголовка X-API-Key или токен из
+// Authorization: Bearer. Без учетных данных возвращает анонимного клиента
+// с ролью AUTH_ANONYMOUS_ROLE или ErrNoCredentials, если роль не задана.
+func (a *Authenticator) Authenticate(r *http.Request) (Identity, error) {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return a.checkAPIKey(key)
+	}
+
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+			return Identity{}, ErrInvalidCredentials
+		}
+		return a.checkToken(strings.TrimSpace(token))
+	}
+
+	if a.anonymous == "" {
+		return Identity{}, ErrNoCredentials
+	}
+	return Identity{Subject: AnonymousSubject, Role: a.anonymous}, nil
+}
+
+// checkAPIKey ищет ключ по хэшу. Сравнение хэшей с постоянным временем
+// не дает подобрать ключ по времени ответа.
+func (a *Authenticator) checkAPIKey(key string) (Identity, error) {
+	sum := sha256.Sum256([]byte(key))
+	for known, identity := range a.keys {
+		if subtle.ConstantTimeCompare(known[:], sum[:]) == 1 {
+			return identity, nil
+		}
+	}
+	return Identity{}, ErrInvalidCredentials
+}
+
+// checkToken проверяет подпись, срок действия, издателя и аудиторию JWT
+// и достает из него роль
+func (a *Authenticator) checkToken(raw string) (Identity, error) {
+	if a.jwks == nil {
+		return Identity{}, ErrInvalidCredentials
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := a.parser.ParseWithClaims(raw, claims, a.jwks.keyfunc); err != nil {
+		return Identity{}, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	subject, _ := claims.GetSubject()
+	return Identity{Subject: subject, Role: roleFromClaim(claims[a.roleClaim])}, nil
+}
+
+// roleFromClaim разбирает роль из строки или списка строк; из списка
+// выбирается роль с наибольшими правами. Неизвестные роли пропускаются.
+func roleFromClaim(claim any) Role {
+	var values []any
+	switch v := claim.(type) {
+	case string:
+		values = []any{v}
+	case []any:
+		values = v
+	}
+
+	var best Role
+	for _, value := range values {
+		s, ok := value.(string)
+		if !ok {
+			continue
+		}
+		if role, err := ParseRole(s); err == nil && role.level() > best.level() {
+			best = role
+		}
+	}
+	return best
+}
+
+// Require возвращает middleware, пропускающий запросы клиентов с ролью
+// не ниже role: без учетных данных - 401, с неверными - 401, с
+// недостаточной ролью - 403. Роль и идентификатор клиента записываются
+// в контекст запроса.
+func (a *Authenticator) Require(role Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity, err := a.identify(c)
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer realm="wb-service"`)
+			apierror.Abort(c, http.StatusUnauthorized, apierror.CodeUnauthorized, err.Error())
+			return
+		}
+
+		if !identity.Role.Allows(role) {
+			apierror.AbortWithDetails(c, http.StatusForbidden, apierror.CodeForbidden, "insufficient role",
+				gin.H{"required_role": role})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// identify проверяет клиента один раз за запрос: если маршрут защищен
+// несколькими Require, повторная проверка берет результат из контекста
+func (a *Authenticator) identify(c *gin.Context) (Identity, error) {
+	if subject := c.GetString(SubjectContextKey); subject != "" {
+		return Identity{Subject: subject, Role: Role(c.GetString(RoleContextKey))}, nil
+	}
+
+	identity, err := a.Authenticate(c.Request)
+	if err != nil {
+		if errors.Is(err, ErrNoCredentials) {
+			return Identity{}, ErrNoCredentials
+		}
+		return Identity{}, ErrInvalidCredentials
+	}
+
+	if identity.Subject == "" {
+		identity.Subject = "unknown"
+	}
+	c.Set(SubjectContextKey, identity.Subject)
+	c.Set(RoleContextKey, string(identity.Role))
+	return identity, nil
+}
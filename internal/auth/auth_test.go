@@ -0,0 +1,284 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"wb-service/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// rsaJWK возвращает открытый RSA ключ в формате JWK
+func rsaJWK(kid string, key *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+// writeJWKS записывает набор ключей во временный файл
+func writeJWKS(t *testing.T, keys ...map[string]string) string {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]any{"keys": keys})
+	if err != nil {
+		t.Fatalf("Failed to marshal JWKS: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("Failed to write JWKS: %v", err)
+	}
+	return path
+}
+
+// signToken подписывает токен ключом RS256 с заданным kid
+func signToken(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return signed
+}
+
+func generateKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	return key
+}
+
+func TestParseRole(t *testing.T) {
+	if role, err := ParseRole(" Admin "); err != nil || role != RoleAdmin {
+		t.Errorf("Expected admin, got %q (%v)", role, err)
+	}
+	if _, err := ParseRole("owner"); err == nil {
+		t.Error("Expected error for unknown role")
+	}
+
+	if !RoleAdmin.Allows(RoleSupport) || !RoleViewer.Allows(RoleViewer) {
+		t.Error("Expected higher role to include lower role")
+	}
+	if RoleViewer.Allows(RoleSupport) || Role("").Allows(RoleViewer) {
+		t.Error("Expected lower or empty role to be denied")
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	a, err := New(config.AuthConfig{APIKeys: []string{"secret=support"}})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(APIKeyHeader, "secret")
+	identity, err := a.Authenticate(req)
+	if err != nil || identity.Role != RoleSupport {
+		t.Errorf("Expected support identity, got %+v (%v)", identity, err)
+	}
+
+	req.Header.Set(APIKeyHeader, "wrong")
+	if _, err := a.Authenticate(req); err != ErrInvalidCredentials {
+		t.Errorf("Expected ErrInvalidCredentials, got %v", err)
+	}
+
+	if _, err := a.Authenticate(httptest.NewRequest("GET", "/", nil)); err != ErrNoCredentials {
+		t.Errorf("Expected ErrNoCredentials without anonymous role, got %v", err)
+	}
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.AuthConfig
+	}{
+		{"key without role", config.AuthConfig{APIKeys: []string{"secret"}}},
+		{"unknown key role", config.AuthConfig{APIKeys: []string{"secret=owner"}}},
+		{"unknown anonymous role", config.AuthConfig{AnonymousRole: "guest"}},
+		{"missing JWKS file", config.AuthConfig{JWKSFile: "/nonexistent/jwks.json"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.cfg)
+			if err == nil {
+				t.Fatal("Expected error")
+			}
+			if tt.cfg.APIKeys != nil && strings.Contains(err.Error(), "secret") {
+				t.Errorf("Error must not contain the API key: %v", err)
+			}
+		})
+	}
+}
+
+func TestAuthenticateJWT(t *testing.T) {
+	key := generateKey(t)
+	other := generateKey(t)
+	a, err := New(config.AuthConfig{
+		JWKSFile:    writeJWKS(t, rsaJWK("k1", &key.PublicKey)),
+		JWTIssuer:   "https://issuer.example",
+		JWTAudience: "wb-service",
+		RoleClaim:   "roles",
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub":   "user-1",
+			"iss":   "https://issuer.example",
+			"aud":   "wb-service",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"roles": []string{"viewer", "admin"},
+		}
+	}
+	with := func(name string, value any) jwt.MapClaims {
+		claims := valid()
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		role  Role
+		ok    bool
+	}{
+		{"valid", signToken(t, key, "k1", valid()), RoleAdmin, true},
+		{"without kid", signToken(t, key, "", valid()), RoleAdmin, true},
+		{"string role", signToken(t, key, "k1", with("roles", "support")), RoleSupport, true},
+		{"no known role", signToken(t, key, "k1", with("roles", "owner")), "", true},
+		{"expired", signToken(t, key, "k1", with("exp", time.Now().Add(-time.Hour).Unix())), "", false},
+		{"no expiration", signToken(t, key, "k1", with("exp", nil)), "", false},
+		{"wrong issuer", signToken(t, key, "k1", with("iss", "https://evil.example")), "", false},
+		{"wrong audience", signToken(t, key, "k1", with("aud", "other")), "", false},
+		{"unknown kid", signToken(t, key, "k2", valid()), "", false},
+		{"foreign key", signToken(t, other, "k1", valid()), "", false},
+		{"malformed", "not.a.token", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+
+			identity, err := a.Authenticate(req)
+			if !tt.ok {
+				if err == nil {
+					t.Fatalf("Expected token to be rejected, got %+v", identity)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate failed: %v", err)
+			}
+			if identity.Role != tt.role || identity.Subject != "user-1" {
+				t.Errorf("Expected user-1 with role %q, got %+v", tt.role, identity)
+			}
+		})
+	}
+
+	// Подпись HS256 открытым ключом как секретом не принимается
+	t.Run("symmetric algorithm", func(t *testing.T) {
+		secret := []byte(rsaJWK("k1", &key.PublicKey)["n"])
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, valid())
+		token.Header["kid"] = "k1"
+		signed, _ := token.SignedString(secret)
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "Bearer "+signed)
+		if _, err := a.Authenticate(req); err == nil {
+			t.Error("Expected HS256 token to be rejected")
+		}
+	})
+}
+
+func TestRequire(t *testing.T) {
+	a, err := New(config.AuthConfig{
+		APIKeys:       []string{"viewer-key=viewer", "admin-key=admin"},
+		AnonymousRole: "viewer",
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	r := gin.New()
+	r.GET("/orders", a.Require(RoleViewer), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(RoleContextKey))
+	})
+	r.POST("/admin", a.Require(RoleViewer), a.Require(RoleAdmin), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(SubjectContextKey))
+	})
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		header   string
+		value    string
+		expected int
+		body     string
+	}{
+		{"anonymous viewer", "GET", "/orders", "", "", http.StatusOK, "viewer"},
+		{"viewer key", "GET", "/orders", APIKeyHeader, "viewer-key", http.StatusOK, "viewer"},
+		{"invalid key", "GET", "/orders", APIKeyHeader, "wrong", http.StatusUnauthorized, ""},
+		{"basic auth", "GET", "/orders", "Authorization", "Basic dXNlcjpwYXNz", http.StatusUnauthorized, ""},
+		{"bearer without JWKS", "GET", "/orders", "Authorization", "Bearer token", http.StatusUnauthorized, ""},
+		{"anonymous admin route", "POST", "/admin", "", "", http.StatusForbidden, ""},
+		{"viewer admin route", "POST", "/admin", APIKeyHeader, "viewer-key", http.StatusForbidden, ""},
+		{"admin key", "POST", "/admin", APIKeyHeader, "admin-key", http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expected, w.Code, w.Body.String())
+			}
+			if tt.body != "" && w.Body.String() != tt.body {
+				t.Errorf("Expected body %q, got %q", tt.body, w.Body.String())
+			}
+			if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("Expected WWW-Authenticate header on 401")
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestParseJWKS(t *testing.T) {
+	rsaKey := generateKey(t)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPub, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	ec := map[string]string{
+		"kty": "EC", "kid": "ec", "crv": "P-256",
+		"x": base64.RawURLEncoding.EncodeToString(ecKey.X.Bytes()),
+		"y": base64.RawURLEncoding.EncodeToString(ecKey.Y.Bytes()),
+	}
+	ed := map[string]string{
+		"kty": "OKP", "kid": "ed", "crv": "Ed25519",
+		"x": base64.RawURLEncoding.EncodeToString(edPub),
+	}
+	enc := map[string]string{"kty": "RSA", "kid": "enc", "use": "enc"}
+
+	data, _ := json.Marshal(map[string]any{"keys": []map[string]string{rsaJWK("rsa", &rsaKey.PublicKey), ec, ed, enc}})
+	jwks, err := ParseJWKS(data)
+	if err != nil {
+		t.Fatalf("ParseJWKS failed: %v", err)
+	}
+	if len(jwks.keys) != 3 {
+		t.Errorf("Expected 3 signing keys, got %d", len(jwks.keys))
+	}
+
+	// Токен, подписанный EC ключом, проверяется по kid
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{"exp": time.Now().Add(time.Hour).Unix()})
+	token.Header["kid"] = "ec"
+	signed, err := token.SignedString(ecKey)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	if _, err := jwt.Parse(signed, jwks.keyfunc, jwt.WithValidMethods(signingMethods)); err != nil {
+		t.Errorf("Expected EC token to verify: %v", err)
+	}
+
+	// Без kid ключ не выбирается, если ключей несколько
+	token.Header["kid"] = nil
+	signed, _ = token.SignedString(ecKey)
+	if _, err := jwt.Parse(signed, jwks.keyfunc, jwt.WithValidMethods(signingMethods)); err == nil {
+		t.Error("Expected token without kid to be rejected for multi-key JWKS")
+	}
+}
+
+func TestParseJWKSInvalid(t *testing.T) {
+	weak := map[string]string{"kty": "RSA", "n": base64.RawURLEncoding.EncodeToString([]byte{0xff, 0x01}), "e": "AQAB"}
+	offCurve := map[string]string{"kty": "EC", "crv": "P-256", "x": "AQ", "y": "Ag"}
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"not json", "{"},
+		{"empty", `{"keys":[]}`},
+		{"unsupported type", `{"keys":[{"kty":"oct","k":"c2VjcmV0"}]}`},
+		{"unsupported curve", `{"keys":[{"kty":"EC","crv":"secp256k1","x":"AQ","y":"Ag"}]}`},
+		{"weak RSA", mustJSON(t, weak)},
+		{"point off curve", mustJSON(t, offCurve)},
+		{"duplicate kid", `{"keys":[{"kty":"OKP","crv":"Ed25519","x":"` + base64.RawURLEncoding.EncodeToString(make([]byte, 32)) +
+			`"},{"kty":"OKP","crv":"Ed25519","x":"` + base64.RawURLEncoding.EncodeToString(make([]byte, 32)) + `"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseJWKS([]byte(tt.data)); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
+
+func mustJSON(t *testing.T, key map[string]string) string {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]any{"keys": []map[string]string{key}})
+	if err != nil {
+		t.Fatalf("Failed to marshal JWKS: %v", err)
+	}
+	return string(data)
+}
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Role - роль клиента HTTP API. Роли упорядочены: каждая следующая
+// включает права предыдущей.
+type Role string
+
+const (
+	// RoleViewer читает заказы (веб-интерфейс)
+	RoleViewer Role = "viewer"
+	// RoleSupport дополнительно видит состояние кэша и удаляет заказы
+	RoleSupport Role = "support"
+	// RoleAdmin управляет кэшем и обезличивает данные клиентов
+	RoleAdmin Role = "admin"
+)
+
+// ParseRole разбирает название роли
+func ParseRole(s string) (Role, error) {
+	role := Role(strings.ToLower(strings.TrimSpace(s)))
+	if role.level() == 0 {
+		return "", fmt.Errorf("unknown role %q", s)
+	}
+	return role, nil
+}
+
+// Allows сообщает, достаточно ли роли прав для required.
+// Пустая или неизвестная роль не дает никаких прав.
+func (r Role) Allows(required Role) bool {
+	return r.level() > 0 && r.level() >= required.level()
+}
+
+// level возвращает уровень прав роли, 0 - неизвестная роль
+func (r Role) level() int {
+	switch r {
+	case RoleViewer:
+		return 1
+	case RoleSupport:
+		return 2
+	case RoleAdmin:
+		return 3
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,147 @@
+package cache
+
+import "container/list"
+
+// Списки ARC: t1/t2 - ключи в кэше, b1/b2 - история вытесненных ключей
+const (
+	arcT1 = iota
+	arcT2
+	arcB1
+	arcB2
+)
+
+type arcEntry struct {
+	key   string
+	where int
+	elem  *list.Element
+}
+
+// arcPolicy реализует Adaptive Replacement Cache (Megiddo, Modha).
+// t1 хранит ключи, к которым обращались один раз, t2 - многократно.
+// По попаданиям в историю b1/b2 политика сдвигает целевой размер t1,
+// поэтому однократный проход по холодным ключам не вымывает t2.
+type arcPolicy struct {
+	capacity int
+	p        int // целевой размер t1
+	lists    [4]*list.List
+	entries  map[string]*arcEntry
+	fromB2   bool // последний добавленный ключ вернулся из b2
+}
+
+func newARCPolicy(capacity int) *arcPolicy {
+	p := &arcPolicy{capacity: capacity}
+	p.Reset()
+	return p
+}
+
+func (p *arcPolicy) Name() string {
+	return PolicyARC
+}
+
+func (p *arcPolicy) Access(key string, hit bool) {
+	e, ok := p.entries[key]
+	if !ok || (e.where != arcT1 && e.where != arcT2) {
+		return
+	}
+	p.move(e, arcT2)
+}
+
+func (p *arcPolicy) Insert(key string) {
+	p.fromB2 = false
+
+	if e, ok := p.entries[key]; ok {
+		switch e.where {
+		case arcB1:
+			// Ключ недавно вытеснен из t1 - t1 стоит увеличить
+			p.p = min(p.capacity, p.p+max(p.lists[arcB2].Len()/max(p.lists[arcB1].Len(), 1), 1))
+			p.move(e, arcT2)
+		case arcB2:
+			p.p = max(0, p.p-max(p.lists[arcB1].Len()/max(p.lists[arcB2].Len(), 1), 1))
+			p.fromB2 = true
+			p.move(e, arcT2)
+		default:
+			p.move(e, arcT2)
+		}
+		return
+	}
+
+	e := &arcEntry{key: key, where: arcT1}
+	e.elem = p.lists[arcT1].PushFront(e)
+	p.entries[key] = e
+	p.trimGhosts()
+}
+
+func (p *arcPolicy) Remove(key string) {
+	if e, ok := p.entries[key]; ok {
+		p.lists[e.where].Remove(e.elem)
+		delete(p.entries, key)
+	}
+}
+
+func (p *arcPolicy) Evict() (string, bool) {
+	t1, t2 := p.lists[arcT1], p.lists[arcT2]
+	if t1.Len() == 0 && t2.Len() == 0 {
+		return "", false
+	}
+
+	var e *arcEntry
+	if t1.Len() > 0 && (t1.Len() > p.p || (p.fromB2 && t1.Len() == p.p) || t2.Len() == 0) {
+		e = t1.Back().Value.(*arcEntry)
+		p.move(e, arcB1)
+	} else {
+		e = t2.Back().Value.(*arcEntry)
+		p.move(e, arcB2)
+	}
+
+	p.trimGhosts()
+	return e.key, true
+}
+
+func (p *arcPolicy) Keys() []string {
+	keys := listEntryKeys(nil, p.lists[arcT2])
+	return listEntryKeys(keys, p.lists[arcT1])
+}
+
+func (p *arcPolicy) Reset() {
+	for i := range p.lists {
+		p.lists[i] = list.New()
+	}
+	p.entries = make(map[string]*arcEntry)
+	p.p = 0
+	p.fromB2 = false
+}
+
+// move переносит ключ в начало указанного списка
+func (p *arcPolicy) move(e *arcEntry, where int) {
+	p.lists[e.where].Remove(e.elem)
+	e.where = where
+	e.elem = p.lists[where].PushFront(e)
+}
+
+// trimGhosts ограничивает историю: |t1|+|b1| <= c и общий размер <= 2c
+func (p *arcPolicy) trimGhosts() {
+	b1, b2 := p.lists[arcB1], p.lists[arcB2]
+
+	for p.lists[arcT1].Len()+b1.Len() > p.capacity && b1.Len() > 0 {
+		p.dropGhost(b1)
+	}
+	for len(p.entries) > 2*p.capacity && b2.Len() > 0 {
+		p.dropGhost(b2)
+	}
+	for len(p.entries) > 2*p.capacity && b1.Len() > 0 {
+		p.dropGhost(b1)
+	}
+}
+
+func (p *arcPolicy) dropGhost(ghosts *list.List) {
+	e := ghosts.Remove(ghosts.Back()).(*arcEntry)
+	delete(p.entries, e.key)
+}
+
+// listEntryKeys дописывает ключи списка arcEntry от начала к концу
+func listEntryKeys(keys []string, ll *list.List) []string {
+	for elem := ll.Front(); elem != nil; elem = elem.Next() {
+		keys = append(keys, elem.Value.(*arcEntry).key)
+	}
+	return keys
+}
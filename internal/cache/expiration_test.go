@@ -0,0 +1,200 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wb-service/internal/interfaces"
+	"wb-service/models"
+)
+
+func newExpiringCache(ttl time.Duration, expiration string) *LRUCache {
+	return NewLRUCacheWithOptions(context.Background(), Options{
+		Capacity:   10,
+		TTL:        ttl,
+		Expiration: expiration,
+	})
+}
+
+func TestLRUCache_AbsoluteExpiration(t *testing.T) {
+	c := newExpiringCache(100*time.Millisecond, ExpirationAbsolute)
+	defer c.Close()
+
+	c.Set("order", &models.Order{OrderUID: "order"})
+
+	time.Sleep(60 * time.Millisecond)
+	if _, found := c.Get("order"); !found {
+		t.Fatal("Expected order before TTL")
+	}
+
+	// Чтение не продлевает срок жизни
+	time.Sleep(60 * time.Millisecond)
+	if _, found := c.Get("order"); found {
+		t.Error("Expected order to expire 100ms after Set despite the read")
+	}
+
+	if stats := c.Stats(); stats.Expiration != ExpirationAbsolute {
+		t.Errorf("Expected absolute expiration in stats, got %s", stats.Expiration)
+	}
+}
+
+func TestLRUCache_SlidingExpiration(t *testing.T) {
+	c := newExpiringCache(100*time.Millisecond, ExpirationSliding)
+	defer c.Close()
+
+	c.Set("order", &models.Order{OrderUID: "order"})
+
+	// Каждое чтение продлевает запись еще на TTL
+	for i := 0; i < 3; i++ {
+		time.Sleep(60 * time.Millisecond)
+		if _, found := c.Get("order"); !found {
+			t.Fatalf("Expected sliding TTL to keep order alive, read %d", i)
+		}
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if _, found := c.Get("order"); found {
+		t.Error("Expected order to expire without reads")
+	}
+
+	if stats := c.Stats(); stats.Expiration != ExpirationSliding {
+		t.Errorf("Expected sliding expiration in stats, got %s", stats.Expiration)
+	}
+}
+
+func TestLRUCache_SetWithTTL(t *testing.T) {
+	c := newExpiringCache(50*time.Millisecond, ExpirationAbsolute)
+	defer c.Close()
+
+	c.Set("default", &models.Order{OrderUID: "default"})
+	c.SetWithTTL("long", &models.Order{OrderUID: "long"}, time.Hour)
+	c.SetWithTTL("forever", &models.Order{OrderUID: "forever"}, NoExpiration)
+	c.SetWithTTL("same", &models.Order{OrderUID: "same"}, DefaultExpiration)
+
+	time.Sleep(80 * time.Millisecond)
+
+	for key, want := range map[string]bool{"default": false, "same": false, "long": true, "forever": true} {
+		if _, found := c.Get(key); found != want {
+			t.Errorf("Key %s: expected found=%v, got %v", key, want, found)
+		}
+	}
+
+	// Перезапись возвращает TTL кэша по умолчанию
+	c.Set("long", &models.Order{OrderUID: "long"})
+	time.Sleep(80 * time.Millisecond)
+	if _, found := c.Get("long"); found {
+		t.Error("Expected overwritten key to use the default TTL")
+	}
+}
+
+func TestLRUCache_SetWithTTLWithoutDefaultTTL(t *testing.T) {
+	c := NewLRUCacheWithOptions(context.Background(), Options{
+		Capacity:        10,
+		CleanupInterval: 10 * time.Millisecond,
+	})
+	defer c.Close()
+
+	c.Set("forever", &models.Order{OrderUID: "forever"})
+	c.SetWithTTL("short", &models.Order{OrderUID: "short"}, 30*time.Millisecond)
+
+	// Очистка работает и без TTL кэша, если у записей есть свой TTL
+	waitFor(t, func() bool { return c.Size() == 1 })
+
+	if _, found := c.Get("forever"); !found {
+		t.Error("Expected key without TTL to stay")
+	}
+	if stats := c.Stats(); stats.Expirations != 1 {
+		t.Errorf("Expected 1 expiration, got %d", stats.Expirations)
+	}
+}
+
+func TestLRUCache_SnapshotKeepsEntryTTL(t *testing.T) {
+	src := newExpiringCache(time.Hour, ExpirationAbsolute)
+	defer src.Close()
+
+	src.Set("default", &models.Order{OrderUID: "default"})
+	src.SetWithTTL("short", &models.Order{OrderUID: "short"}, time.Minute)
+	src.SetWithTTL("forever", &models.Order{OrderUID: "forever"}, NoExpiration)
+
+	entries := src.Entries()
+	byKey := make(map[string]interfaces.CacheEntry)
+	for _, entry := range entries {
+		byKey[entry.Key] = entry
+	}
+	if byKey["short"].TTL != time.Minute || byKey["forever"].TTL != NoExpiration || byKey["default"].TTL != time.Hour {
+		t.Fatalf("Unexpected entry TTLs: %+v", byKey)
+	}
+
+	// Кэш с другим TTL по умолчанию сохраняет TTL отдельных записей
+	dst := newExpiringCache(time.Second, ExpirationAbsolute)
+	defer dst.Close()
+
+	if n := dst.Restore(entries); n != 3 {
+		t.Fatalf("Expected 3 restored entries, got %d", n)
+	}
+	for _, entry := range dst.Entries() {
+		want := byKey[entry.Key]
+		if entry.TTL != want.TTL || !entry.ExpiresAt.Equal(want.ExpiresAt) {
+			t.Errorf("Key %s: expected TTL %v until %v, got %v until %v",
+				entry.Key, want.TTL, want.ExpiresAt, entry.TTL, entry.ExpiresAt)
+		}
+	}
+}
+
+func TestLRUCache_RestoreLegacyEntries(t *testing.T) {
+	c := newExpiringCache(time.Minute, ExpirationAbsolute)
+	defer c.Close()
+
+	// В старых снимках нет TTL: срок считается от времени записи по TTL кэша
+	entries := []interfaces.CacheEntry{
+		{Key: "fresh", Order: &models.Order{OrderUID: "fresh"}, Timestamp: time.Now().Add(-30 * time.Second)},
+		{Key: "stale", Order: &models.Order{OrderUID: "stale"}, Timestamp: time.Now().Add(-2 * time.Minute)},
+	}
+
+	if n := c.Restore(entries); n != 1 {
+		t.Fatalf("Expected 1 restored entry, got %d", n)
+	}
+
+	restored := c.Entries()
+	if restored[0].Key != "fresh" || !restored[0].ExpiresAt.Equal(entries[0].Timestamp.Add(time.Minute)) {
+		t.Errorf("Unexpected restored entry: %+v", restored[0])
+	}
+}
+
+func TestShardedCache_SetWithTTL(t *testing.T) {
+	c := NewShardedCache(context.Background(), 4, Options{
+		Capacity:   40,
+		TTL:        50 * time.Millisecond,
+		Expiration: ExpirationSliding,
+	})
+	defer c.Close()
+
+	c.Set("default", &models.Order{OrderUID: "default"})
+	c.SetWithTTL("long", &models.Order{OrderUID: "long"}, time.Hour)
+
+	time.Sleep(80 * time.Millisecond)
+
+	if _, found := c.Get("default"); found {
+		t.Error("Expected default key to expire")
+	}
+	if _, found := c.Get("long"); !found {
+		t.Error("Expected key with long TTL to stay")
+	}
+	if stats := c.Stats(); stats.Expiration != ExpirationSliding {
+		t.Errorf("Expected sliding expiration in stats, got %s", stats.Expiration)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	base := time.Second
+	for i := 0; i < 1000; i++ {
+		d := jitter(base)
+		if d < 800*time.Millisecond || d > 1200*time.Millisecond {
+			t.Fatalf("Jittered interval %v is outside ±20%% of %v", d, base)
+		}
+	}
+
+	if d := jitter(time.Nanosecond); d != time.Nanosecond {
+		t.Errorf("Expected tiny interval unchanged, got %v", d)
+	}
+}
@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SaveHotKeys атомарно записывает ключи в файл, по одному на строку
+func SaveHotKeys(path string, keys []string) error {
+	return writeFileAtomic(path, func(w *bufio.Writer) error {
+		for _, key := range keys {
+			w.WriteString(key)
+			w.WriteByte('\n')
+		}
+		return nil
+	})
+}
+
+// LoadHotKeys читает ключи, сохраненные SaveHotKeys. Отсутствие файла
+// не считается ошибкой - возвращается пустой список.
+func LoadHotKeys(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var keys []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if key := strings.TrimSpace(scanner.Text()); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys, scanner.Err()
+}
+
+// writeFileAtomic записывает файл через временный файл в том же каталоге
+// и переименование, поэтому при падении процесса старый файл остается целым
+func writeFileAtomic(path string, write func(w *bufio.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	if err := write(w); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,215 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+	"wb-service/internal/interfaces"
+	"wb-service/models"
+)
+
+// immutabilityCaches возвращает все локальные реализации кэша со всеми политиками
+func immutabilityCaches() map[string]func() interfaces.Cache {
+	caches := map[string]func() interfaces.Cache{
+		"sharded": func() interfaces.Cache {
+			return NewShardedCache(context.Background(), 4, Options{Capacity: 100, TTL: time.Hour})
+		},
+	}
+	for _, policy := range []string{PolicyLRU, PolicyLFU, PolicyARC, PolicyTinyLFU} {
+		caches["lru/"+policy] = func() interfaces.Cache {
+			return NewLRUCacheWithOptions(context.Background(), Options{Capacity: 100, TTL: time.Hour, Policy: policy})
+		}
+	}
+	return caches
+}
+
+func TestCache_ImmutableEntries(t *testing.T) {
+	for name, newCache := range immutabilityCaches() {
+		t.Run(name, func(t *testing.T) {
+			c := newCache()
+			defer c.Close()
+
+			order := createTestOrderForLoadFromDB("immutable")
+			c.Set(order.OrderUID, order)
+
+			// Изменение исходного заказа после Set не должно попасть в кэш
+			order.TrackNumber = "mutated"
+			order.Items[0].Name = "mutated"
+
+			got, found := c.Get("immutable")
+			if !found {
+				t.Fatal("Expected to find order")
+			}
+			if got.TrackNumber != "LOAD_TRACK_immutable" || got.Items[0].Name == "mutated" {
+				t.Errorf("Cache entry changed through the original pointer: %+v", got)
+			}
+
+			// Изменение полученного заказа не должно попасть в кэш
+			got.Delivery.Name = "mutated"
+			got.Items[0].Price = -1
+			got.Items = nil
+
+			again, _ := c.Get("immutable")
+			if again == got {
+				t.Error("Expected Get to return a new copy each time")
+			}
+			if again.Delivery.Name == "mutated" || len(again.Items) != 1 || again.Items[0].Price == -1 {
+				t.Errorf("Cache entry changed through the returned pointer: %+v", again)
+			}
+		})
+	}
+}
+
+func TestCache_ImmutableSnapshotEntries(t *testing.T) {
+	c := NewLRUCache(10, time.Hour)
+	defer c.Close()
+
+	c.Set("snap", createTestOrderForLoadFromDB("snap"))
+	entries := c.Entries()
+	entries[0].Order.Items[0].Name = "mutated"
+
+	if got, _ := c.Get("snap"); got.Items[0].Name == "mutated" {
+		t.Error("Cache entry changed through the snapshot entry")
+	}
+
+	restored := createTestOrderForLoadFromDB("restored")
+	c.Restore([]interfaces.CacheEntry{{Key: "restored", Order: restored, Timestamp: time.Now()}})
+	restored.Items[0].Name = "mutated"
+
+	if got, _ := c.Get("restored"); got.Items[0].Name == "mutated" {
+		t.Error("Cache entry changed through the restored order")
+	}
+}
+
+func TestCache_ImmutableLoadedOrders(t *testing.T) {
+	c := NewLRUCache(10, time.Hour)
+	defer c.Close()
+
+	loaded := createTestOrderForLoadFromDB("loaded")
+	order, err := c.GetOrLoad("loaded", func(string) (*models.Order, error) {
+		return loaded, nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	order.Items[0].Name = "mutated"
+	loaded.TrackNumber = "mutated"
+
+	got, _ := c.Get("loaded")
+	if got.Items[0].Name == "mutated" || got.TrackNumber == "mutated" {
+		t.Errorf("Cache entry changed through the loaded order: %+v", got)
+	}
+}
+
+// TestCache_ConcurrentMutations предназначен для запуска с -race: читатели
+// изменяют полученные заказы, пока писатели перезаписывают те же ключи
+func TestCache_ConcurrentMutations(t *testing.T) {
+	const (
+		workers    = 8
+		iterations = 500
+		keys       = 16
+	)
+
+	for name, newCache := range immutabilityCaches() {
+		t.Run(name, func(t *testing.T) {
+			c := newCache()
+			defer c.Close()
+
+			for i := 0; i < keys; i++ {
+				key := fmt.Sprintf("key_%d", i)
+				c.Set(key, createTestOrderForLoadFromDB(key))
+			}
+
+			var wg sync.WaitGroup
+			for w := 0; w < workers; w++ {
+				wg.Add(2)
+
+				go func(w int) {
+					defer wg.Done()
+					order := createTestOrderForLoadFromDB("writer")
+					for i := 0; i < iterations; i++ {
+						key := fmt.Sprintf("key_%d", (w+i)%keys)
+						order.OrderUID = key
+						order.TrackNumber = "LOAD_TRACK_" + key
+						// Писатель переиспользует один и тот же заказ между Set
+						order.Items[0].Name = fmt.Sprintf("writer_%d_%d", w, i)
+						c.Set(key, order)
+					}
+				}(w)
+
+				go func(w int) {
+					defer wg.Done()
+					for i := 0; i < iterations; i++ {
+						key := fmt.Sprintf("key_%d", (w*3+i)%keys)
+						order, found := c.Get(key)
+						if !found {
+							continue
+						}
+						if order.OrderUID != key || order.TrackNumber != "LOAD_TRACK_"+key {
+							t.Errorf("Inconsistent order for %s: uid=%s track=%s", key, order.OrderUID, order.TrackNumber)
+							return
+						}
+						order.TrackNumber = "reader"
+						order.Items[0].Name = "reader"
+						order.Items = append(order.Items, models.Item{})
+					}
+				}(w)
+			}
+			wg.Wait()
+
+			for i := 0; i < keys; i++ {
+				key := fmt.Sprintf("key_%d", i)
+				order, found := c.Get(key)
+				if !found {
+					continue
+				}
+				if order.TrackNumber == "reader" || len(order.Items) != 1 || order.Items[0].Name == "reader" {
+					t.Errorf("Cache entry %s corrupted by a reader: %+v", key, order)
+				}
+			}
+		})
+	}
+}
+
+func TestReadThrough_SharedLoadsGetCopies(t *testing.T) {
+	c := NewLRUCache(10, time.Hour)
+	defer c.Close()
+
+	release := make(chan struct{})
+	load := func(key string) (*models.Order, error) {
+		<-release
+		return createTestOrderForLoadFromDB(key), nil
+	}
+
+	const callers = 4
+	results := make([]*models.Order, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			order, err := c.GetOrLoad("shared", load)
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+			results[i] = order
+			order.Items[0].Name = fmt.Sprintf("caller_%d", i)
+		}(i)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for i := 0; i < callers; i++ {
+		for j := i + 1; j < callers; j++ {
+			if results[i] != nil && results[i] == results[j] {
+				t.Errorf("Callers %d and %d share the same order pointer", i, j)
+			}
+		}
+	}
+}
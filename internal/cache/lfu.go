@@ -0,0 +1,151 @@
+package cache
+
+import (
+	"container/list"
+	"sort"
+)
+
+// lfuEntry - ключ и количество обращений к нему
+type lfuEntry struct {
+	key  string
+	freq int
+	elem *list.Element
+}
+
+// lfuPolicy вытесняет наименее часто используемый ключ; среди ключей
+// с одинаковой частотой - наименее давно использованный. Access, Insert и
+// Remove выполняются за O(1). Evict обычно тоже O(1), но если minFreq
+// устарел после Remove или единственный ключ минимальной частоты только
+// что добавлен, он перебирает частоты: O(число различных частот).
+type lfuPolicy struct {
+	entries map[string]*lfuEntry
+	freqs   map[int]*list.List // частота -> ключи, от свежих к старым
+	minFreq int
+	fresh   string // последний добавленный ключ, вытесняется в последнюю очередь
+}
+
+func newLFUPolicy() *lfuPolicy {
+	return &lfuPolicy{
+		entries: make(map[string]*lfuEntry),
+		freqs:   make(map[int]*list.List),
+	}
+}
+
+func (p *lfuPolicy) Name() string {
+	return PolicyLFU
+}
+
+func (p *lfuPolicy) Access(key string, hit bool) {
+	e, ok := p.entries[key]
+	if !ok {
+		return
+	}
+
+	p.unlink(e)
+	e.freq++
+	p.link(e)
+}
+
+func (p *lfuPolicy) Insert(key string) {
+	e := &lfuEntry{key: key, freq: 1}
+	p.entries[key] = e
+	p.link(e)
+	p.minFreq = 1
+	p.fresh = key
+}
+
+func (p *lfuPolicy) Remove(key string) {
+	if e, ok := p.entries[key]; ok {
+		p.unlink(e)
+		delete(p.entries, key)
+	}
+}
+
+func (p *lfuPolicy) Evict() (string, bool) {
+	if len(p.entries) == 0 {
+		return "", false
+	}
+
+	bucket, ok := p.freqs[p.minFreq]
+	if !ok {
+		// minFreq устарел после Remove - ищем минимум заново
+		p.minFreq = 0
+		for freq := range p.freqs {
+			if p.minFreq == 0 || freq < p.minFreq {
+				p.minFreq = freq
+			}
+		}
+		bucket = p.freqs[p.minFreq]
+	}
+
+	// Новый ключ всегда имеет минимальную частоту; вытеснять его сразу
+	// после вставки бессмысленно, поэтому берем следующую по частоте жертву
+	e := bucket.Back().Value.(*lfuEntry)
+	if e.key == p.fresh && len(p.entries) > 1 {
+		if bucket.Len() > 1 {
+			e = bucket.Back().Prev().Value.(*lfuEntry)
+		} else {
+			e = p.freqs[p.nextFreq(e.freq)].Back().Value.(*lfuEntry)
+		}
+	}
+
+	p.unlink(e)
+	delete(p.entries, e.key)
+	return e.key, true
+}
+
+func (p *lfuPolicy) Keys() []string {
+	freqs := make([]int, 0, len(p.freqs))
+	for freq := range p.freqs {
+		freqs = append(freqs, freq)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(freqs)))
+
+	keys := make([]string, 0, len(p.entries))
+	for _, freq := range freqs {
+		for elem := p.freqs[freq].Front(); elem != nil; elem = elem.Next() {
+			keys = append(keys, elem.Value.(*lfuEntry).key)
+		}
+	}
+	return keys
+}
+
+func (p *lfuPolicy) Reset() {
+	p.entries = make(map[string]*lfuEntry)
+	p.freqs = make(map[int]*list.List)
+	p.minFreq = 0
+	p.fresh = ""
+}
+
+// nextFreq возвращает наименьшую частоту больше freq
+func (p *lfuPolicy) nextFreq(freq int) int {
+	next := 0
+	for f := range p.freqs {
+		if f > freq && (next == 0 || f < next) {
+			next = f
+		}
+	}
+	return next
+}
+
+// link добавляет ключ в список своей частоты
+func (p *lfuPolicy) link(e *lfuEntry) {
+	bucket, ok := p.freqs[e.freq]
+	if !ok {
+		bucket = list.New()
+		p.freqs[e.freq] = bucket
+	}
+	e.elem = bucket.PushFront(e)
+}
+
+// unlink убирает ключ из списка его частоты
+func (p *lfuPolicy) unlink(e *lfuEntry) {
+	bucket := p.freqs[e.freq]
+	bucket.Remove(e.elem)
+	if bucket.Len() == 0 {
+		delete(p.freqs, e.freq)
+		if p.minFreq == e.freq {
+			p.minFreq++
+		}
+	}
+}
@@ -1,40 +1,142 @@
 package cache
 
 import (
-	"container/list"
+	"context"
+	"math/rand/v2"
 	"sync"
 	"time"
 	"wb-service/internal/interfaces"
 	"wb-service/models"
 )
 
+// Режимы истечения записей
+const (
+	// ExpirationAbsolute - запись истекает через TTL после записи, чтения срок не продлевают
+	ExpirationAbsolute = "absolute"
+	// ExpirationSliding - каждое успешное чтение продлевает запись еще на TTL
+	ExpirationSliding = "sliding"
+)
+
+// Специальные значения TTL для SetWithTTL
+const (
+	DefaultExpiration time.Duration = 0  // TTL кэша по умолчанию
+	NoExpiration      time.Duration = -1 // запись не истекает
+)
+
+// DefaultCleanupInterval - период очистки, если TTL кэша не задан,
+// но отдельные записи могут иметь свой TTL
+const DefaultCleanupInterval = time.Minute
+
+// cleanupJitter - доля, на которую случайно отклоняется период очистки,
+// чтобы очистки шардов и реплик не совпадали по времени
+const cleanupJitter = 0.2
+
 // CacheItem представляет элемент кэша с временной меткой
 type CacheItem struct {
 	Key       string
 	Value     *models.Order
-	Timestamp time.Time
+	Timestamp time.Time     // время записи
+	ExpiresAt time.Time     // момент истечения, нулевое значение - не истекает
+	TTL       time.Duration // время жизни записи, 0 - не истекает
+	Size      int64         // оценка занимаемой памяти, см. EstimateOrderSize
+}
+
+// expired сообщает, истекла ли запись к моменту now
+func (i *CacheItem) expired(now time.Time) bool {
+	return !i.ExpiresAt.IsZero() && now.After(i.ExpiresAt)
 }
 
-// LRUCache реализует LRU кэш с поддержкой TTL
+// expiresAt возвращает момент истечения записи с TTL ttl, сделанной в момент from
+func expiresAt(from time.Time, ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return from.Add(ttl)
+}
+
+// Options задает параметры кэша. Нулевое значение лимита означает
+// отсутствие ограничения; если заданы оба лимита, соблюдаются оба.
+type Options struct {
+	Capacity int           // максимальное количество заказов
+	MaxBytes int64         // максимальный оценочный объем заказов в байтах
+	TTL      time.Duration // время жизни элемента, 0 - без ограничения
+	Policy   string        // политика вытеснения (lru, lfu, arc, tinylfu), по умолчанию lru
+
+	// Expiration - режим истечения (absolute, sliding), по умолчанию absolute
+	Expiration string
+	// CleanupInterval - средний период фоновой очистки, 0 - TTL/2
+	// или DefaultCleanupInterval без TTL
+	CleanupInterval time.Duration
+
+	// NegativeTTL - сколько GetOrLoad помнит ненайденные ключи, 0 - не помнить
+	NegativeTTL time.Duration
+}
+
+// LRUCache реализует кэш с поддержкой TTL. Несмотря на имя, порядок
+// вытеснения определяет Policy; по умолчанию это LRU.
 type LRUCache struct {
 	mutex    sync.RWMutex
 	capacity int
+	maxBytes int64
+	bytes    int64 // текущий оценочный объем записей
 	ttl      time.Duration
-	items    map[string]*list.Element
-	evictList *list.List
+	sliding  bool          // чтения продлевают срок жизни записей
+	cleanup  time.Duration // средний период фоновой очистки
+	items    map[string]*CacheItem
+	policy   Policy
+	stats    interfaces.CacheStats
+	loader   *readThrough
+
+	cancel    context.CancelFunc // останавливает горутину очистки
+	done      chan struct{}      // закрывается после остановки горутины очистки
+	closeOnce sync.Once
 }
 
 // NewLRUCache создает новый LRU кэш
 func NewLRUCache(capacity int, ttl time.Duration) *LRUCache {
+	return NewLRUCacheWithContext(context.Background(), capacity, ttl)
+}
+
+// NewLRUCacheWithContext создает новый LRU кэш, фоновая очистка которого
+// останавливается при отмене ctx или вызове Close
+func NewLRUCacheWithContext(ctx context.Context, capacity int, ttl time.Duration) *LRUCache {
+	return NewLRUCacheWithOptions(ctx, Options{Capacity: capacity, TTL: ttl})
+}
+
+// NewLRUCacheWithOptions создает кэш с ограничением по количеству
+// и/или по объему памяти. Неизвестная политика заменяется на LRU,
+// неизвестный режим истечения - на absolute.
+func NewLRUCacheWithOptions(ctx context.Context, opts Options) *LRUCache {
+	policy, err := NewPolicy(opts.Policy, opts.Capacity)
+	if err != nil {
+		policy = newLRUPolicy()
+	}
+
+	cleanup := opts.CleanupInterval
+	if cleanup <= 0 {
+		cleanup = DefaultCleanupInterval
+		if opts.TTL > 0 {
+			cleanup = opts.TTL / 2
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
 	c := &LRUCache{
-		capacity:  capacity,
-		ttl:       ttl,
-		items:     make(map[string]*list.Element),
-		evictList: list.New(),
+		capacity: opts.Capacity,
+		maxBytes: opts.MaxBytes,
+		ttl:      opts.TTL,
+		sliding:  opts.Expiration == ExpirationSliding,
+		cleanup:  cleanup,
+		items:    make(map[string]*CacheItem),
+		policy:   policy,
+		loader:   newReadThrough(opts.NegativeTTL),
+		cancel:   cancel,
+		done:     make(chan struct{}),
 	}
 
 	// Запускаем горутину для очистки устаревших элементов
-	go c.cleanupExpired()
+	go c.cleanupExpired(ctx)
 
 	return c
 }
@@ -44,84 +146,331 @@ func (c *LRUCache) Get(key string) (*models.Order, bool) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	if elem, ok := c.items[key]; ok {
-		item := elem.Value.(*CacheItem)
-
+	if item, ok := c.items[key]; ok {
 		// Проверяем, не истек ли TTL
-		if c.ttl > 0 && time.Since(item.Timestamp) > c.ttl {
-			c.removeElement(elem)
+		now := time.Now()
+		if item.expired(now) {
+			c.removeItem(item)
+			c.stats.Expirations++
+			c.stats.Misses++
+			c.policy.Access(key, false)
 			return nil, false
 		}
 
-		// Перемещаем элемент в начало списка (most recently used)
-		c.evictList.MoveToFront(elem)
-		return item.Value, true
+		if c.sliding {
+			item.ExpiresAt = expiresAt(now, item.TTL)
+		}
+
+		c.policy.Access(key, true)
+		c.stats.Hits++
+		return item.Value.Clone(), true
 	}
 
+	c.stats.Misses++
+	c.policy.Access(key, false)
 	return nil, false
 }
 
-// Set добавляет значение в кэш
+// GetOrLoad получает значение из кэша или загружает его через load
+func (c *LRUCache) GetOrLoad(key string, load interfaces.OrderLoader) (*models.Order, error) {
+	return c.loader.getOrLoad(key, c.Get, c.Set, load)
+}
+
+// Set добавляет значение в кэш с TTL кэша
 func (c *LRUCache) Set(key string, order *models.Order) {
+	c.SetWithTTL(key, order, DefaultExpiration)
+}
+
+// SetWithTTL добавляет значение в кэш с собственным TTL: DefaultExpiration -
+// TTL кэша, NoExpiration - запись не истекает
+func (c *LRUCache) SetWithTTL(key string, order *models.Order, ttl time.Duration) {
+	c.loader.forget(key)
+	ttl = c.resolveTTL(ttl)
+
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
+	// Кэш хранит собственную копию, чтобы изменения заказа вызывающим
+	// кодом не затрагивали закэшированное состояние
+	order = order.Clone()
+	size := entrySize(key, order)
+	now := time.Now()
+
 	// Если элемент уже существует, обновляем его
-	if elem, ok := c.items[key]; ok {
-		item := elem.Value.(*CacheItem)
+	if item, ok := c.items[key]; ok {
+		c.bytes += size - item.Size
 		item.Value = order
-		item.Timestamp = time.Now()
-		c.evictList.MoveToFront(elem)
+		item.Timestamp = now
+		item.ExpiresAt = expiresAt(now, ttl)
+		item.TTL = ttl
+		item.Size = size
+		c.policy.Access(key, true)
+		c.evictOverLimit()
 		return
 	}
 
-	// Создаем новый элемент
+	c.addItem(key, order, ttl, size, now)
+}
+
+// SetIfAbsent добавляет значение с TTL кэша, только если ключа нет или
+// его запись истекла. Существующая запись не обновляется и не
+// продвигается в порядке вытеснения.
+func (c *LRUCache) SetIfAbsent(key string, order *models.Order) bool {
+	c.loader.forget(key)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	if item, ok := c.items[key]; ok {
+		if !item.expired(now) {
+			return false
+		}
+		c.removeItem(item)
+		c.stats.Expirations++
+	}
+
+	order = order.Clone()
+	c.addItem(key, order, c.ttl, entrySize(key, order), now)
+	return true
+}
+
+// addItem создает новый элемент и вытесняет лишние, если превышены лимиты
+func (c *LRUCache) addItem(key string, order *models.Order, ttl time.Duration, size int64, now time.Time) {
 	item := &CacheItem{
 		Key:       key,
 		Value:     order,
-		Timestamp: time.Now(),
+		Timestamp: now,
+		ExpiresAt: expiresAt(now, ttl),
+		TTL:       ttl,
+		Size:      size,
 	}
 
-	// Добавляем в начало списка
-	elem := c.evictList.PushFront(item)
-	c.items[key] = elem
+	c.items[key] = item
+	c.bytes += size
+	c.policy.Insert(key)
+
+	// Если превышены лимиты, политика выбирает, что вытеснить
+	c.evictOverLimit()
+}
+
+// Delete удаляет значение из кэша. Возвращает false, если ключа не было
+func (c *LRUCache) Delete(key string) bool {
+	c.loader.invalidate(key)
 
-	// Если превышена емкость, удаляем последний элемент
-	if c.evictList.Len() > c.capacity {
-		c.removeOldest()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	item, ok := c.items[key]
+	if !ok {
+		return false
 	}
+
+	c.removeItem(item)
+	return true
 }
 
-// LoadFromDB загружает данные из базы данных в кэш
+// LoadFromDB загружает в кэш самые свежие заказы из базы данных.
+// Заказы читаются страницами, пока кэш не заполнится.
 func (c *LRUCache) LoadFromDB(db interfaces.Database) error {
 	if db == nil {
 		return nil // Просто возвращаем без ошибки для nil db
 	}
 
-	orders, err := db.GetAllOrders()
-	if err != nil {
-		return err
+	start := time.Now()
+
+	loaded, err := loadRecent(db, c.capacity, DefaultWarmupPageSize, c.loadOrders)
+	c.finishLoad(loaded, start)
+
+	return err
+}
+
+// finishLoad сохраняет статистику загрузки из БД.
+// start - момент начала загрузки.
+func (c *LRUCache) finishLoad(loaded int, start time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.stats.LoadedEntries = loaded
+	c.stats.LoadDuration = time.Since(start)
+	c.stats.LoadedAt = time.Now()
+}
+
+// loadOrders добавляет в кэш заказы, которых в нем еще нет, пока они
+// укладываются в лимиты. Возвращает количество добавленных и false, если
+// очередной заказ не поместился. Существующая запись не перезаписывается:
+// ее мог записать consumer, а строка из БД может быть старее. Повторы
+// ключа в orders (страницы одной загрузки могут пересекаться) пропускаются.
+func (c *LRUCache) loadOrders(orders []models.Order) (int, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	loaded := 0
+	for i := range orders {
+		key := orders[i].OrderUID
+		if item, ok := c.items[key]; ok {
+			if !item.expired(now) {
+				continue
+			}
+			c.removeItem(item)
+			c.stats.Expirations++
+		}
+
+		if c.capacity > 0 && len(c.items) >= c.capacity {
+			return loaded, false
+		}
+		order := orders[i].Clone()
+		size := entrySize(key, order)
+		if c.maxBytes > 0 && c.bytes+size > c.maxBytes {
+			return loaded, false
+		}
+
+		c.items[key] = &CacheItem{
+			Key:       key,
+			Value:     order,
+			Timestamp: now,
+			ExpiresAt: expiresAt(now, c.ttl),
+			TTL:       c.ttl,
+			Size:      size,
+		}
+		c.bytes += size
+		c.policy.Insert(key)
+		loaded++
 	}
 
+	return loaded, true
+}
+
+// HotKeys возвращает до n ключей от наиболее ценных к наименее ценным
+// с точки зрения политики вытеснения. n <= 0 - все ключи.
+func (c *LRUCache) HotKeys(n int) []string {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	for _, order := range orders {
-		if len(c.items) >= c.capacity {
-			break
+	keys := c.policy.Keys()
+	if n > 0 && len(keys) > n {
+		keys = keys[:n]
+	}
+	return keys
+}
+
+// Entries возвращает неустаревшие записи от наиболее ценных к наименее
+// ценным для сохранения снимка
+func (c *LRUCache) Entries() []interfaces.CacheEntry {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	keys := c.policy.Keys()
+	entries := make([]interfaces.CacheEntry, 0, len(keys))
+	for _, key := range keys {
+		item := c.items[key]
+		if item.expired(now) {
+			continue
+		}
+
+		ttl := item.TTL
+		if ttl <= 0 {
+			ttl = NoExpiration
+		}
+		entries = append(entries, interfaces.CacheEntry{
+			Key:       key,
+			Order:     item.Value.Clone(),
+			Timestamp: item.Timestamp,
+			ExpiresAt: item.ExpiresAt,
+			TTL:       ttl,
+		})
+	}
+	return entries
+}
+
+// Restore восстанавливает записи снимка. Устаревшие по TTL записи
+// пропускаются, из остальных берутся самые ценные, которые укладываются
+// в лимиты. Они добавляются от менее ценных к более ценным, чтобы политика
+// вытеснения получила тот же порядок, что был при сохранении.
+func (c *LRUCache) Restore(entries []interfaces.CacheEntry) int {
+	start := time.Now()
+
+	c.mutex.Lock()
+
+	fit := make([]*CacheItem, 0, len(entries))
+	count, bytes := len(c.items), c.bytes
+	for _, entry := range entries {
+		if entry.Order == nil {
+			continue
 		}
 
-		item := &CacheItem{
-			Key:       order.OrderUID,
-			Value:     &order,
-			Timestamp: time.Now(),
+		ttl, expires := c.entryExpiry(entry)
+		if !expires.IsZero() && start.After(expires) {
+			continue
+		}
+		if _, ok := c.items[entry.Key]; ok {
+			continue
 		}
 
-		elem := c.evictList.PushFront(item)
-		c.items[order.OrderUID] = elem
+		size := entrySize(entry.Key, entry.Order)
+		if c.capacity > 0 && count >= c.capacity {
+			break
+		}
+		if c.maxBytes > 0 && bytes+size > c.maxBytes {
+			break
+		}
+
+		fit = append(fit, &CacheItem{
+			Key:       entry.Key,
+			Value:     entry.Order.Clone(),
+			Timestamp: entry.Timestamp,
+			ExpiresAt: expires,
+			TTL:       ttl,
+			Size:      size,
+		})
+		count++
+		bytes += size
 	}
 
-	return nil
+	for i := len(fit) - 1; i >= 0; i-- {
+		item := fit[i]
+		c.items[item.Key] = item
+		c.bytes += item.Size
+		c.policy.Insert(item.Key)
+	}
+
+	c.mutex.Unlock()
+
+	c.finishLoad(len(fit), start)
+	return len(fit)
+}
+
+// expiration возвращает режим истечения записей
+func (c *LRUCache) expiration() string {
+	if c.sliding {
+		return ExpirationSliding
+	}
+	return ExpirationAbsolute
+}
+
+// resolveTTL переводит TTL из SetWithTTL во время жизни записи, 0 - не истекает
+func (c *LRUCache) resolveTTL(ttl time.Duration) time.Duration {
+	switch {
+	case ttl == DefaultExpiration:
+		return c.ttl
+	case ttl < 0:
+		return 0
+	default:
+		return ttl
+	}
+}
+
+// entryExpiry возвращает время жизни и момент истечения записи снимка.
+// У записей без TTL (снимки до появления TTL отдельных записей) срок
+// отсчитывается от времени записи по TTL кэша.
+func (c *LRUCache) entryExpiry(entry interfaces.CacheEntry) (time.Duration, time.Time) {
+	ttl := c.resolveTTL(entry.TTL)
+	if entry.TTL == DefaultExpiration || entry.ExpiresAt.IsZero() {
+		return ttl, expiresAt(entry.Timestamp, ttl)
+	}
+	return ttl, entry.ExpiresAt
 }
 
 // Size возвращает текущий размер кэша
@@ -131,56 +480,117 @@ func (c *LRUCache) Size() int {
 	return len(c.items)
 }
 
+// Stats возвращает статистику работы кэша
+func (c *LRUCache) Stats() interfaces.CacheStats {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	stats := c.stats
+	stats.Size = len(c.items)
+	stats.Capacity = c.capacity
+	stats.Bytes = c.bytes
+	stats.MaxBytes = c.maxBytes
+	stats.Policy = c.policy.Name()
+	stats.Expiration = c.expiration()
+	c.loader.addStats(&stats)
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
 // Clear очищает кэш
 func (c *LRUCache) Clear() {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	c.items = make(map[string]*list.Element)
-	c.evictList.Init()
+	c.items = make(map[string]*CacheItem)
+	c.policy.Reset()
+	c.bytes = 0
+	c.loader.reset()
 }
 
-// removeOldest удаляет самый старый элемент
-func (c *LRUCache) removeOldest() {
-	elem := c.evictList.Back()
-	if elem != nil {
-		c.removeElement(elem)
+// Close останавливает фоновую очистку и дожидается ее завершения.
+// Кэш остается рабочим, устаревшие элементы удаляются при чтении.
+// Повторные вызовы безопасны.
+func (c *LRUCache) Close() error {
+	c.closeOnce.Do(c.cancel)
+	<-c.done
+	return nil
+}
+
+// overLimit сообщает, превышен ли хотя бы один из лимитов кэша
+func (c *LRUCache) overLimit() bool {
+	if c.capacity > 0 && len(c.items) > c.capacity {
+		return true
 	}
+	return c.maxBytes > 0 && c.bytes > c.maxBytes
 }
 
-// removeElement удаляет элемент из кэша
-func (c *LRUCache) removeElement(elem *list.Element) {
-	c.evictList.Remove(elem)
-	item := elem.Value.(*CacheItem)
+// evictOverLimit вытесняет выбранные политикой элементы, пока кэш не уложится в лимиты
+func (c *LRUCache) evictOverLimit() {
+	for c.overLimit() {
+		key, ok := c.policy.Evict()
+		if !ok {
+			return
+		}
+
+		if item, ok := c.items[key]; ok {
+			delete(c.items, key)
+			c.bytes -= item.Size
+			c.stats.Evictions++
+		}
+	}
+}
+
+// removeItem удаляет элемент из кэша и из политики вытеснения
+func (c *LRUCache) removeItem(item *CacheItem) {
 	delete(c.items, item.Key)
+	c.bytes -= item.Size
+	c.policy.Remove(item.Key)
 }
 
-// cleanupExpired периодически очищает устаревшие элементы
-func (c *LRUCache) cleanupExpired() {
-	if c.ttl <= 0 {
-		return
-	}
+// cleanupExpired периодически очищает устаревшие элементы до отмены ctx
+func (c *LRUCache) cleanupExpired(ctx context.Context) {
+	defer close(c.done)
+
+	// Период каждый раз случайно отклоняется от c.cleanup
+	timer := time.NewTimer(jitter(c.cleanup))
+	defer timer.Stop()
 
-	ticker := time.NewTicker(c.ttl / 2) // Проверяем каждые ttl/2
-	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
 
-	for range ticker.C {
 		c.mutex.Lock()
 
-		var toRemove []*list.Element
-		for elem := c.evictList.Back(); elem != nil; elem = elem.Prev() {
-			item := elem.Value.(*CacheItem)
-			if time.Since(item.Timestamp) > c.ttl {
-				toRemove = append(toRemove, elem)
-			} else {
-				break // Так как список отсортирован по времени, можем остановиться
+		// Порядок политики не совпадает с порядком истечения, поэтому
+		// проверяем все элементы
+		now := time.Now()
+		expired := 0
+		for _, item := range c.items {
+			if item.expired(now) {
+				c.removeItem(item)
+				expired++
 			}
 		}
-
-		for _, elem := range toRemove {
-			c.removeElement(elem)
-		}
+		c.stats.Expirations += uint64(expired)
+		c.loader.prune()
 
 		c.mutex.Unlock()
+
+		timer.Reset(jitter(c.cleanup))
 	}
-}
\ No newline at end of file
+}
+
+// jitter возвращает случайный период в пределах ±cleanupJitter от d
+func jitter(d time.Duration) time.Duration {
+	spread := int64(float64(d) * cleanupJitter)
+	if spread <= 0 {
+		return d
+	}
+	return d - time.Duration(spread) + time.Duration(rand.Int64N(2*spread+1))
+}
@@ -1,8 +1,12 @@
 package cache
 
 import (
+	"context"
+	"fmt"
+	"reflect"
 	"testing"
 	"time"
+	"wb-service/internal/interfaces"
 	"wb-service/models"
 
 	"gorm.io/driver/sqlite"
@@ -106,6 +110,49 @@ func TestLRUCache_Update(t *testing.T) {
 	}
 }
 
+func TestLRUCache_SetIfAbsent(t *testing.T) {
+	cache := NewLRUCache(2, time.Hour)
+	defer cache.Close()
+
+	cache.Set("test1", &models.Order{OrderUID: "test1", TrackNumber: "new"})
+	cache.Set("test2", &models.Order{OrderUID: "test2"})
+
+	if cache.SetIfAbsent("test1", &models.Order{OrderUID: "test1", TrackNumber: "old"}) {
+		t.Error("Expected SetIfAbsent to keep the existing entry")
+	}
+
+	// test1 не продвинут: следующая запись вытесняет именно его
+	cache.Set("test3", &models.Order{OrderUID: "test3"})
+	if _, found := cache.Get("test1"); found {
+		t.Error("Expected SetIfAbsent not to promote the existing entry")
+	}
+
+	if !cache.SetIfAbsent("test1", &models.Order{OrderUID: "test1", TrackNumber: "old"}) {
+		t.Error("Expected SetIfAbsent to add a missing entry")
+	}
+	if got, found := cache.Get("test1"); !found || got.TrackNumber != "old" {
+		t.Errorf("Expected added entry, got %v", got)
+	}
+}
+
+func TestLRUCache_SetIfAbsentExpired(t *testing.T) {
+	cache := NewLRUCache(3, 50*time.Millisecond)
+	defer cache.Close()
+
+	cache.Set("test1", &models.Order{OrderUID: "test1", TrackNumber: "expired"})
+	time.Sleep(80 * time.Millisecond)
+
+	if !cache.SetIfAbsent("test1", &models.Order{OrderUID: "test1", TrackNumber: "fresh"}) {
+		t.Fatal("Expected SetIfAbsent to replace an expired entry")
+	}
+	if got, found := cache.Get("test1"); !found || got.TrackNumber != "fresh" {
+		t.Errorf("Expected fresh entry, got %v", got)
+	}
+	if cache.Size() != 1 {
+		t.Errorf("Expected size 1, got %d", cache.Size())
+	}
+}
+
 func TestLRUCache_TTL(t *testing.T) {
 	cache := NewLRUCache(3, 100*time.Millisecond) // Very short TTL for testing
 
@@ -163,6 +210,297 @@ func TestLRUCache_ZeroTTL(t *testing.T) {
 	}
 }
 
+func TestLRUCache_Delete(t *testing.T) {
+	cache := NewLRUCache(3, time.Hour)
+
+	cache.Set("test1", &models.Order{OrderUID: "test1"})
+
+	if !cache.Delete("test1") {
+		t.Error("Expected Delete to report existing key")
+	}
+
+	if _, found := cache.Get("test1"); found {
+		t.Error("Expected test1 to be removed")
+	}
+
+	if cache.Delete("test1") {
+		t.Error("Expected Delete to report missing key")
+	}
+
+	if cache.Size() != 0 {
+		t.Errorf("Expected size 0 after delete, got %d", cache.Size())
+	}
+}
+
+func TestLRUCache_Stats(t *testing.T) {
+	t.Run("hits and misses", func(t *testing.T) {
+		cache := NewLRUCache(3, time.Hour)
+		cache.Set("test1", &models.Order{OrderUID: "test1"})
+
+		cache.Get("test1")
+		cache.Get("test1")
+		cache.Get("missing")
+
+		stats := cache.Stats()
+		if stats.Hits != 2 {
+			t.Errorf("Expected 2 hits, got %d", stats.Hits)
+		}
+		if stats.Misses != 1 {
+			t.Errorf("Expected 1 miss, got %d", stats.Misses)
+		}
+		if stats.HitRatio < 0.66 || stats.HitRatio > 0.67 {
+			t.Errorf("Expected hit ratio ~0.67, got %f", stats.HitRatio)
+		}
+		if stats.Size != 1 || stats.Capacity != 3 {
+			t.Errorf("Expected size 1 and capacity 3, got %d and %d", stats.Size, stats.Capacity)
+		}
+	})
+
+	t.Run("evictions", func(t *testing.T) {
+		cache := NewLRUCache(1, time.Hour)
+		cache.Set("test1", &models.Order{OrderUID: "test1"})
+		cache.Set("test2", &models.Order{OrderUID: "test2"})
+		cache.Set("test3", &models.Order{OrderUID: "test3"})
+
+		if stats := cache.Stats(); stats.Evictions != 2 {
+			t.Errorf("Expected 2 evictions, got %d", stats.Evictions)
+		}
+	})
+
+	t.Run("expirations", func(t *testing.T) {
+		cache := NewLRUCache(3, 50*time.Millisecond)
+		cache.Set("test1", &models.Order{OrderUID: "test1"})
+
+		time.Sleep(70 * time.Millisecond)
+		cache.Get("test1")
+
+		stats := cache.Stats()
+		if stats.Expirations != 1 {
+			t.Errorf("Expected 1 expiration, got %d", stats.Expirations)
+		}
+		if stats.Misses != 1 {
+			t.Errorf("Expected expired read to count as miss, got %d misses", stats.Misses)
+		}
+	})
+
+	t.Run("load time", func(t *testing.T) {
+		db := setupTestDatabase()
+		db.CreateOrder(createTestOrderForLoadFromDB("stats1"))
+		db.CreateOrder(createTestOrderForLoadFromDB("stats2"))
+
+		cache := NewLRUCache(10, time.Hour)
+		if err := cache.LoadFromDB(db); err != nil {
+			t.Fatalf("LoadFromDB failed: %v", err)
+		}
+
+		stats := cache.Stats()
+		if stats.LoadedEntries != 2 {
+			t.Errorf("Expected 2 loaded entries, got %d", stats.LoadedEntries)
+		}
+		if stats.LoadDuration <= 0 {
+			t.Error("Expected positive load duration")
+		}
+		if stats.LoadedAt.IsZero() {
+			t.Error("Expected LoadedAt to be set")
+		}
+	})
+}
+
+func TestLRUCache_Close(t *testing.T) {
+	t.Run("close stops cleanup goroutine", func(t *testing.T) {
+		cache := NewLRUCache(3, time.Hour)
+
+		if err := cache.Close(); err != nil {
+			t.Fatalf("Expected no error on close, got: %v", err)
+		}
+
+		select {
+		case <-cache.done:
+		default:
+			t.Error("Expected cleanup goroutine to be stopped after Close")
+		}
+	})
+
+	t.Run("close is idempotent", func(t *testing.T) {
+		cache := NewLRUCache(3, time.Hour)
+		cache.Close()
+
+		if err := cache.Close(); err != nil {
+			t.Errorf("Expected second Close to succeed, got: %v", err)
+		}
+	})
+
+	t.Run("cache works after close", func(t *testing.T) {
+		cache := NewLRUCache(3, time.Hour)
+		cache.Close()
+
+		cache.Set("test1", &models.Order{OrderUID: "test1"})
+		if _, found := cache.Get("test1"); !found {
+			t.Error("Expected cache to keep working after Close")
+		}
+	})
+
+	t.Run("close with zero TTL", func(t *testing.T) {
+		cache := NewLRUCache(3, 0)
+
+		if err := cache.Close(); err != nil {
+			t.Errorf("Expected no error on close, got: %v", err)
+		}
+	})
+}
+
+func TestNewLRUCacheWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cache := NewLRUCacheWithContext(ctx, 3, time.Hour)
+
+	cancel()
+
+	select {
+	case <-cache.done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected cleanup goroutine to stop after context cancellation")
+	}
+
+	// Close после отмены контекста не должен блокироваться
+	if err := cache.Close(); err != nil {
+		t.Errorf("Expected no error on close, got: %v", err)
+	}
+}
+
+func TestLRUCache_ByteBudget(t *testing.T) {
+	small := &models.Order{OrderUID: "small", Items: make([]models.Item, 1)}
+	large := &models.Order{OrderUID: "large", Items: make([]models.Item, 200)}
+	smallSize := entrySize(small.OrderUID, small)
+	largeSize := entrySize(large.OrderUID, large)
+
+	if largeSize <= smallSize*10 {
+		t.Fatalf("Expected order with 200 items to be much larger: %d vs %d", largeSize, smallSize)
+	}
+
+	t.Run("evicts until under byte budget", func(t *testing.T) {
+		cache := NewLRUCacheWithOptions(context.Background(), Options{MaxBytes: largeSize + smallSize})
+		defer cache.Close()
+
+		cache.Set("a", &models.Order{OrderUID: "a", Items: make([]models.Item, 1)})
+		cache.Set("b", &models.Order{OrderUID: "b", Items: make([]models.Item, 1)})
+		cache.Set(large.OrderUID, large)
+
+		// Для большого заказа пришлось вытеснить оба маленьких
+		if _, found := cache.Get("a"); found {
+			t.Error("Expected a to be evicted")
+		}
+		if _, found := cache.Get("b"); !found {
+			t.Error("Expected b to fit next to the large order")
+		}
+
+		stats := cache.Stats()
+		if stats.Bytes > stats.MaxBytes {
+			t.Errorf("Expected bytes %d to be within budget %d", stats.Bytes, stats.MaxBytes)
+		}
+		expected := largeSize + entrySize("b", &models.Order{OrderUID: "b", Items: make([]models.Item, 1)})
+		if stats.Bytes != expected {
+			t.Errorf("Expected bytes %d, got %d", expected, stats.Bytes)
+		}
+	})
+
+	t.Run("count limit disabled with byte budget only", func(t *testing.T) {
+		cache := NewLRUCacheWithOptions(context.Background(), Options{MaxBytes: smallSize * 100})
+		defer cache.Close()
+
+		for i := 0; i < 50; i++ {
+			key := fmt.Sprintf("order%02d", i)
+			cache.Set(key, &models.Order{OrderUID: key[:5], Items: make([]models.Item, 1)})
+		}
+
+		if cache.Size() != 50 {
+			t.Errorf("Expected 50 entries, got %d", cache.Size())
+		}
+	})
+
+	t.Run("both limits enforced", func(t *testing.T) {
+		cache := NewLRUCacheWithOptions(context.Background(), Options{Capacity: 2, MaxBytes: largeSize * 10})
+		defer cache.Close()
+
+		cache.Set("a", small)
+		cache.Set("b", small)
+		cache.Set("c", small)
+
+		if cache.Size() != 2 {
+			t.Errorf("Expected count limit 2, got %d", cache.Size())
+		}
+	})
+
+	t.Run("update adjusts bytes", func(t *testing.T) {
+		cache := NewLRUCacheWithOptions(context.Background(), Options{MaxBytes: largeSize * 2})
+		defer cache.Close()
+
+		cache.Set("key", small)
+		cache.Set("key", large)
+
+		if got := cache.Stats().Bytes; got != entrySize("key", large) {
+			t.Errorf("Expected bytes %d after update, got %d", entrySize("key", large), got)
+		}
+
+		cache.Delete("key")
+		if got := cache.Stats().Bytes; got != 0 {
+			t.Errorf("Expected 0 bytes after delete, got %d", got)
+		}
+	})
+
+	t.Run("clear resets bytes", func(t *testing.T) {
+		cache := NewLRUCacheWithOptions(context.Background(), Options{MaxBytes: largeSize * 2})
+		defer cache.Close()
+
+		cache.Set("key", large)
+		cache.Clear()
+
+		if got := cache.Stats().Bytes; got != 0 {
+			t.Errorf("Expected 0 bytes after clear, got %d", got)
+		}
+	})
+
+	t.Run("load from DB respects byte budget", func(t *testing.T) {
+		db := setupTestDatabase()
+		for i := 0; i < 5; i++ {
+			db.CreateOrder(createTestOrderForLoadFromDB(fmt.Sprintf("bytes%d", i)))
+		}
+
+		loaded, err := db.GetOrder("bytes0")
+		if err != nil {
+			t.Fatalf("Failed to get test order: %v", err)
+		}
+		one := entrySize(loaded.OrderUID, loaded.Clone())
+		cache := NewLRUCacheWithOptions(context.Background(), Options{MaxBytes: one*2 + one/2})
+		defer cache.Close()
+
+		if err := cache.LoadFromDB(db); err != nil {
+			t.Fatalf("LoadFromDB failed: %v", err)
+		}
+
+		if cache.Size() != 2 {
+			t.Errorf("Expected 2 orders to fit the budget, got %d", cache.Size())
+		}
+	})
+}
+
+func TestEstimateOrderSize(t *testing.T) {
+	if EstimateOrderSize(nil) != 0 {
+		t.Error("Expected zero size for nil order")
+	}
+
+	base := &models.Order{OrderUID: "uid"}
+	withStrings := &models.Order{OrderUID: "uid", Delivery: models.Delivery{Address: "a long delivery address"}}
+	withItems := &models.Order{OrderUID: "uid", Items: make([]models.Item, 3)}
+
+	if EstimateOrderSize(withStrings) != EstimateOrderSize(base)+int64(len(withStrings.Delivery.Address)) {
+		t.Error("Expected string contents to be counted")
+	}
+
+	if EstimateOrderSize(withItems) <= EstimateOrderSize(base) {
+		t.Error("Expected items to increase size")
+	}
+}
+
 type mockRepository struct {
 	db *gorm.DB
 }
@@ -188,6 +526,28 @@ func (m *mockRepository) GetAllOrders() ([]models.Order, error) {
 	return orders, err
 }
 
+func (m *mockRepository) GetRecentOrders(after interfaces.OrderCursor, limit int) ([]models.Order, error) {
+	query := m.db.Preload("Delivery").Preload("Payment").Preload("Items")
+	if after.OrderUID != "" {
+		query = query.Where("date_created < ? OR (date_created = ? AND order_uid > ?)",
+			after.DateCreated, after.DateCreated, after.OrderUID)
+	}
+	var orders []models.Order
+	err := query.Order("date_created DESC, order_uid").Limit(limit).Find(&orders).Error
+	return orders, err
+}
+
+func (m *mockRepository) GetOrdersByUIDs(orderUIDs []string) ([]models.Order, error) {
+	var orders []models.Order
+	for _, uid := range orderUIDs {
+		order, err := m.GetOrder(uid)
+		if err == nil {
+			orders = append(orders, *order)
+		}
+	}
+	return orders, nil
+}
+
 func (m *mockRepository) Close() error {
 	sqlDB, err := m.db.DB()
 	if err != nil {
@@ -403,4 +763,98 @@ func TestLRUCache_LoadFromDB(t *testing.T) {
 			t.Errorf("Expected cache to remain empty after nil DB, got size %d", cache.Size())
 		}
 	})
-}
\ No newline at end of file
+}
+
+// benchmarkParallel прогоняет смешанную нагрузку (90% чтений, 10% записей)
+// на прогретом кэше из b.RunParallel
+func benchmarkParallel(b *testing.B, c interfaces.Cache) {
+	const keyCount = 1024
+
+	keys := make([]string, keyCount)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("order_%d", i)
+		c.Set(keys[i], &models.Order{OrderUID: keys[i]})
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := keys[i%keyCount]
+			if i%10 == 0 {
+				c.Set(key, &models.Order{OrderUID: key})
+			} else {
+				c.Get(key)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkLRUCache_Parallel(b *testing.B) {
+	c := NewLRUCache(2048, time.Hour)
+	defer c.Close()
+	benchmarkParallel(b, c)
+}
+
+func BenchmarkShardedCache_Parallel(b *testing.B) {
+	for _, shards := range []int{4, 16, 64} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			c := NewShardedCache(context.Background(), shards, Options{Capacity: 2048, TTL: time.Hour})
+			defer c.Close()
+			benchmarkParallel(b, c)
+		})
+	}
+}
+
+func TestLRUCache_LoadDuplicates(t *testing.T) {
+	cache := NewLRUCache(10, time.Hour)
+	defer cache.Close()
+
+	order := createTestOrderForLoadFromDB("dup")
+	size := entrySize(order.OrderUID, order.Clone())
+
+	// Один и тот же заказ в странице и в повторных загрузках
+	for i := 0; i < 3; i++ {
+		cache.loadOrders([]models.Order{*order, *order})
+	}
+
+	if got := cache.Stats().Bytes; got != size {
+		t.Errorf("Expected %d bytes after repeated loads, got %d", size, got)
+	}
+	if keys := cache.HotKeys(0); !reflect.DeepEqual(keys, []string{"dup"}) {
+		t.Errorf("Expected single hot key, got %v", keys)
+	}
+
+	cache.Delete("dup")
+	if got := cache.Stats().Bytes; got != 0 {
+		t.Errorf("Expected 0 bytes after delete, got %d", got)
+	}
+	if keys := cache.HotKeys(0); len(keys) != 0 {
+		t.Errorf("Expected no hot keys after delete, got %v", keys)
+	}
+
+	t.Run("load does not overwrite newer entry", func(t *testing.T) {
+		db := setupTestDatabase()
+		db.CreateOrder(createTestOrderForLoadFromDB("fresh"))
+
+		newer := createTestOrderForLoadFromDB("fresh")
+		newer.TrackNumber = "NEWER"
+		cache.Set("fresh", newer)
+
+		if err := cache.LoadFromDB(db); err != nil {
+			t.Fatalf("LoadFromDB failed: %v", err)
+		}
+		if err := cache.LoadFromDB(db); err != nil {
+			t.Fatalf("LoadFromDB failed: %v", err)
+		}
+
+		cached, _ := cache.Get("fresh")
+		if cached == nil || cached.TrackNumber != "NEWER" {
+			t.Errorf("Expected entry set before load to be kept, got %+v", cached)
+		}
+		if got := cache.Stats().Bytes; got != entrySize("fresh", newer.Clone()) {
+			t.Errorf("Expected bytes of a single entry, got %d", got)
+		}
+	})
+}
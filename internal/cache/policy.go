@@ -0,0 +1,121 @@
+package cache
+
+import (
+	"container/list"
+	"fmt"
+	"strings"
+)
+
+// Имена поддерживаемых политик вытеснения
+const (
+	PolicyLRU      = "lru"
+	PolicyLFU      = "lfu"
+	PolicyARC      = "arc"
+	PolicyTinyLFU  = "tinylfu"
+	defaultGhostsN = 1000 // размер истории для ARC/W-TinyLFU, если лимит по количеству не задан
+)
+
+// Policy решает, какие ключи допускать в кэш и какие вытеснять.
+// Кэш хранит сами значения и вызывает методы политики под своей
+// блокировкой, поэтому реализации не обязаны быть потокобезопасными.
+type Policy interface {
+	// Name возвращает имя политики
+	Name() string
+	// Access отмечает чтение ключа; hit - был ли ключ в кэше
+	Access(key string, hit bool)
+	// Insert отмечает добавление нового ключа в кэш
+	Insert(key string)
+	// Remove забывает ключ, удаленный явно или по TTL
+	Remove(key string)
+	// Evict выбирает ключ для вытеснения и забывает его.
+	// Политика с фильтром допуска может вернуть только что добавленный ключ.
+	Evict() (string, bool)
+	// Keys возвращает ключи от наиболее ценных к наименее ценным
+	Keys() []string
+	// Reset забывает все ключи
+	Reset()
+}
+
+// NewPolicy создает политику по имени. capacity - ожидаемое количество
+// записей, используется политиками с историей обращений.
+func NewPolicy(name string, capacity int) (Policy, error) {
+	if capacity <= 0 {
+		capacity = defaultGhostsN
+	}
+
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case PolicyLRU, "":
+		return newLRUPolicy(), nil
+	case PolicyLFU:
+		return newLFUPolicy(), nil
+	case PolicyARC:
+		return newARCPolicy(capacity), nil
+	case PolicyTinyLFU, "w-tinylfu":
+		return newTinyLFUPolicy(capacity), nil
+	default:
+		return nil, fmt.Errorf("unknown cache policy %q", name)
+	}
+}
+
+// lruPolicy вытесняет наименее давно использованный ключ
+type lruPolicy struct {
+	ll    *list.List
+	elems map[string]*list.Element
+}
+
+func newLRUPolicy() *lruPolicy {
+	return &lruPolicy{
+		ll:    list.New(),
+		elems: make(map[string]*list.Element),
+	}
+}
+
+func (p *lruPolicy) Name() string {
+	return PolicyLRU
+}
+
+func (p *lruPolicy) Access(key string, hit bool) {
+	if elem, ok := p.elems[key]; ok {
+		p.ll.MoveToFront(elem)
+	}
+}
+
+func (p *lruPolicy) Insert(key string) {
+	p.elems[key] = p.ll.PushFront(key)
+}
+
+func (p *lruPolicy) Remove(key string) {
+	if elem, ok := p.elems[key]; ok {
+		p.ll.Remove(elem)
+		delete(p.elems, key)
+	}
+}
+
+func (p *lruPolicy) Evict() (string, bool) {
+	elem := p.ll.Back()
+	if elem == nil {
+		return "", false
+	}
+
+	key := elem.Value.(string)
+	p.ll.Remove(elem)
+	delete(p.elems, key)
+	return key, true
+}
+
+func (p *lruPolicy) Keys() []string {
+	return listKeys(nil, p.ll)
+}
+
+func (p *lruPolicy) Reset() {
+	p.ll.Init()
+	p.elems = make(map[string]*list.Element)
+}
+
+// listKeys дописывает ключи списка от начала к концу
+func listKeys(keys []string, ll *list.List) []string {
+	for elem := ll.Front(); elem != nil; elem = elem.Next() {
+		keys = append(keys, elem.Value.(string))
+	}
+	return keys
+}
@@ -0,0 +1,307 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+	"wb-service/models"
+)
+
+func TestNewPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"", PolicyLRU},
+		{"lru", PolicyLRU},
+		{"LFU", PolicyLFU},
+		{" arc ", PolicyARC},
+		{"tinylfu", PolicyTinyLFU},
+		{"w-tinylfu", PolicyTinyLFU},
+	}
+
+	for _, tt := range tests {
+		p, err := NewPolicy(tt.name, 10)
+		if err != nil {
+			t.Fatalf("NewPolicy(%q) returned error: %v", tt.name, err)
+		}
+		if p.Name() != tt.expected {
+			t.Errorf("NewPolicy(%q) = %s, expected %s", tt.name, p.Name(), tt.expected)
+		}
+	}
+
+	if _, err := NewPolicy("fifo", 10); err == nil {
+		t.Error("Expected error for unknown policy")
+	}
+}
+
+func TestLRUCache_UnknownPolicyFallsBackToLRU(t *testing.T) {
+	c := NewLRUCacheWithOptions(context.Background(), Options{Capacity: 2, Policy: "fifo"})
+	defer c.Close()
+
+	if policy := c.Stats().Policy; policy != PolicyLRU {
+		t.Errorf("Expected lru policy, got %s", policy)
+	}
+}
+
+func TestLFUPolicy_EvictsLeastFrequent(t *testing.T) {
+	c := NewLRUCacheWithOptions(context.Background(), Options{Capacity: 2, Policy: PolicyLFU})
+	defer c.Close()
+
+	c.Set("a", &models.Order{OrderUID: "a"})
+	c.Set("b", &models.Order{OrderUID: "b"})
+
+	// a читается чаще, b новее - LRU вытеснил бы a, LFU вытесняет b
+	c.Get("a")
+	c.Get("a")
+	c.Get("b")
+	c.Get("a")
+
+	c.Set("c", &models.Order{OrderUID: "c"})
+
+	if _, found := c.Get("a"); !found {
+		t.Error("Expected frequently used a to stay in cache")
+	}
+	if _, found := c.Get("b"); found {
+		t.Error("Expected b to be evicted")
+	}
+	if _, found := c.Get("c"); !found {
+		t.Error("Expected c to be in cache")
+	}
+}
+
+func TestLFUPolicy_TieBreaksByRecency(t *testing.T) {
+	p := newLFUPolicy()
+	p.Insert("a")
+	p.Insert("b")
+	p.Insert("c")
+
+	if key, _ := p.Evict(); key != "a" {
+		t.Errorf("Expected oldest key a to be evicted, got %s", key)
+	}
+
+	p.Access("b", true)
+	p.Insert("d")
+	if key, _ := p.Evict(); key != "c" {
+		t.Errorf("Expected c to be evicted, got %s", key)
+	}
+
+	// Только что добавленный ключ вытесняется последним
+	if key, _ := p.Evict(); key != "b" {
+		t.Errorf("Expected b to be evicted before fresh d, got %s", key)
+	}
+	if key, _ := p.Evict(); key != "d" {
+		t.Errorf("Expected d to be evicted last, got %s", key)
+	}
+	if _, ok := p.Evict(); ok {
+		t.Error("Expected empty policy to evict nothing")
+	}
+}
+
+func TestARCPolicy_ScanResistance(t *testing.T) {
+	c := NewLRUCacheWithOptions(context.Background(), Options{Capacity: 4, Policy: PolicyARC})
+	defer c.Close()
+
+	// Горячие ключи читаются повторно и попадают в t2
+	for _, key := range []string{"h1", "h2"} {
+		c.Set(key, &models.Order{OrderUID: key})
+		c.Get(key)
+	}
+
+	// Однократный проход по холодным ключам
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("scan%d", i)
+		c.Set(key, &models.Order{OrderUID: key})
+	}
+
+	for _, key := range []string{"h1", "h2"} {
+		if _, found := c.Get(key); !found {
+			t.Errorf("Expected hot key %s to survive the scan", key)
+		}
+	}
+	if c.Size() != 4 {
+		t.Errorf("Expected size 4, got %d", c.Size())
+	}
+}
+
+func TestARCPolicy_GhostHitAdaptsTarget(t *testing.T) {
+	p := newARCPolicy(2)
+	p.Insert("a")
+	p.Insert("b")
+
+	if key, _ := p.Evict(); key != "a" {
+		t.Fatalf("Expected a to be evicted, got %s", key)
+	}
+
+	// a в истории b1: повторная вставка увеличивает целевой размер t1
+	p.Insert("a")
+	if p.p == 0 {
+		t.Error("Expected ghost hit in b1 to increase target size of t1")
+	}
+	if e := p.entries["a"]; e == nil || e.where != arcT2 {
+		t.Error("Expected a to be moved to t2 after ghost hit")
+	}
+
+	keys := p.Keys()
+	if len(keys) != 2 || keys[0] != "a" {
+		t.Errorf("Expected t2 keys first, got %v", keys)
+	}
+}
+
+func TestTinyLFUPolicy_AdmissionFilter(t *testing.T) {
+	c := NewLRUCacheWithOptions(context.Background(), Options{Capacity: 10, Policy: PolicyTinyLFU})
+	defer c.Close()
+
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("hot%d", i)
+		c.Set(key, &models.Order{OrderUID: key})
+		for j := 0; j < 5; j++ {
+			c.Get(key)
+		}
+	}
+
+	// Новые ключи без истории обращений не должны вытеснять горячие
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("cold%d", i)
+		c.Set(key, &models.Order{OrderUID: key})
+	}
+
+	hot := 0
+	for i := 0; i < 10; i++ {
+		if _, found := c.Get(fmt.Sprintf("hot%d", i)); found {
+			hot++
+		}
+	}
+	if hot < 9 {
+		t.Errorf("Expected at least 9 hot keys to stay in cache, got %d", hot)
+	}
+	if c.Size() != 10 {
+		t.Errorf("Expected size 10, got %d", c.Size())
+	}
+}
+
+func TestCMSketch(t *testing.T) {
+	s := newCMSketch(64)
+
+	for i := 0; i < 20; i++ {
+		s.Increment("hot")
+	}
+	s.Increment("cold")
+
+	if est := s.Estimate("hot"); est != sketchMaxCounter {
+		t.Errorf("Expected hot estimate to saturate at %d, got %d", sketchMaxCounter, est)
+	}
+	if est := s.Estimate("cold"); est < 1 {
+		t.Errorf("Expected cold estimate >= 1, got %d", est)
+	}
+
+	// После sampleSize увеличений счетчики делятся пополам
+	for i := 0; i < s.sampleSize; i++ {
+		s.Increment(fmt.Sprintf("k%d", i))
+	}
+	if est := s.Estimate("hot"); est >= sketchMaxCounter {
+		t.Errorf("Expected hot estimate to decay after aging, got %d", est)
+	}
+
+	s.Reset()
+	if est := s.Estimate("hot"); est != 0 {
+		t.Errorf("Expected zero estimate after reset, got %d", est)
+	}
+}
+
+// Все политики должны соблюдать лимиты, TTL и корректно удалять ключи
+func TestPolicies_CacheInvariants(t *testing.T) {
+	for _, policy := range []string{PolicyLRU, PolicyLFU, PolicyARC, PolicyTinyLFU} {
+		t.Run(policy, func(t *testing.T) {
+			c := NewLRUCacheWithOptions(context.Background(), Options{Capacity: 50, TTL: time.Hour, Policy: policy})
+			defer c.Close()
+
+			rng := rand.New(rand.NewSource(1))
+			for i := 0; i < 5000; i++ {
+				key := fmt.Sprintf("order%d", rng.Intn(200))
+				if _, found := c.Get(key); !found {
+					c.Set(key, &models.Order{OrderUID: key})
+				}
+				if i%97 == 0 {
+					c.Delete(key)
+				}
+				if c.Size() > 50 {
+					t.Fatalf("Cache size %d exceeds capacity", c.Size())
+				}
+			}
+
+			stats := c.Stats()
+			if stats.Policy != policy {
+				t.Errorf("Expected policy %s in stats, got %s", policy, stats.Policy)
+			}
+			if stats.Evictions == 0 {
+				t.Error("Expected some evictions")
+			}
+
+			c.Clear()
+			if c.Size() != 0 {
+				t.Errorf("Expected empty cache after clear, got %d", c.Size())
+			}
+			c.Set("after-clear", &models.Order{OrderUID: "after-clear"})
+			if _, found := c.Get("after-clear"); !found && policy != PolicyTinyLFU {
+				t.Error("Expected key to be cached after clear")
+			}
+		})
+	}
+}
+
+// zipfKeys генерирует обращения к ключам с распределением Зипфа.
+// При scanEvery > 0 каждые scanEvery обращений вставляется проход
+// по scanLen уникальным ключам, которые больше не запрашиваются.
+func zipfKeys(n, keySpace, scanEvery, scanLen int) []string {
+	rng := rand.New(rand.NewSource(42))
+	zipf := rand.NewZipf(rng, 1.01, 1, uint64(keySpace-1))
+
+	keys := make([]string, 0, n)
+	scan := 0
+	for len(keys) < n {
+		if scanEvery > 0 && len(keys)%scanEvery == 0 && len(keys) > 0 {
+			for i := 0; i < scanLen && len(keys) < n; i++ {
+				keys = append(keys, fmt.Sprintf("scan%d", scan))
+				scan++
+			}
+		}
+		keys = append(keys, fmt.Sprintf("order%d", zipf.Uint64()))
+	}
+	return keys
+}
+
+// benchmarkHitRatio прогоняет нагрузку через кэш (cache-aside) и
+// сообщает долю попаданий в метрике hit%
+func benchmarkHitRatio(b *testing.B, keys []string) {
+	order := &models.Order{}
+
+	for _, policy := range []string{PolicyLRU, PolicyLFU, PolicyARC, PolicyTinyLFU} {
+		b.Run(policy, func(b *testing.B) {
+			var hits, total int
+			for i := 0; i < b.N; i++ {
+				c := NewLRUCacheWithOptions(context.Background(), Options{Capacity: 500, Policy: policy})
+				for _, key := range keys {
+					if _, found := c.Get(key); found {
+						hits++
+					} else {
+						c.Set(key, order)
+					}
+				}
+				total += len(keys)
+				c.Close()
+			}
+			b.ReportMetric(100*float64(hits)/float64(total), "hit%")
+		})
+	}
+}
+
+func BenchmarkPolicy_Zipf(b *testing.B) {
+	benchmarkHitRatio(b, zipfKeys(200000, 50000, 0, 0))
+}
+
+func BenchmarkPolicy_ZipfWithScans(b *testing.B) {
+	benchmarkHitRatio(b, zipfKeys(200000, 50000, 10000, 2000))
+}
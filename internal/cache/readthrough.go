@@ -0,0 +1,239 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"time"
+	"wb-service/internal/interfaces"
+	"wb-service/models"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// maxNegativeEntries ограничивает число ключей, закэшированных как
+// отсутствующие, чтобы перебор случайных UID не раздувал память
+const maxNegativeEntries = 10000
+
+// readThrough реализует GetOrLoad поверх Get/Set кэша: объединяет
+// одновременные загрузки одного ключа и помнит ненайденные ключи
+// в течение negativeTTL
+type readThrough struct {
+	group       singleflight.Group
+	negativeTTL time.Duration
+
+	// setMutex делает проверку, не устарела ли загрузка, и запись ее
+	// результата атомарными относительно invalidate
+	setMutex sync.Mutex
+
+	mutex        sync.Mutex
+	negative     map[string]time.Time    // ключ -> когда забыть
+	loading      map[string]*pendingLoad // выполняющиеся загрузки
+	loads        uint64
+	sharedLoads  uint64
+	negativeHits uint64
+}
+
+// pendingLoad - выполняющаяся загрузка ключа. stale означает, что ключ
+// удалили во время загрузки и ее результат нельзя класть в кэш.
+type pendingLoad struct {
+	stale bool
+}
+
+func newReadThrough(negativeTTL time.Duration) *readThrough {
+	return &readThrough{
+		negativeTTL: negativeTTL,
+		negative:    make(map[string]time.Time),
+		loading:     make(map[string]*pendingLoad),
+	}
+}
+
+// getOrLoad возвращает заказ через get, а при промахе - через load,
+// сохраняя результат через set
+func (r *readThrough) getOrLoad(
+	key string,
+	get func(key string) (*models.Order, bool),
+	set func(key string, order *models.Order),
+	load interfaces.OrderLoader,
+) (*models.Order, error) {
+	if order, found := get(key); found {
+		return order, nil
+	}
+
+	if r.isNegative(key) {
+		return nil, interfaces.ErrNotFound
+	}
+
+	v, err, shared := r.group.Do(key, func() (any, error) {
+		pending := r.begin(key)
+		defer r.finish(key, pending)
+
+		order, err := load(key)
+		switch {
+		case err == nil && order != nil:
+			// Загрузка могла прочитать строку до удаления заказа из БД;
+			// если ключ за это время удалили из кэша, результат не пишется
+			r.setMutex.Lock()
+			if !r.isStale(pending) {
+				set(key, order)
+			}
+			r.setMutex.Unlock()
+			return order, nil
+		case err == nil, errors.Is(err, interfaces.ErrNotFound):
+			r.markNegative(key)
+			return nil, interfaces.ErrNotFound
+		default:
+			return nil, err
+		}
+	})
+
+	if shared {
+		r.mutex.Lock()
+		r.sharedLoads++
+		r.mutex.Unlock()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	// Результат одной загрузки получают все ожидающие, поэтому каждому
+	// достается своя копия
+	return v.(*models.Order).Clone(), nil
+}
+
+// begin регистрирует загрузку ключа
+func (r *readThrough) begin(key string) *pendingLoad {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.loads++
+	pending := &pendingLoad{}
+	r.loading[key] = pending
+	return pending
+}
+
+// finish снимает регистрацию загрузки, если ключ не занят более новой
+func (r *readThrough) finish(key string, pending *pendingLoad) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.loading[key] == pending {
+		delete(r.loading, key)
+	}
+}
+
+func (r *readThrough) isStale(pending *pendingLoad) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return pending.stale
+}
+
+// isNegative сообщает, закэширован ли ключ как отсутствующий
+func (r *readThrough) isNegative(key string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	expires, ok := r.negative[key]
+	if !ok {
+		return false
+	}
+	if time.Now().After(expires) {
+		delete(r.negative, key)
+		return false
+	}
+
+	r.negativeHits++
+	return true
+}
+
+func (r *readThrough) markNegative(key string) {
+	if r.negativeTTL <= 0 {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	if len(r.negative) >= maxNegativeEntries {
+		r.pruneLocked(now)
+		if len(r.negative) >= maxNegativeEntries {
+			return
+		}
+	}
+	r.negative[key] = now.Add(r.negativeTTL)
+}
+
+// forget убирает ключ из отрицательного кэша, например когда заказ
+// появился в кэше через Set
+func (r *readThrough) forget(key string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.negative, key)
+}
+
+// invalidate забывает ключ перед удалением из кэша: убирает его из
+// отрицательного кэша и не дает выполняющейся загрузке записать в кэш
+// прочитанное до удаления значение. Следующий промах начинает новую
+// загрузку, а не присоединяется к устаревшей. Вызывается до удаления
+// записи: загрузка, успевшая записать значение, завершает запись раньше,
+// чем invalidate вернет управление, и удаление его уберет.
+func (r *readThrough) invalidate(key string) {
+	r.setMutex.Lock()
+	defer r.setMutex.Unlock()
+
+	r.mutex.Lock()
+	delete(r.negative, key)
+	if pending, ok := r.loading[key]; ok {
+		pending.stale = true
+		delete(r.loading, key)
+	}
+	r.mutex.Unlock()
+
+	r.group.Forget(key)
+}
+
+// reset забывает все ненайденные ключи и, как invalidate, отменяет
+// запись результатов выполняющихся загрузок
+func (r *readThrough) reset() {
+	r.setMutex.Lock()
+	defer r.setMutex.Unlock()
+
+	r.mutex.Lock()
+	r.negative = make(map[string]time.Time)
+	loading := r.loading
+	r.loading = make(map[string]*pendingLoad)
+	for _, pending := range loading {
+		pending.stale = true
+	}
+	r.mutex.Unlock()
+
+	for key := range loading {
+		r.group.Forget(key)
+	}
+}
+
+// prune удаляет устаревшие записи отрицательного кэша
+func (r *readThrough) prune() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.pruneLocked(time.Now())
+}
+
+func (r *readThrough) pruneLocked(now time.Time) {
+	for key, expires := range r.negative {
+		if now.After(expires) {
+			delete(r.negative, key)
+		}
+	}
+}
+
+// addStats дописывает счетчики read-through в статистику кэша
+func (r *readThrough) addStats(stats *interfaces.CacheStats) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	stats.Loads = r.loads
+	stats.SharedLoads = r.sharedLoads
+	stats.NegativeHits = r.negativeHits
+	stats.NegativeEntries = len(r.negative)
+}
@@ -0,0 +1,220 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"wb-service/internal/interfaces"
+	"wb-service/models"
+)
+
+func TestGetOrLoad_DeduplicatesConcurrentLoads(t *testing.T) {
+	caches := map[string]interfaces.Cache{
+		"lru":     NewLRUCache(10, time.Hour),
+		"sharded": NewShardedCache(context.Background(), 4, Options{Capacity: 10, TTL: time.Hour}),
+	}
+
+	for name, c := range caches {
+		t.Run(name, func(t *testing.T) {
+			defer c.Close()
+
+			var calls atomic.Int32
+			release := make(chan struct{})
+			load := func(key string) (*models.Order, error) {
+				calls.Add(1)
+				<-release
+				return &models.Order{OrderUID: key}, nil
+			}
+
+			const callers = 50
+			var wg sync.WaitGroup
+			errs := make(chan error, callers)
+			for i := 0; i < callers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					order, err := c.GetOrLoad("order1", load)
+					if err == nil && order.OrderUID != "order1" {
+						err = errors.New("unexpected order " + order.OrderUID)
+					}
+					errs <- err
+				}()
+			}
+
+			// Даем горутинам дойти до загрузки, затем отпускаем ее
+			time.Sleep(50 * time.Millisecond)
+			close(release)
+			wg.Wait()
+			close(errs)
+
+			for err := range errs {
+				if err != nil {
+					t.Fatalf("GetOrLoad failed: %v", err)
+				}
+			}
+
+			if n := calls.Load(); n != 1 {
+				t.Errorf("Expected exactly 1 load, got %d", n)
+			}
+			if _, found := c.Get("order1"); !found {
+				t.Error("Expected loaded order to be cached")
+			}
+
+			stats := c.Stats()
+			if stats.Loads != 1 {
+				t.Errorf("Expected 1 load in stats, got %d", stats.Loads)
+			}
+			if stats.SharedLoads == 0 {
+				t.Error("Expected some callers to share the load")
+			}
+		})
+	}
+}
+
+func TestGetOrLoad_NegativeCaching(t *testing.T) {
+	c := NewLRUCacheWithOptions(context.Background(), Options{Capacity: 10, NegativeTTL: 50 * time.Millisecond})
+	defer c.Close()
+
+	calls := 0
+	load := func(key string) (*models.Order, error) {
+		calls++
+		return nil, interfaces.ErrNotFound
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.GetOrLoad("missing", load); !errors.Is(err, interfaces.ErrNotFound) {
+			t.Fatalf("Expected ErrNotFound, got %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 load while negative entry is fresh, got %d", calls)
+	}
+
+	stats := c.Stats()
+	if stats.NegativeHits != 2 || stats.NegativeEntries != 1 {
+		t.Errorf("Unexpected negative cache stats: %+v", stats)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	c.GetOrLoad("missing", load)
+	if calls != 2 {
+		t.Errorf("Expected reload after negative TTL, got %d loads", calls)
+	}
+
+	// Set заказа, появившегося позже, отменяет отрицательную запись
+	c.Set("missing", &models.Order{OrderUID: "missing"})
+	if order, err := c.GetOrLoad("missing", load); err != nil || order.OrderUID != "missing" {
+		t.Errorf("Expected order after Set, got %v, %v", order, err)
+	}
+}
+
+func TestGetOrLoad_ErrorsAreNotCached(t *testing.T) {
+	c := NewLRUCacheWithOptions(context.Background(), Options{Capacity: 10, NegativeTTL: time.Hour})
+	defer c.Close()
+
+	dbErr := errors.New("connection refused")
+	calls := 0
+	load := func(key string) (*models.Order, error) {
+		calls++
+		return nil, dbErr
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetOrLoad("order1", load); !errors.Is(err, dbErr) {
+			t.Fatalf("Expected loader error, got %v", err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("Expected errors not to be cached, got %d loads", calls)
+	}
+	if c.Size() != 0 || c.Stats().NegativeEntries != 0 {
+		t.Error("Expected nothing to be cached after loader error")
+	}
+}
+
+func TestGetOrLoad_NegativeCachingDisabled(t *testing.T) {
+	c := NewLRUCache(10, time.Hour)
+	defer c.Close()
+
+	calls := 0
+	load := func(key string) (*models.Order, error) {
+		calls++
+		return nil, interfaces.ErrNotFound
+	}
+
+	c.GetOrLoad("missing", load)
+	c.GetOrLoad("missing", load)
+	if calls != 2 {
+		t.Errorf("Expected every miss to reach the loader, got %d loads", calls)
+	}
+}
+
+func TestGetOrLoad_DeleteDuringLoad(t *testing.T) {
+	redisCache, _ := setupRedisCache(t, RedisOptions{TTL: time.Hour})
+	l2, _ := setupRedisCache(t, RedisOptions{TTL: time.Hour})
+	tiered, err := NewTieredCache(NewLRUCache(10, time.Hour), l2, "", Options{})
+	if err != nil {
+		t.Fatalf("NewTieredCache failed: %v", err)
+	}
+
+	caches := map[string]interfaces.Cache{
+		"lru":     NewLRUCache(10, time.Hour),
+		"sharded": NewShardedCache(context.Background(), 4, Options{Capacity: 10, TTL: time.Hour}),
+		"redis":   redisCache,
+		"tiered":  tiered,
+	}
+
+	for name, c := range caches {
+		t.Run(name, func(t *testing.T) {
+			defer c.Close()
+
+			// Загрузка читает заказ до его удаления из БД и ждет
+			loading := make(chan struct{})
+			release := make(chan struct{})
+			stale := func(key string) (*models.Order, error) {
+				close(loading)
+				<-release
+				return &models.Order{OrderUID: key}, nil
+			}
+
+			done := make(chan error, 1)
+			go func() {
+				_, err := c.GetOrLoad("order1", stale)
+				done <- err
+			}()
+			<-loading
+
+			// Заказ удален из БД и из кэша, пока загрузка еще не записала его
+			c.Delete("order1")
+
+			// Новый промах не присоединяется к устаревшей загрузке
+			fresh := make(chan error, 1)
+			go func() {
+				_, err := c.GetOrLoad("order1", func(string) (*models.Order, error) {
+					return nil, interfaces.ErrNotFound
+				})
+				fresh <- err
+			}()
+			select {
+			case err := <-fresh:
+				if !errors.Is(err, interfaces.ErrNotFound) {
+					t.Errorf("Expected ErrNotFound after delete, got %v", err)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("GetOrLoad after delete waited for the stale load")
+			}
+
+			close(release)
+			if err := <-done; err != nil {
+				t.Fatalf("GetOrLoad failed: %v", err)
+			}
+
+			if _, found := c.Get("order1"); found {
+				t.Error("Expected load started before delete not to repopulate the cache")
+			}
+		})
+	}
+}
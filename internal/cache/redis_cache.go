@@ -0,0 +1,396 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+	"wb-service/internal/codec"
+	"wb-service/internal/interfaces"
+	"wb-service/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// PolicyRedis - имя политики в статистике RedisCache: вытеснением
+// управляет сам Redis (maxmemory-policy)
+const PolicyRedis = "redis"
+
+// RedisOptions задает параметры кэша в Redis
+type RedisOptions struct {
+	Prefix   string        // префикс ключей, по умолчанию "order:"
+	TTL      time.Duration // время жизни заказа, 0 - без ограничения
+	Format   string        // формат сериализации заказов (json, protobuf, avro)
+	Capacity int           // сколько заказов загружать в LoadFromDB, 0 - все
+	Timeout  time.Duration // таймаут одной операции с Redis, по умолчанию 1с
+
+	// NegativeTTL - сколько GetOrLoad помнит ненайденные ключи, 0 - не помнить
+	NegativeTTL time.Duration
+}
+
+// RedisCache хранит заказы в Redis, поэтому все реплики сервиса видят
+// один и тот же кэш. Ошибки Redis не прерывают обработку запросов:
+// чтение считается промахом, запись пропускается, ошибка пишется в лог.
+type RedisCache struct {
+	client  redis.UniversalClient
+	prefix  string
+	ttl     time.Duration
+	codec   interfaces.OrderCodec
+	limit   int
+	timeout time.Duration
+	loader  *readThrough
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+	errors atomic.Uint64
+
+	loadMutex     sync.Mutex
+	loadedEntries int
+	loadDuration  time.Duration
+	loadedAt      time.Time
+}
+
+// NewRedisCache создает кэш поверх клиента Redis. Кэш владеет клиентом
+// и закрывает его в Close.
+func NewRedisCache(client redis.UniversalClient, opts RedisOptions) (*RedisCache, error) {
+	orderCodec, err := codec.New(opts.Format)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Prefix == "" {
+		opts.Prefix = "order:"
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = time.Second
+	}
+
+	return &RedisCache{
+		client:  client,
+		prefix:  opts.Prefix,
+		ttl:     opts.TTL,
+		codec:   orderCodec,
+		limit:   opts.Capacity,
+		timeout: opts.Timeout,
+		loader:  newReadThrough(opts.NegativeTTL),
+	}, nil
+}
+
+// context возвращает контекст с таймаутом одной операции
+func (c *RedisCache) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), c.timeout)
+}
+
+// fail учитывает ошибку Redis
+func (c *RedisCache) fail(op string, err error) {
+	c.errors.Add(1)
+	log.Printf("Ошибка Redis при %s: %v", op, err)
+}
+
+// Get получает значение из кэша
+func (c *RedisCache) Get(key string) (*models.Order, bool) {
+	ctx, cancel := c.context()
+	defer cancel()
+
+	data, err := c.client.Get(ctx, c.prefix+key).Bytes()
+	return c.decode(key, data, err)
+}
+
+// getWithTTL получает значение вместе с оставшимся временем жизни ключа,
+// чтобы локальный кэш не хранил копию дольше, чем Redis. Если время жизни
+// узнать не удалось, возвращается DefaultExpiration.
+func (c *RedisCache) getWithTTL(key string) (*models.Order, time.Duration, bool) {
+	ctx, cancel := c.context()
+	defer cancel()
+
+	pipe := c.client.Pipeline()
+	get := pipe.Get(ctx, c.prefix+key)
+	pttl := pipe.PTTL(ctx, c.prefix+key)
+	_, _ = pipe.Exec(ctx) // ошибки проверяются по каждой команде
+
+	data, err := get.Bytes()
+	order, found := c.decode(key, data, err)
+	if !found {
+		return nil, 0, false
+	}
+
+	ttl := DefaultExpiration
+	if remaining, err := pttl.Result(); err == nil {
+		switch {
+		case remaining > 0:
+			ttl = remaining
+		case remaining == -1: // у ключа нет срока жизни
+			ttl = NoExpiration
+		}
+	}
+	return order, ttl, true
+}
+
+// decode декодирует результат GET и учитывает попадание или промах
+func (c *RedisCache) decode(key string, data []byte, err error) (*models.Order, bool) {
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			c.fail("чтении "+key, err)
+		}
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	order, err := c.codec.Decode(data)
+	if err != nil {
+		c.fail("декодировании "+key, err)
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	c.hits.Add(1)
+	return order, true
+}
+
+// Set добавляет значение в кэш
+func (c *RedisCache) Set(key string, order *models.Order) {
+	c.SetWithTTL(key, order, DefaultExpiration)
+}
+
+// SetWithTTL добавляет значение в кэш с собственным TTL. Скользящее
+// истечение Redis не поддерживает, срок всегда отсчитывается от записи.
+func (c *RedisCache) SetWithTTL(key string, order *models.Order, ttl time.Duration) {
+	c.loader.forget(key)
+
+	switch {
+	case ttl == DefaultExpiration:
+		ttl = c.ttl
+	case ttl < 0:
+		ttl = 0 // в go-redis 0 означает ключ без срока жизни
+	}
+
+	data, err := c.codec.Encode(order)
+	if err != nil {
+		c.fail("кодировании "+key, err)
+		return
+	}
+
+	ctx, cancel := c.context()
+	defer cancel()
+
+	if err := c.client.Set(ctx, c.prefix+key, data, ttl).Err(); err != nil {
+		c.fail("записи "+key, err)
+	}
+}
+
+// SetIfAbsent добавляет значение с TTL кэша, только если ключа нет (SET NX)
+func (c *RedisCache) SetIfAbsent(key string, order *models.Order) bool {
+	c.loader.forget(key)
+
+	data, err := c.codec.Encode(order)
+	if err != nil {
+		c.fail("кодировании "+key, err)
+		return false
+	}
+
+	ctx, cancel := c.context()
+	defer cancel()
+
+	added, err := c.client.SetNX(ctx, c.prefix+key, data, c.ttl).Result()
+	if err != nil {
+		c.fail("записи "+key, err)
+		return false
+	}
+	return added
+}
+
+// GetOrLoad получает значение из кэша или загружает его через load.
+// Загрузки объединяются в пределах одной реплики.
+func (c *RedisCache) GetOrLoad(key string, load interfaces.OrderLoader) (*models.Order, error) {
+	return c.loader.getOrLoad(key, c.Get, c.Set, load)
+}
+
+// Delete удаляет значение из кэша. Возвращает false, если ключа не было
+func (c *RedisCache) Delete(key string) bool {
+	c.loader.invalidate(key)
+
+	ctx, cancel := c.context()
+	defer cancel()
+
+	n, err := c.client.Del(ctx, c.prefix+key).Result()
+	if err != nil {
+		c.fail("удалении "+key, err)
+		return false
+	}
+	return n > 0
+}
+
+// LoadFromDB загружает самые свежие заказы из базы данных, записывая
+// каждую страницу одним pipeline
+func (c *RedisCache) LoadFromDB(db interfaces.Database) error {
+	if db == nil {
+		return nil
+	}
+
+	start := time.Now()
+
+	loaded, err := loadRecent(db, c.limit, DefaultWarmupPageSize, func(orders []models.Order) (int, bool) {
+		n, err := c.store(orders)
+		if err != nil {
+			c.fail("загрузке из БД", err)
+			return n, false
+		}
+		return n, true
+	})
+
+	c.loadMutex.Lock()
+	c.loadedEntries = loaded
+	c.loadDuration = time.Since(start)
+	c.loadedAt = time.Now()
+	c.loadMutex.Unlock()
+
+	return err
+}
+
+// store записывает одним pipeline заказы, которых еще нет в Redis (SET NX),
+// и возвращает количество записанных. Существующий ключ не перезаписывается:
+// его мог записать consumer, а страница из БД может быть старее.
+func (c *RedisCache) store(orders []models.Order) (int, error) {
+	ctx, cancel := c.context()
+	defer cancel()
+
+	pipe := c.client.Pipeline()
+	cmds := make([]*redis.BoolCmd, 0, len(orders))
+	for i := range orders {
+		data, err := c.codec.Encode(&orders[i])
+		if err != nil {
+			return 0, err
+		}
+		cmds = append(cmds, pipe.SetNX(ctx, c.prefix+orders[i].OrderUID, data, c.ttl))
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+
+	stored := 0
+	for _, cmd := range cmds {
+		if cmd.Val() {
+			stored++
+		}
+	}
+	return stored, nil
+}
+
+// scan вызывает fn для пачек ключей кэша (с префиксом)
+func (c *RedisCache) scan(fn func(keys []string) error) error {
+	ctx := context.Background()
+
+	iter := c.client.Scan(ctx, 0, c.prefix+"*", 1000).Iterator()
+	batch := make([]string, 0, 1000)
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == cap(batch) {
+			if err := fn(batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if len(batch) > 0 {
+		return fn(batch)
+	}
+	return nil
+}
+
+// Size возвращает количество заказов в Redis. Ключи перебираются через
+// SCAN, поэтому операция линейна по размеру кэша.
+func (c *RedisCache) Size() int {
+	size := 0
+	err := c.scan(func(keys []string) error {
+		size += len(keys)
+		return nil
+	})
+	if err != nil {
+		c.fail("подсчете ключей", err)
+	}
+	return size
+}
+
+// HotKeys возвращает до n ключей кэша. Redis не сообщает частоту
+// обращений, поэтому порядок ключей не определен.
+func (c *RedisCache) HotKeys(n int) []string {
+	var keys []string
+	errEnough := errors.New("enough keys")
+
+	err := c.scan(func(batch []string) error {
+		for _, key := range batch {
+			if n > 0 && len(keys) >= n {
+				return errEnough
+			}
+			keys = append(keys, key[len(c.prefix):])
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errEnough) {
+		c.fail("чтении ключей", err)
+	}
+	return keys
+}
+
+// Clear удаляет из Redis все заказы с префиксом кэша
+func (c *RedisCache) Clear() {
+	c.loader.reset()
+
+	// Ключи собираются заранее: удаление во время SCAN может сдвинуть курсор
+	var keys []string
+	err := c.scan(func(batch []string) error {
+		keys = append(keys, batch...)
+		return nil
+	})
+	if err != nil {
+		c.fail("очистке", err)
+		return
+	}
+
+	for start := 0; start < len(keys); start += 1000 {
+		ctx, cancel := c.context()
+		err := c.client.Unlink(ctx, keys[start:min(start+1000, len(keys))]...).Err()
+		cancel()
+		if err != nil {
+			c.fail("очистке", err)
+			return
+		}
+	}
+}
+
+// Stats возвращает статистику кэша. Счетчики попаданий и промахов
+// относятся к текущей реплике.
+func (c *RedisCache) Stats() interfaces.CacheStats {
+	stats := interfaces.CacheStats{
+		Hits:     c.hits.Load(),
+		Misses:   c.misses.Load(),
+		Size:     c.Size(),
+		Capacity: c.limit,
+		Policy:   PolicyRedis,
+		Errors:   c.errors.Load(),
+
+		Expiration: ExpirationAbsolute,
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+
+	c.loadMutex.Lock()
+	stats.LoadedEntries = c.loadedEntries
+	stats.LoadDuration = c.loadDuration
+	stats.LoadedAt = c.loadedAt
+	c.loadMutex.Unlock()
+
+	c.loader.addStats(&stats)
+	return stats
+}
+
+// Close закрывает соединение с Redis
+func (c *RedisCache) Close() error {
+	return c.client.Close()
+}
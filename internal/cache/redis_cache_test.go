@@ -0,0 +1,415 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"wb-service/internal/interfaces"
+	"wb-service/models"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func setupRedisCache(t *testing.T, opts RedisOptions) (*RedisCache, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+
+	c, err := NewRedisCache(client, opts)
+	if err != nil {
+		t.Fatalf("NewRedisCache failed: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	return c, server
+}
+
+func TestRedisCache_Implements(t *testing.T) {
+	var _ interfaces.Cache = (*RedisCache)(nil)
+	var _ interfaces.Cache = (*TieredCache)(nil)
+	var _ interfaces.CacheSnapshotter = (*TieredCache)(nil)
+}
+
+func TestRedisCache_BasicOperations(t *testing.T) {
+	for _, format := range []string{"json", "protobuf"} {
+		t.Run(format, func(t *testing.T) {
+			c, server := setupRedisCache(t, RedisOptions{Format: format})
+
+			order := createTestOrderForLoadFromDB("redis1")
+			c.Set("redis1", order)
+
+			if !server.Exists("order:redis1") {
+				t.Fatal("Expected key with default prefix in Redis")
+			}
+
+			got, found := c.Get("redis1")
+			if !found {
+				t.Fatal("Expected to find order")
+			}
+			if got.Delivery.Name != order.Delivery.Name || len(got.Items) != 1 {
+				t.Errorf("Unexpected order after round trip: %+v", got)
+			}
+
+			if _, found := c.Get("missing"); found {
+				t.Error("Expected miss for missing key")
+			}
+
+			if !c.Delete("redis1") {
+				t.Error("Expected Delete to report existing key")
+			}
+			if c.Delete("redis1") {
+				t.Error("Expected Delete to report missing key")
+			}
+
+			stats := c.Stats()
+			if stats.Hits != 1 || stats.Misses != 1 || stats.Policy != PolicyRedis {
+				t.Errorf("Unexpected stats: %+v", stats)
+			}
+		})
+	}
+}
+
+func TestRedisCache_TTL(t *testing.T) {
+	c, server := setupRedisCache(t, RedisOptions{TTL: time.Minute})
+
+	c.Set("ttl", &models.Order{OrderUID: "ttl"})
+	if ttl := server.TTL("order:ttl"); ttl != time.Minute {
+		t.Errorf("Expected TTL 1m, got %v", ttl)
+	}
+
+	server.FastForward(2 * time.Minute)
+	if _, found := c.Get("ttl"); found {
+		t.Error("Expected order to expire")
+	}
+}
+
+func TestRedisCache_SetWithTTL(t *testing.T) {
+	c, server := setupRedisCache(t, RedisOptions{TTL: time.Minute})
+
+	c.SetWithTTL("long", &models.Order{OrderUID: "long"}, time.Hour)
+	c.SetWithTTL("forever", &models.Order{OrderUID: "forever"}, NoExpiration)
+	c.SetWithTTL("default", &models.Order{OrderUID: "default"}, DefaultExpiration)
+
+	if ttl := server.TTL("order:long"); ttl != time.Hour {
+		t.Errorf("Expected TTL 1h, got %v", ttl)
+	}
+	if ttl := server.TTL("order:forever"); ttl != 0 {
+		t.Errorf("Expected key without TTL, got %v", ttl)
+	}
+	if ttl := server.TTL("order:default"); ttl != time.Minute {
+		t.Errorf("Expected default TTL 1m, got %v", ttl)
+	}
+}
+
+func TestRedisCache_SetIfAbsent(t *testing.T) {
+	c, server := setupRedisCache(t, RedisOptions{TTL: time.Minute})
+
+	if !c.SetIfAbsent("nx", &models.Order{OrderUID: "nx", TrackNumber: "first"}) {
+		t.Fatal("Expected SetIfAbsent to add a missing key")
+	}
+	if c.SetIfAbsent("nx", &models.Order{OrderUID: "nx", TrackNumber: "second"}) {
+		t.Error("Expected SetIfAbsent to keep the existing key")
+	}
+	if got, found := c.Get("nx"); !found || got.TrackNumber != "first" {
+		t.Errorf("Expected first value, got %v", got)
+	}
+	if ttl := server.TTL("order:nx"); ttl != time.Minute {
+		t.Errorf("Expected cache TTL, got %v", ttl)
+	}
+}
+
+func TestRedisCache_ClearKeepsForeignKeys(t *testing.T) {
+	c, server := setupRedisCache(t, RedisOptions{Prefix: "test:"})
+
+	for i := 0; i < 1500; i++ {
+		key := fmt.Sprintf("o%d", i)
+		c.Set(key, &models.Order{OrderUID: key})
+	}
+	server.Set("other:key", "value")
+
+	if size := c.Size(); size != 1500 {
+		t.Fatalf("Expected size 1500, got %d", size)
+	}
+	if keys := c.HotKeys(10); len(keys) != 10 {
+		t.Errorf("Expected 10 keys, got %d", len(keys))
+	}
+
+	c.Clear()
+
+	if size := c.Size(); size != 0 {
+		t.Errorf("Expected empty cache after Clear, got %d", size)
+	}
+	if !server.Exists("other:key") {
+		t.Error("Expected Clear to keep keys without cache prefix")
+	}
+}
+
+func TestRedisCache_LoadFromDB(t *testing.T) {
+	db := setupWarmupDatabase(t, 10)
+	c, _ := setupRedisCache(t, RedisOptions{Capacity: 4})
+
+	if err := c.LoadFromDB(db); err != nil {
+		t.Fatalf("LoadFromDB failed: %v", err)
+	}
+
+	if size := c.Size(); size != 4 {
+		t.Errorf("Expected 4 orders, got %d", size)
+	}
+	if _, found := c.Get("warm9"); !found {
+		t.Error("Expected the most recent order to be loaded")
+	}
+	if stats := c.Stats(); stats.LoadedEntries != 4 {
+		t.Errorf("Expected 4 loaded entries, got %d", stats.LoadedEntries)
+	}
+}
+
+func TestRedisCache_LoadFromDBKeepsNewerEntries(t *testing.T) {
+	db := setupWarmupDatabase(t, 3)
+	c, _ := setupRedisCache(t, RedisOptions{TTL: time.Hour})
+
+	// Consumer записал более новую версию заказа до загрузки из БД
+	newer := createTestOrderForLoadFromDB("warm2")
+	newer.TrackNumber = "NEWER"
+	c.Set("warm2", newer)
+
+	if err := c.LoadFromDB(db); err != nil {
+		t.Fatalf("LoadFromDB failed: %v", err)
+	}
+
+	cached, found := c.Get("warm2")
+	if !found || cached.TrackNumber != "NEWER" {
+		t.Errorf("Expected LoadFromDB to keep the newer entry, got %+v", cached)
+	}
+	if stats := c.Stats(); stats.LoadedEntries != 2 {
+		t.Errorf("Expected 2 loaded entries, got %d", stats.LoadedEntries)
+	}
+	if size := c.Size(); size != 3 {
+		t.Errorf("Expected 3 orders, got %d", size)
+	}
+}
+
+func TestRedisCache_GetOrLoad(t *testing.T) {
+	c, _ := setupRedisCache(t, RedisOptions{NegativeTTL: time.Minute})
+
+	calls := 0
+	load := func(key string) (*models.Order, error) {
+		calls++
+		if key == "missing" {
+			return nil, interfaces.ErrNotFound
+		}
+		return &models.Order{OrderUID: key}, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		if order, err := c.GetOrLoad("order1", load); err != nil || order.OrderUID != "order1" {
+			t.Fatalf("Unexpected result: %v, %v", order, err)
+		}
+		if _, err := c.GetOrLoad("missing", load); !errors.Is(err, interfaces.ErrNotFound) {
+			t.Fatalf("Expected ErrNotFound, got %v", err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 loads, got %d", calls)
+	}
+}
+
+func TestRedisCache_ServerDown(t *testing.T) {
+	c, server := setupRedisCache(t, RedisOptions{Timeout: 100 * time.Millisecond})
+	c.Set("order1", &models.Order{OrderUID: "order1"})
+
+	server.Close()
+
+	// Недоступный Redis - это промах, а не ошибка запроса
+	if _, found := c.Get("order1"); found {
+		t.Error("Expected miss while Redis is down")
+	}
+	c.Set("order2", &models.Order{OrderUID: "order2"})
+
+	if stats := c.Stats(); stats.Errors == 0 {
+		t.Error("Expected Redis errors to be counted")
+	}
+}
+
+func TestNewRedisCache_UnknownFormat(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "localhost:0"})
+	defer client.Close()
+
+	if _, err := NewRedisCache(client, RedisOptions{Format: "xml"}); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+func TestTieredCache_Invalidation(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	newReplica := func() *TieredCache {
+		l2, err := NewRedisCache(redis.NewClient(&redis.Options{Addr: server.Addr()}), RedisOptions{})
+		if err != nil {
+			t.Fatalf("NewRedisCache failed: %v", err)
+		}
+		c, err := NewTieredCache(NewLRUCache(10, time.Hour), l2, "", Options{})
+		if err != nil {
+			t.Fatalf("NewTieredCache failed: %v", err)
+		}
+		t.Cleanup(func() { c.Close() })
+		return c
+	}
+
+	a, b := newReplica(), newReplica()
+
+	// b читает заказ из L2 и копирует его в свой L1
+	a.Set("order1", &models.Order{OrderUID: "order1", TrackNumber: "v1"})
+	if got, found := b.Get("order1"); !found || got.TrackNumber != "v1" {
+		t.Fatalf("Expected b to read v1 from L2, got %v", got)
+	}
+	if b.Size() != 1 {
+		t.Fatalf("Expected order1 in b's L1, got size %d", b.Size())
+	}
+
+	// Обновление в a удаляет устаревшую копию из L1 реплики b
+	a.Set("order1", &models.Order{OrderUID: "order1", TrackNumber: "v2"})
+	waitFor(t, func() bool { return b.Size() == 0 })
+
+	if got, found := b.Get("order1"); !found || got.TrackNumber != "v2" {
+		t.Errorf("Expected b to read v2 after invalidation, got %v", got)
+	}
+
+	// Clear очищает L1 всех реплик
+	b.Set("order2", &models.Order{OrderUID: "order2"})
+	a.Get("order2")
+	b.Clear()
+	waitFor(t, func() bool { return a.Size() == 0 })
+
+	if _, found := a.Get("order1"); found {
+		t.Error("Expected L2 to be cleared")
+	}
+
+	stats := a.Stats()
+	if stats.L2 == nil || stats.L2.Policy != PolicyRedis {
+		t.Errorf("Expected L2 stats, got %+v", stats.L2)
+	}
+}
+
+func TestTieredCache_InvalidationClearsNegativeEntries(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	newReplica := func() *TieredCache {
+		l2, err := NewRedisCache(redis.NewClient(&redis.Options{Addr: server.Addr()}), RedisOptions{})
+		if err != nil {
+			t.Fatalf("NewRedisCache failed: %v", err)
+		}
+		c, err := NewTieredCache(NewLRUCache(10, time.Hour), l2, "", Options{NegativeTTL: time.Hour})
+		if err != nil {
+			t.Fatalf("NewTieredCache failed: %v", err)
+		}
+		t.Cleanup(func() { c.Close() })
+		return c
+	}
+
+	a, b := newReplica(), newReplica()
+	notFound := func(string) (*models.Order, error) { return nil, interfaces.ErrNotFound }
+
+	// b запомнил, что заказов нет
+	for _, key := range []string{"order1", "order2"} {
+		if _, err := b.GetOrLoad(key, notFound); !errors.Is(err, interfaces.ErrNotFound) {
+			t.Fatalf("Expected ErrNotFound for %s, got %v", key, err)
+		}
+	}
+
+	// Заказ записан через другую реплику
+	a.Set("order1", &models.Order{OrderUID: "order1"})
+	waitFor(t, func() bool { return b.Stats().NegativeEntries == 1 })
+
+	// Clear в другой реплике забывает все ненайденные ключи
+	a.Clear()
+	waitFor(t, func() bool { return b.Stats().NegativeEntries == 0 })
+
+	var calls int
+	order, err := b.GetOrLoad("order2", func(key string) (*models.Order, error) {
+		calls++
+		return &models.Order{OrderUID: key}, nil
+	})
+	if err != nil || order.OrderUID != "order2" || calls != 1 {
+		t.Errorf("Expected order2 to be loaded after invalidation, got %v, %v (%d loads)", order, err, calls)
+	}
+}
+
+func TestTieredCache_L1FollowsL2TTL(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	l2, err := NewRedisCache(redis.NewClient(&redis.Options{Addr: server.Addr()}), RedisOptions{TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("NewRedisCache failed: %v", err)
+	}
+	l1 := NewLRUCache(10, time.Hour)
+	c, err := NewTieredCache(l1, l2, "", Options{})
+	if err != nil {
+		t.Fatalf("NewTieredCache failed: %v", err)
+	}
+	defer c.Close()
+
+	// Другая реплика записала заказ с коротким TTL
+	l2.SetWithTTL("short", &models.Order{OrderUID: "short"}, time.Minute)
+	l2.SetWithTTL("forever", &models.Order{OrderUID: "forever"}, NoExpiration)
+
+	c.Get("short")
+	c.Get("forever")
+
+	ttls := make(map[string]time.Duration)
+	for _, entry := range l1.Entries() {
+		ttls[entry.Key] = entry.TTL
+	}
+	if ttls["short"] != time.Minute || ttls["forever"] != NoExpiration {
+		t.Errorf("Expected L1 copies to follow L2 TTL, got %v", ttls)
+	}
+
+	c.SetWithTTL("own", &models.Order{OrderUID: "own"}, 2*time.Minute)
+	if ttl := server.TTL("order:own"); ttl != 2*time.Minute {
+		t.Errorf("Expected TTL 2m in L2, got %v", ttl)
+	}
+}
+
+func TestTieredCache_LoadFromDBOnlyWhenL2Empty(t *testing.T) {
+	db := setupWarmupDatabase(t, 5)
+	server := miniredis.RunT(t)
+
+	l2, _ := NewRedisCache(redis.NewClient(&redis.Options{Addr: server.Addr()}), RedisOptions{})
+	c, err := NewTieredCache(NewLRUCache(10, time.Hour), l2, "", Options{})
+	if err != nil {
+		t.Fatalf("NewTieredCache failed: %v", err)
+	}
+	defer c.Close()
+
+	if err := c.LoadFromDB(db); err != nil {
+		t.Fatalf("LoadFromDB failed: %v", err)
+	}
+	if size := l2.Size(); size != 5 {
+		t.Fatalf("Expected 5 orders in L2, got %d", size)
+	}
+
+	// Вторая реплика находит общий кэш прогретым и не читает БД
+	w := NewWarmer(c, db, WarmupOptions{})
+	if err := w.Run(context.Background()); err != nil {
+		t.Fatalf("Warmup failed: %v", err)
+	}
+	if progress := w.Progress(); !progress.Skipped || progress.Loaded != 0 {
+		t.Errorf("Expected warmup to be skipped, got %+v", progress)
+	}
+}
+
+// waitFor ждет выполнения условия, которое наступает асинхронно
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
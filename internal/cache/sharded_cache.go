@@ -0,0 +1,276 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+	"wb-service/internal/interfaces"
+	"wb-service/models"
+)
+
+// ShardedCache распределяет заказы по независимым LRU шардам по хешу
+// order_uid. У каждого шарда свой список и своя блокировка, поэтому
+// параллельные чтения разных ключей не ждут друг друга.
+type ShardedCache struct {
+	shards   []*LRUCache
+	capacity int
+	maxBytes int64
+	loader   *readThrough
+
+	// статистика последней загрузки из БД всего кэша
+	loadMutex    sync.Mutex
+	loadDuration time.Duration
+	loadedAt     time.Time
+}
+
+// NewShardedCache создает кэш из shardCount шардов. Лимиты opts делятся
+// между шардами поровну, поэтому вытеснение происходит внутри шарда.
+func NewShardedCache(ctx context.Context, shardCount int, opts Options) *ShardedCache {
+	if shardCount < 1 {
+		shardCount = 1
+	}
+
+	shardOpts := opts
+	shardOpts.NegativeTTL = 0 // GetOrLoad обслуживает сам ShardedCache
+	if opts.Capacity > 0 {
+		shardOpts.Capacity = (opts.Capacity + shardCount - 1) / shardCount
+	}
+	if opts.MaxBytes > 0 {
+		shardOpts.MaxBytes = (opts.MaxBytes + int64(shardCount) - 1) / int64(shardCount)
+	}
+
+	c := &ShardedCache{
+		shards:   make([]*LRUCache, shardCount),
+		capacity: opts.Capacity,
+		maxBytes: opts.MaxBytes,
+		loader:   newReadThrough(opts.NegativeTTL),
+	}
+	for i := range c.shards {
+		c.shards[i] = NewLRUCacheWithOptions(ctx, shardOpts)
+	}
+
+	return c
+}
+
+// shardFor возвращает шард для ключа (FNV-1a без аллокаций)
+func (c *ShardedCache) shardFor(key string) *LRUCache {
+	return c.shards[shardIndex(key, len(c.shards))]
+}
+
+func shardIndex(key string, shardCount int) int {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+
+	hash := uint32(offset32)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(key[i])
+		hash *= prime32
+	}
+	return int(hash % uint32(shardCount))
+}
+
+// Get получает значение из кэша
+func (c *ShardedCache) Get(key string) (*models.Order, bool) {
+	return c.shardFor(key).Get(key)
+}
+
+// GetOrLoad получает значение из кэша или загружает его через load.
+// Загрузки объединяются на уровне всего кэша, а не отдельного шарда.
+func (c *ShardedCache) GetOrLoad(key string, load interfaces.OrderLoader) (*models.Order, error) {
+	return c.loader.getOrLoad(key, c.Get, c.Set, load)
+}
+
+// Set добавляет значение в кэш
+func (c *ShardedCache) Set(key string, order *models.Order) {
+	c.SetWithTTL(key, order, DefaultExpiration)
+}
+
+// SetWithTTL добавляет значение в кэш с собственным TTL
+func (c *ShardedCache) SetWithTTL(key string, order *models.Order, ttl time.Duration) {
+	c.loader.forget(key)
+	c.shardFor(key).SetWithTTL(key, order, ttl)
+}
+
+// SetIfAbsent добавляет значение, только если ключа в кэше нет
+func (c *ShardedCache) SetIfAbsent(key string, order *models.Order) bool {
+	c.loader.forget(key)
+	return c.shardFor(key).SetIfAbsent(key, order)
+}
+
+// Delete удаляет значение из кэша
+func (c *ShardedCache) Delete(key string) bool {
+	c.loader.invalidate(key)
+	return c.shardFor(key).Delete(key)
+}
+
+// LoadFromDB загружает самые свежие заказы из базы данных, раскладывая
+// их по шардам. Загрузка останавливается, когда заполнились все шарды,
+// получившие заказы страницы.
+func (c *ShardedCache) LoadFromDB(db interfaces.Database) error {
+	if db == nil {
+		return nil
+	}
+
+	start := time.Now()
+
+	perShard := make([]int, len(c.shards))
+	groups := make([][]models.Order, len(c.shards))
+	_, err := loadRecent(db, c.capacity, DefaultWarmupPageSize, func(orders []models.Order) (int, bool) {
+		for i := range groups {
+			groups[i] = groups[i][:0]
+		}
+		for _, order := range orders {
+			i := shardIndex(order.OrderUID, len(c.shards))
+			groups[i] = append(groups[i], order)
+		}
+
+		loaded, more := 0, false
+		for i, shard := range c.shards {
+			if len(groups[i]) == 0 {
+				continue
+			}
+			n, room := shard.loadOrders(groups[i])
+			perShard[i] += n
+			loaded += n
+			more = more || room
+		}
+		return loaded, more
+	})
+
+	for i, shard := range c.shards {
+		shard.finishLoad(perShard[i], start)
+	}
+
+	c.loadMutex.Lock()
+	c.loadDuration = time.Since(start)
+	c.loadedAt = time.Now()
+	c.loadMutex.Unlock()
+
+	return err
+}
+
+// HotKeys возвращает до n самых ценных ключей, по очереди беря
+// лучшие ключи каждого шарда. n <= 0 - все ключи.
+func (c *ShardedCache) HotKeys(n int) []string {
+	perShard := make([][]string, len(c.shards))
+	total := 0
+	for i, shard := range c.shards {
+		perShard[i] = shard.HotKeys(n)
+		total += len(perShard[i])
+	}
+	if n <= 0 || n > total {
+		n = total
+	}
+
+	keys := make([]string, 0, n)
+	for rank := 0; len(keys) < n; rank++ {
+		for _, shardKeys := range perShard {
+			if rank < len(shardKeys) && len(keys) < n {
+				keys = append(keys, shardKeys[rank])
+			}
+		}
+	}
+	return keys
+}
+
+// Entries возвращает записи всех шардов, по очереди беря самые ценные
+// записи каждого шарда
+func (c *ShardedCache) Entries() []interfaces.CacheEntry {
+	perShard := make([][]interfaces.CacheEntry, len(c.shards))
+	total := 0
+	for i, shard := range c.shards {
+		perShard[i] = shard.Entries()
+		total += len(perShard[i])
+	}
+
+	entries := make([]interfaces.CacheEntry, 0, total)
+	for rank := 0; len(entries) < total; rank++ {
+		for _, shardEntries := range perShard {
+			if rank < len(shardEntries) {
+				entries = append(entries, shardEntries[rank])
+			}
+		}
+	}
+	return entries
+}
+
+// Restore раскладывает записи снимка по шардам, сохраняя их порядок
+func (c *ShardedCache) Restore(entries []interfaces.CacheEntry) int {
+	start := time.Now()
+
+	groups := make([][]interfaces.CacheEntry, len(c.shards))
+	for _, entry := range entries {
+		i := shardIndex(entry.Key, len(c.shards))
+		groups[i] = append(groups[i], entry)
+	}
+
+	restored := 0
+	for i, shard := range c.shards {
+		restored += shard.Restore(groups[i])
+	}
+
+	c.loadMutex.Lock()
+	c.loadDuration = time.Since(start)
+	c.loadedAt = time.Now()
+	c.loadMutex.Unlock()
+
+	return restored
+}
+
+// Size возвращает текущий размер кэша
+func (c *ShardedCache) Size() int {
+	size := 0
+	for _, shard := range c.shards {
+		size += shard.Size()
+	}
+	return size
+}
+
+// Clear очищает все шарды
+func (c *ShardedCache) Clear() {
+	for _, shard := range c.shards {
+		shard.Clear()
+	}
+	c.loader.reset()
+}
+
+// Stats возвращает суммарную статистику по всем шардам
+func (c *ShardedCache) Stats() interfaces.CacheStats {
+	var stats interfaces.CacheStats
+	for _, shard := range c.shards {
+		s := shard.Stats()
+		stats.Hits += s.Hits
+		stats.Misses += s.Misses
+		stats.Evictions += s.Evictions
+		stats.Expirations += s.Expirations
+		stats.Size += s.Size
+		stats.Bytes += s.Bytes
+		stats.LoadedEntries += s.LoadedEntries
+	}
+
+	stats.Capacity = c.capacity
+	stats.MaxBytes = c.maxBytes
+	stats.Policy = c.shards[0].policy.Name()
+	stats.Expiration = c.shards[0].expiration()
+	c.loader.addStats(&stats)
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+
+	c.loadMutex.Lock()
+	stats.LoadDuration = c.loadDuration
+	stats.LoadedAt = c.loadedAt
+	c.loadMutex.Unlock()
+
+	return stats
+}
+
+// Close останавливает фоновую очистку во всех шардах
+func (c *ShardedCache) Close() error {
+	for _, shard := range c.shards {
+		shard.Close()
+	}
+	return nil
+}
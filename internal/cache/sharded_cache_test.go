@@ -0,0 +1,190 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+	"wb-service/internal/interfaces"
+	"wb-service/models"
+)
+
+func TestShardedCache_ImplementsCache(t *testing.T) {
+	var _ interfaces.Cache = NewShardedCache(context.Background(), 4, Options{Capacity: 10})
+}
+
+func TestShardedCache_BasicOperations(t *testing.T) {
+	cache := NewShardedCache(context.Background(), 4, Options{Capacity: 100, TTL: time.Hour})
+	defer cache.Close()
+
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("order%d", i)
+		cache.Set(key, &models.Order{OrderUID: key})
+	}
+
+	if cache.Size() != 20 {
+		t.Errorf("Expected size 20, got %d", cache.Size())
+	}
+
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("order%d", i)
+		if got, found := cache.Get(key); !found || got.OrderUID != key {
+			t.Errorf("Expected to find %s", key)
+		}
+	}
+
+	if !cache.Delete("order0") {
+		t.Error("Expected Delete to report existing key")
+	}
+	if _, found := cache.Get("order0"); found {
+		t.Error("Expected order0 to be removed")
+	}
+
+	cache.Clear()
+	if cache.Size() != 0 {
+		t.Errorf("Expected empty cache after clear, got %d", cache.Size())
+	}
+}
+
+func TestShardedCache_Distribution(t *testing.T) {
+	const shardCount = 8
+	cache := NewShardedCache(context.Background(), shardCount, Options{Capacity: 10000})
+	defer cache.Close()
+
+	for i := 0; i < 8000; i++ {
+		key := fmt.Sprintf("order_%d", i)
+		cache.Set(key, &models.Order{OrderUID: key})
+	}
+
+	// Ожидаем примерно по 1000 ключей на шард
+	for i, shard := range cache.shards {
+		if size := shard.Size(); size < 700 || size > 1300 {
+			t.Errorf("Shard %d has unbalanced size %d", i, size)
+		}
+	}
+}
+
+func TestShardedCache_Limits(t *testing.T) {
+	cache := NewShardedCache(context.Background(), 4, Options{Capacity: 40})
+	defer cache.Close()
+
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("order%d", i)
+		cache.Set(key, &models.Order{OrderUID: key})
+	}
+
+	stats := cache.Stats()
+	if stats.Size > 40 {
+		t.Errorf("Expected size <= 40, got %d", stats.Size)
+	}
+	if stats.Capacity != 40 {
+		t.Errorf("Expected reported capacity 40, got %d", stats.Capacity)
+	}
+	if stats.Evictions == 0 {
+		t.Error("Expected evictions to be aggregated across shards")
+	}
+}
+
+func TestShardedCache_Stats(t *testing.T) {
+	cache := NewShardedCache(context.Background(), 4, Options{Capacity: 100})
+	defer cache.Close()
+
+	cache.Set("a", &models.Order{OrderUID: "a"})
+	cache.Set("b", &models.Order{OrderUID: "b"})
+	cache.Get("a")
+	cache.Get("b")
+	cache.Get("missing")
+
+	stats := cache.Stats()
+	if stats.Hits != 2 || stats.Misses != 1 {
+		t.Errorf("Expected 2 hits and 1 miss, got %d and %d", stats.Hits, stats.Misses)
+	}
+	if stats.Size != 2 {
+		t.Errorf("Expected size 2, got %d", stats.Size)
+	}
+	if stats.Bytes == 0 {
+		t.Error("Expected bytes to be aggregated across shards")
+	}
+}
+
+func TestShardedCache_LoadFromDB(t *testing.T) {
+	db := setupTestDatabase()
+	for i := 0; i < 10; i++ {
+		db.CreateOrder(createTestOrderForLoadFromDB(fmt.Sprintf("shard%d", i)))
+	}
+
+	cache := NewShardedCache(context.Background(), 4, Options{Capacity: 100, TTL: time.Hour})
+	defer cache.Close()
+
+	if err := cache.LoadFromDB(db); err != nil {
+		t.Fatalf("LoadFromDB failed: %v", err)
+	}
+
+	if cache.Size() != 10 {
+		t.Errorf("Expected 10 orders, got %d", cache.Size())
+	}
+
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("shard%d", i)
+		if _, found := cache.Get(key); !found {
+			t.Errorf("Expected %s to be loaded", key)
+		}
+	}
+
+	stats := cache.Stats()
+	if stats.LoadedEntries != 10 {
+		t.Errorf("Expected 10 loaded entries, got %d", stats.LoadedEntries)
+	}
+	if stats.LoadedAt.IsZero() {
+		t.Error("Expected LoadedAt to be set")
+	}
+
+	// Повторная загрузка не добавляет уже загруженные заказы
+	bytes := cache.Stats().Bytes
+	if err := cache.LoadFromDB(db); err != nil {
+		t.Fatalf("Second LoadFromDB failed: %v", err)
+	}
+	if got := cache.Stats().Bytes; got != bytes {
+		t.Errorf("Expected %d bytes after reload, got %d", bytes, got)
+	}
+	if keys := cache.HotKeys(0); len(keys) != 10 {
+		t.Errorf("Expected 10 hot keys after reload, got %d", len(keys))
+	}
+
+	if err := cache.LoadFromDB(nil); err != nil {
+		t.Errorf("Expected nil DB to be ignored, got: %v", err)
+	}
+}
+
+func TestShardedCache_Concurrent(t *testing.T) {
+	cache := NewShardedCache(context.Background(), 8, Options{Capacity: 500})
+	defer cache.Close()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				key := fmt.Sprintf("order%d_%d", g, i%50)
+				cache.Set(key, &models.Order{OrderUID: key})
+				cache.Get(key)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if cache.Size() > 500 {
+		t.Errorf("Expected size <= 500, got %d", cache.Size())
+	}
+}
+
+func TestNewShardedCache_InvalidShardCount(t *testing.T) {
+	cache := NewShardedCache(context.Background(), 0, Options{Capacity: 10})
+	defer cache.Close()
+
+	if len(cache.shards) != 1 {
+		t.Errorf("Expected 1 shard for invalid count, got %d", len(cache.shards))
+	}
+}
@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"container/list"
+	"unsafe"
+	"wb-service/models"
+)
+
+// entryOverhead - примерные накладные расходы кэша на одну запись:
+// элемент списка, CacheItem и запись в map (указатель + ключ)
+const entryOverhead = int64(unsafe.Sizeof(list.Element{})) +
+	int64(unsafe.Sizeof(CacheItem{})) +
+	int64(unsafe.Sizeof(uintptr(0))+unsafe.Sizeof(""))
+
+// EstimateOrderSize оценивает объем памяти, занимаемый заказом в кэше.
+// Учитываются сами структуры, содержимое строк и массив товаров;
+// выравнивание аллокатора и служебные поля GC не учитываются.
+func EstimateOrderSize(order *models.Order) int64 {
+	if order == nil {
+		return 0
+	}
+
+	size := int64(unsafe.Sizeof(*order))
+	size += int64(len(order.OrderUID) + len(order.TrackNumber) + len(order.Entry) +
+		len(order.Locale) + len(order.InternalSignature) + len(order.CustomerID) +
+		len(order.DeliveryService) + len(order.Shardkey) + len(order.OofShard))
+
+	d := &order.Delivery
+	size += int64(len(d.OrderUID) + len(d.Name) + len(d.Phone) + len(d.Zip) +
+		len(d.City) + len(d.Address) + len(d.Region) + len(d.Email))
+
+	p := &order.Payment
+	size += int64(len(p.OrderUID) + len(p.Transaction) + len(p.RequestID) +
+		len(p.Currency) + len(p.Provider) + len(p.Bank))
+
+	size += int64(cap(order.Items)) * int64(unsafe.Sizeof(models.Item{}))
+	for i := range order.Items {
+		it := &order.Items[i]
+		size += int64(len(it.OrderUID) + len(it.TrackNumber) + len(it.Rid) +
+			len(it.Name) + len(it.Size) + len(it.Brand))
+	}
+
+	return size
+}
+
+// entrySize возвращает размер записи кэша с учетом ключа и накладных расходов
+func entrySize(key string, order *models.Order) int64 {
+	return entryOverhead + int64(len(key)) + EstimateOrderSize(order)
+}
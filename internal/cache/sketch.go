@@ -0,0 +1,99 @@
+package cache
+
+// cmSketch - count-min sketch для приблизительного подсчета частоты
+// обращений к ключам. Счетчики насыщаются на 15, а после sampleSize
+// увеличений все счетчики делятся пополам, чтобы старые обращения
+// постепенно забывались.
+type cmSketch struct {
+	rows       [sketchDepth][]uint8
+	mask       uint32
+	additions  int
+	sampleSize int
+}
+
+const (
+	sketchDepth      = 4
+	sketchMaxCounter = 15
+)
+
+func newCMSketch(capacity int) *cmSketch {
+	// Не меньше 64 счетчиков в строке, иначе в маленьком кэше
+	// коллизии уравнивают оценки горячих и холодных ключей
+	width := 64
+	for width < capacity {
+		width <<= 1
+	}
+
+	s := &cmSketch{
+		mask:       uint32(width - 1),
+		sampleSize: 10 * width,
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+// Increment учитывает обращение к ключу
+func (s *cmSketch) Increment(key string) {
+	h1, h2 := sketchHash(key)
+
+	for i := range s.rows {
+		idx := (h1 + uint32(i)*h2) & s.mask
+		if s.rows[i][idx] < sketchMaxCounter {
+			s.rows[i][idx]++
+		}
+	}
+
+	s.additions++
+	if s.additions >= s.sampleSize {
+		s.age()
+	}
+}
+
+// Estimate возвращает оценку частоты обращений к ключу сверху
+func (s *cmSketch) Estimate(key string) uint8 {
+	h1, h2 := sketchHash(key)
+
+	estimate := uint8(sketchMaxCounter)
+	for i := range s.rows {
+		if v := s.rows[i][(h1+uint32(i)*h2)&s.mask]; v < estimate {
+			estimate = v
+		}
+	}
+	return estimate
+}
+
+// Reset обнуляет все счетчики
+func (s *cmSketch) Reset() {
+	for i := range s.rows {
+		clear(s.rows[i])
+	}
+	s.additions = 0
+}
+
+// age делит счетчики пополам
+func (s *cmSketch) age() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	s.additions /= 2
+}
+
+// sketchHash возвращает две независимые половины 64-битного FNV-1a,
+// из которых строятся индексы для всех строк (double hashing)
+func sketchHash(key string) (uint32, uint32) {
+	const (
+		offset64 = 14695981039346656037
+		prime64  = 1099511628211
+	)
+
+	hash := uint64(offset64)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint64(key[i])
+		hash *= prime64
+	}
+	return uint32(hash), uint32(hash>>32) | 1
+}
@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+	"wb-service/internal/interfaces"
+)
+
+// Формат файла снимка:
+//
+//	magic    [4]byte  "WBCS"
+//	version  uint16
+//	created  int64    время создания, unix nano
+//	checksum [32]byte SHA-256 от payload
+//	payload           gob([]interfaces.CacheEntry), от ценных записей к менее ценным
+const (
+	snapshotMagic   = "WBCS"
+	snapshotVersion = 1
+)
+
+// Ошибки чтения снимка. Отсутствие файла возвращается как fs.ErrNotExist.
+var (
+	ErrSnapshotCorrupt = errors.New("cache snapshot is corrupt")
+	ErrSnapshotStale   = errors.New("cache snapshot is stale")
+)
+
+type snapshotHeader struct {
+	Magic    [4]byte
+	Version  uint16
+	Created  int64
+	Checksum [sha256.Size]byte
+}
+
+// SaveSnapshot атомарно записывает записи кэша в файл снимка
+func SaveSnapshot(path string, entries []interfaces.CacheEntry) error {
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(entries); err != nil {
+		return fmt.Errorf("encode cache snapshot: %w", err)
+	}
+
+	header := snapshotHeader{
+		Version:  snapshotVersion,
+		Created:  time.Now().UnixNano(),
+		Checksum: sha256.Sum256(payload.Bytes()),
+	}
+	copy(header.Magic[:], snapshotMagic)
+
+	return writeFileAtomic(path, func(w *bufio.Writer) error {
+		if err := binary.Write(w, binary.BigEndian, &header); err != nil {
+			return err
+		}
+		_, err := payload.WriteTo(w)
+		return err
+	})
+}
+
+// LoadSnapshot читает файл снимка и проверяет его контрольную сумму.
+// Снимок старше maxAge (если maxAge > 0) отклоняется с ErrSnapshotStale.
+// Возвращает записи и время создания снимка.
+func LoadSnapshot(path string, maxAge time.Duration) ([]interfaces.CacheEntry, time.Time, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer f.Close()
+
+	var header snapshotHeader
+	if err := binary.Read(f, binary.BigEndian, &header); err != nil {
+		return nil, time.Time{}, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+	if string(header.Magic[:]) != snapshotMagic || header.Version != snapshotVersion {
+		return nil, time.Time{}, fmt.Errorf("%w: unknown format", ErrSnapshotCorrupt)
+	}
+
+	created := time.Unix(0, header.Created)
+	if maxAge > 0 && time.Since(created) > maxAge {
+		return nil, created, fmt.Errorf("%w: created %v ago", ErrSnapshotStale, time.Since(created).Round(time.Second))
+	}
+
+	payload, err := io.ReadAll(f)
+	if err != nil {
+		return nil, created, err
+	}
+	if sha256.Sum256(payload) != header.Checksum {
+		return nil, created, fmt.Errorf("%w: checksum mismatch", ErrSnapshotCorrupt)
+	}
+
+	var entries []interfaces.CacheEntry
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&entries); err != nil {
+		return nil, created, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+	return entries, created, nil
+}
@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+	"wb-service/internal/interfaces"
+	"wb-service/models"
+)
+
+func TestSnapshot_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+
+	src := NewLRUCache(10, time.Hour)
+	defer src.Close()
+
+	for _, uid := range []string{"a", "b", "c"} {
+		src.Set(uid, createTestOrderForLoadFromDB(uid))
+	}
+	src.Get("a") // a становится самым свежим
+
+	if err := SaveSnapshot(path, src.Entries()); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+
+	entries, created, err := LoadSnapshot(path, time.Hour)
+	if err != nil {
+		t.Fatalf("LoadSnapshot failed: %v", err)
+	}
+	if time.Since(created) > time.Minute {
+		t.Errorf("Unexpected snapshot creation time %v", created)
+	}
+
+	dst := NewLRUCache(10, time.Hour)
+	defer dst.Close()
+
+	if restored := dst.Restore(entries); restored != 3 {
+		t.Fatalf("Expected 3 restored entries, got %d", restored)
+	}
+
+	if keys := dst.HotKeys(0); !reflect.DeepEqual(keys, []string{"a", "c", "b"}) {
+		t.Errorf("Expected LRU order [a c b], got %v", keys)
+	}
+
+	got, found := dst.Get("b")
+	if !found {
+		t.Fatal("Expected b to be restored")
+	}
+	if got.Delivery.Name != "Test User b" || len(got.Items) != 1 {
+		t.Errorf("Expected order to be restored with relations, got %+v", got)
+	}
+
+	restoredAt := make(map[string]time.Time)
+	for _, entry := range dst.Entries() {
+		restoredAt[entry.Key] = entry.Timestamp
+	}
+	for _, entry := range src.Entries() {
+		if !entry.Timestamp.Equal(restoredAt[entry.Key]) {
+			t.Errorf("Expected timestamp of %s to be preserved", entry.Key)
+		}
+	}
+	if stats := dst.Stats(); stats.LoadedEntries != 3 {
+		t.Errorf("Expected 3 loaded entries in stats, got %d", stats.LoadedEntries)
+	}
+}
+
+func TestLRUCache_RestoreRespectsLimitsAndTTL(t *testing.T) {
+	now := time.Now()
+	entries := []interfaces.CacheEntry{
+		{Key: "hot", Order: &models.Order{OrderUID: "hot"}, Timestamp: now},
+		{Key: "expired", Order: &models.Order{OrderUID: "expired"}, Timestamp: now.Add(-2 * time.Hour)},
+		{Key: "warm", Order: &models.Order{OrderUID: "warm"}, Timestamp: now},
+		{Key: "cold", Order: &models.Order{OrderUID: "cold"}, Timestamp: now},
+	}
+
+	c := NewLRUCache(2, time.Hour)
+	defer c.Close()
+
+	if restored := c.Restore(entries); restored != 2 {
+		t.Fatalf("Expected 2 restored entries, got %d", restored)
+	}
+	if keys := c.HotKeys(0); !reflect.DeepEqual(keys, []string{"hot", "warm"}) {
+		t.Errorf("Expected the most valuable entries [hot warm], got %v", keys)
+	}
+	if stats := c.Stats(); stats.Evictions != 0 {
+		t.Errorf("Expected restore not to evict, got %d evictions", stats.Evictions)
+	}
+}
+
+func TestShardedCache_Snapshot(t *testing.T) {
+	src := NewShardedCache(context.Background(), 4, Options{Capacity: 100, TTL: time.Hour})
+	defer src.Close()
+
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("order%d", i)
+		src.Set(key, &models.Order{OrderUID: key})
+	}
+
+	entries := src.Entries()
+	if len(entries) != 20 {
+		t.Fatalf("Expected 20 entries, got %d", len(entries))
+	}
+
+	dst := NewShardedCache(context.Background(), 4, Options{Capacity: 100, TTL: time.Hour})
+	defer dst.Close()
+
+	if restored := dst.Restore(entries); restored != 20 {
+		t.Errorf("Expected 20 restored entries, got %d", restored)
+	}
+	if _, found := dst.Get("order7"); !found {
+		t.Error("Expected order7 to be restored")
+	}
+}
+
+func TestLoadSnapshot_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("missing file", func(t *testing.T) {
+		_, _, err := LoadSnapshot(filepath.Join(dir, "missing"), time.Hour)
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Expected fs.ErrNotExist, got %v", err)
+		}
+	})
+
+	entries := []interfaces.CacheEntry{
+		{Key: "a", Order: &models.Order{OrderUID: "a"}, Timestamp: time.Now()},
+	}
+
+	t.Run("stale snapshot", func(t *testing.T) {
+		path := filepath.Join(dir, "stale")
+		if err := SaveSnapshot(path, entries); err != nil {
+			t.Fatalf("SaveSnapshot failed: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		if _, _, err := LoadSnapshot(path, time.Millisecond); !errors.Is(err, ErrSnapshotStale) {
+			t.Errorf("Expected ErrSnapshotStale, got %v", err)
+		}
+		if _, _, err := LoadSnapshot(path, 0); err != nil {
+			t.Errorf("Expected no age check for maxAge 0, got %v", err)
+		}
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		path := filepath.Join(dir, "corrupt")
+		if err := SaveSnapshot(path, entries); err != nil {
+			t.Fatalf("SaveSnapshot failed: %v", err)
+		}
+
+		data, _ := os.ReadFile(path)
+		data[len(data)-1] ^= 0xff
+		os.WriteFile(path, data, 0o644)
+
+		if _, _, err := LoadSnapshot(path, time.Hour); !errors.Is(err, ErrSnapshotCorrupt) {
+			t.Errorf("Expected ErrSnapshotCorrupt, got %v", err)
+		}
+	})
+
+	t.Run("not a snapshot", func(t *testing.T) {
+		path := filepath.Join(dir, "garbage")
+		os.WriteFile(path, []byte("hello"), 0o644)
+
+		if _, _, err := LoadSnapshot(path, time.Hour); !errors.Is(err, ErrSnapshotCorrupt) {
+			t.Errorf("Expected ErrSnapshotCorrupt, got %v", err)
+		}
+	})
+
+	t.Run("empty cache", func(t *testing.T) {
+		path := filepath.Join(dir, "empty")
+		if err := SaveSnapshot(path, nil); err != nil {
+			t.Fatalf("SaveSnapshot failed: %v", err)
+		}
+		got, _, err := LoadSnapshot(path, time.Hour)
+		if err != nil || len(got) != 0 {
+			t.Errorf("Expected empty snapshot, got %v, %v", got, err)
+		}
+	})
+}
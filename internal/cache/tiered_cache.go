@@ -0,0 +1,246 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"wb-service/internal/interfaces"
+	"wb-service/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Типы хранилища кэша (CACHE_BACKEND)
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+	BackendTiered = "tiered"
+)
+
+// DefaultInvalidationChannel - канал Redis для сообщений об инвалидации L1
+const DefaultInvalidationChannel = "wb-service:cache:invalidate"
+
+// Операции в сообщениях инвалидации: "<instance> <op> [key]"
+const (
+	invalidateKey   = "del"
+	invalidateClear = "clear"
+)
+
+// TieredCache - двухуровневый кэш: локальный кэш реплики (L1) перед общим
+// кэшем в Redis (L2). Промах L1 читается из L2 и копируется в L1. При Set,
+// Delete и Clear реплика публикует сообщение в канал Redis, и остальные
+// реплики удаляют устаревшую копию из своего L1.
+type TieredCache struct {
+	l1      interfaces.Cache
+	l2      *RedisCache
+	channel string
+	id      string // идентификатор реплики, свои сообщения игнорируются
+	loader  *readThrough
+
+	pubsub    *redis.PubSub
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewTieredCache создает двухуровневый кэш и подписывается на канал
+// инвалидации. Закрывает оба уровня в Close.
+func NewTieredCache(l1 interfaces.Cache, l2 *RedisCache, channel string, opts Options) (*TieredCache, error) {
+	if channel == "" {
+		channel = DefaultInvalidationChannel
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	c := &TieredCache{
+		l1:      l1,
+		l2:      l2,
+		channel: channel,
+		id:      hex.EncodeToString(id),
+		loader:  newReadThrough(opts.NegativeTTL),
+		done:    make(chan struct{}),
+	}
+
+	// Дожидаемся подтверждения подписки, чтобы не пропустить первые сообщения
+	ctx, cancel := l2.context()
+	defer cancel()
+
+	c.pubsub = l2.client.Subscribe(context.Background(), channel)
+	if _, err := c.pubsub.Receive(ctx); err != nil {
+		c.pubsub.Close()
+		return nil, err
+	}
+
+	go c.listen()
+
+	return c, nil
+}
+
+// listen применяет сообщения инвалидации других реплик к L1
+func (c *TieredCache) listen() {
+	defer close(c.done)
+
+	for msg := range c.pubsub.Channel() {
+		fields := strings.SplitN(msg.Payload, " ", 3)
+		if len(fields) < 2 || fields[0] == c.id {
+			continue
+		}
+
+		// Вместе с L1 забываются и ненайденные ключи: заказ, который
+		// записала другая реплика, не должен скрываться до истечения NegativeTTL
+		switch fields[1] {
+		case invalidateKey:
+			if len(fields) == 3 {
+				c.loader.invalidate(fields[2])
+				c.l1.Delete(fields[2])
+			}
+		case invalidateClear:
+			c.loader.reset()
+			c.l1.Clear()
+		}
+	}
+}
+
+// publish рассылает сообщение инвалидации остальным репликам
+func (c *TieredCache) publish(op string, key string) {
+	ctx, cancel := c.l2.context()
+	defer cancel()
+
+	payload := c.id + " " + op
+	if key != "" {
+		payload += " " + key
+	}
+	if err := c.l2.client.Publish(ctx, c.channel, payload).Err(); err != nil {
+		c.l2.fail("публикации инвалидации", err)
+	}
+}
+
+// Get ищет заказ в L1, затем в L2
+func (c *TieredCache) Get(key string) (*models.Order, bool) {
+	if order, found := c.l1.Get(key); found {
+		return order, true
+	}
+
+	// L1 хранит копию не дольше, чем осталось жить ключу в Redis
+	order, ttl, found := c.l2.getWithTTL(key)
+	if found {
+		c.l1.SetWithTTL(key, order, ttl)
+	}
+	return order, found
+}
+
+// Set записывает заказ в оба уровня и инвалидирует L1 остальных реплик
+func (c *TieredCache) Set(key string, order *models.Order) {
+	c.SetWithTTL(key, order, DefaultExpiration)
+}
+
+// SetWithTTL записывает заказ с собственным TTL в оба уровня
+// и инвалидирует L1 остальных реплик
+func (c *TieredCache) SetWithTTL(key string, order *models.Order, ttl time.Duration) {
+	c.loader.forget(key)
+	c.l2.SetWithTTL(key, order, ttl)
+	c.l1.SetWithTTL(key, order, ttl)
+	c.publish(invalidateKey, key)
+}
+
+// SetIfAbsent добавляет заказ, только если его нет в общем кэше. Раз
+// ключа не было в Redis, в L1 остальных реплик его тоже нет, и
+// инвалидация не рассылается.
+func (c *TieredCache) SetIfAbsent(key string, order *models.Order) bool {
+	c.loader.forget(key)
+	if !c.l2.SetIfAbsent(key, order) {
+		return false
+	}
+	c.l1.SetWithTTL(key, order, DefaultExpiration)
+	return true
+}
+
+// GetOrLoad получает значение из кэша или загружает его через load
+func (c *TieredCache) GetOrLoad(key string, load interfaces.OrderLoader) (*models.Order, error) {
+	return c.loader.getOrLoad(key, c.Get, c.Set, load)
+}
+
+// Delete удаляет заказ из обоих уровней во всех репликах
+func (c *TieredCache) Delete(key string) bool {
+	c.loader.invalidate(key)
+	inL2 := c.l2.Delete(key)
+	inL1 := c.l1.Delete(key)
+	c.publish(invalidateKey, key)
+	return inL1 || inL2
+}
+
+// LoadFromDB загружает заказы из БД в L2, только если общий кэш пуст:
+// иначе его уже прогрела другая реплика, и L1 заполнится из L2 по запросам
+func (c *TieredCache) LoadFromDB(db interfaces.Database) error {
+	if db == nil {
+		return nil
+	}
+
+	if size := c.l2.Size(); size > 0 {
+		log.Printf("Кэш в Redis уже содержит %d заказов, загрузка из БД не нужна", size)
+		return nil
+	}
+	return c.l2.LoadFromDB(db)
+}
+
+// Size возвращает размер L1
+func (c *TieredCache) Size() int {
+	return c.l1.Size()
+}
+
+// HotKeys возвращает самые ценные ключи L1
+func (c *TieredCache) HotKeys(n int) []string {
+	return c.l1.HotKeys(n)
+}
+
+// Clear очищает оба уровня во всех репликах
+func (c *TieredCache) Clear() {
+	c.loader.reset()
+	c.l2.Clear()
+	c.l1.Clear()
+	c.publish(invalidateClear, "")
+}
+
+// Stats возвращает статистику L1, статистика Redis - в поле L2
+func (c *TieredCache) Stats() interfaces.CacheStats {
+	stats := c.l1.Stats()
+	l2 := c.l2.Stats()
+	stats.L2 = &l2
+	c.loader.addStats(&stats)
+	return stats
+}
+
+// Entries возвращает записи L1 для снимка
+func (c *TieredCache) Entries() []interfaces.CacheEntry {
+	if snapshotter, ok := c.l1.(interfaces.CacheSnapshotter); ok {
+		return snapshotter.Entries()
+	}
+	return nil
+}
+
+// Restore восстанавливает записи снимка в L1
+func (c *TieredCache) Restore(entries []interfaces.CacheEntry) int {
+	if snapshotter, ok := c.l1.(interfaces.CacheSnapshotter); ok {
+		return snapshotter.Restore(entries)
+	}
+	return 0
+}
+
+// Close отписывается от инвалидаций и закрывает оба уровня
+func (c *TieredCache) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.pubsub.Close()
+		<-c.done
+
+		c.l1.Close()
+		err = c.l2.Close()
+	})
+	return err
+}
@@ -0,0 +1,156 @@
+package cache
+
+import "container/list"
+
+// Сегменты W-TinyLFU
+const (
+	segWindow = iota
+	segProbation
+	segProtected
+)
+
+type tinyLFUEntry struct {
+	key  string
+	seg  int
+	elem *list.Element
+}
+
+// tinyLFUPolicy реализует W-TinyLFU (Einziger, Friedman, Manes).
+// Новые ключи попадают в небольшое LRU-окно (1% емкости). Вытесненный
+// из окна кандидат допускается в основную SLRU-область (probation 20%
+// и protected 80%) только если по оценке count-min sketch к нему
+// обращаются чаще, чем к жертве из probation.
+type tinyLFUPolicy struct {
+	windowCap    int
+	mainCap      int
+	protectedCap int
+
+	segments [3]*list.List
+	entries  map[string]*tinyLFUEntry
+	sketch   *cmSketch
+}
+
+func newTinyLFUPolicy(capacity int) *tinyLFUPolicy {
+	windowCap := max(1, capacity/100)
+	mainCap := max(0, capacity-windowCap)
+
+	p := &tinyLFUPolicy{
+		windowCap:    windowCap,
+		mainCap:      mainCap,
+		protectedCap: mainCap * 80 / 100,
+		sketch:       newCMSketch(capacity),
+	}
+	p.Reset()
+	return p
+}
+
+func (p *tinyLFUPolicy) Name() string {
+	return PolicyTinyLFU
+}
+
+func (p *tinyLFUPolicy) Access(key string, hit bool) {
+	p.sketch.Increment(key)
+
+	e, ok := p.entries[key]
+	if !ok {
+		return
+	}
+
+	switch e.seg {
+	case segProbation:
+		// Повторное обращение переводит ключ в protected
+		p.move(e, segProtected)
+		if p.segments[segProtected].Len() > p.protectedCap {
+			demoted := p.segments[segProtected].Back().Value.(*tinyLFUEntry)
+			p.move(demoted, segProbation)
+		}
+	default:
+		p.segments[e.seg].MoveToFront(e.elem)
+	}
+}
+
+func (p *tinyLFUPolicy) Insert(key string) {
+	p.sketch.Increment(key)
+
+	e := &tinyLFUEntry{key: key, seg: segWindow}
+	e.elem = p.segments[segWindow].PushFront(e)
+	p.entries[key] = e
+}
+
+func (p *tinyLFUPolicy) Remove(key string) {
+	if e, ok := p.entries[key]; ok {
+		p.segments[e.seg].Remove(e.elem)
+		delete(p.entries, key)
+	}
+}
+
+func (p *tinyLFUPolicy) Evict() (string, bool) {
+	window := p.segments[segWindow]
+
+	for window.Len() > p.windowCap {
+		candidate := window.Back().Value.(*tinyLFUEntry)
+
+		// Пока основная область не заполнена, кандидат проходит без отбора
+		if p.segments[segProbation].Len()+p.segments[segProtected].Len() < p.mainCap {
+			p.move(candidate, segProbation)
+			continue
+		}
+
+		victim := p.mainVictim()
+		if victim == nil || p.sketch.Estimate(candidate.key) <= p.sketch.Estimate(victim.key) {
+			return p.drop(candidate), true
+		}
+
+		p.move(candidate, segProbation)
+		return p.drop(victim), true
+	}
+
+	for _, seg := range []int{segProbation, segProtected, segWindow} {
+		if elem := p.segments[seg].Back(); elem != nil {
+			return p.drop(elem.Value.(*tinyLFUEntry)), true
+		}
+	}
+	return "", false
+}
+
+func (p *tinyLFUPolicy) Keys() []string {
+	var keys []string
+	for _, seg := range []int{segProtected, segProbation, segWindow} {
+		for elem := p.segments[seg].Front(); elem != nil; elem = elem.Next() {
+			keys = append(keys, elem.Value.(*tinyLFUEntry).key)
+		}
+	}
+	return keys
+}
+
+func (p *tinyLFUPolicy) Reset() {
+	for i := range p.segments {
+		p.segments[i] = list.New()
+	}
+	p.entries = make(map[string]*tinyLFUEntry)
+	p.sketch.Reset()
+}
+
+// mainVictim возвращает жертву основной области: сначала из probation
+func (p *tinyLFUPolicy) mainVictim() *tinyLFUEntry {
+	if elem := p.segments[segProbation].Back(); elem != nil {
+		return elem.Value.(*tinyLFUEntry)
+	}
+	if elem := p.segments[segProtected].Back(); elem != nil {
+		return elem.Value.(*tinyLFUEntry)
+	}
+	return nil
+}
+
+// move переносит ключ в начало указанного сегмента
+func (p *tinyLFUPolicy) move(e *tinyLFUEntry, seg int) {
+	p.segments[e.seg].Remove(e.elem)
+	e.seg = seg
+	e.elem = p.segments[seg].PushFront(e)
+}
+
+func (p *tinyLFUPolicy) drop(e *tinyLFUEntry) string {
+	p.segments[e.seg].Remove(e.elem)
+	delete(p.entries, e.key)
+	return e.key
+}
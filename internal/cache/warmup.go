@@ -0,0 +1,320 @@
+package cache
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+	"wb-service/internal/interfaces"
+	"wb-service/models"
+)
+
+// DefaultWarmupPageSize - размер страницы при чтении заказов из БД
+const DefaultWarmupPageSize = 500
+
+// Состояния прогрева кэша
+const (
+	WarmupPending  = "pending"
+	WarmupRunning  = "running"
+	WarmupDone     = "done"
+	WarmupFailed   = "failed"
+	WarmupCanceled = "canceled"
+)
+
+// loadRecent читает заказы страницами от новых к старым и передает их в load,
+// пока не загружено limit заказов (0 - без ограничения), не закончилась
+// таблица или load не попросил остановиться. Возвращает число загруженных.
+// Страницы читаются по курсору, а не по смещению: заказы, добавленные во
+// время загрузки, не сдвигают страницы и не повторяют уже прочитанные.
+func loadRecent(db interfaces.Database, limit, pageSize int, load func([]models.Order) (int, bool)) (int, error) {
+	if pageSize <= 0 {
+		pageSize = DefaultWarmupPageSize
+	}
+
+	loaded := 0
+	var after interfaces.OrderCursor
+	for limit <= 0 || loaded < limit {
+		size := pageSize
+		if limit > 0 {
+			size = min(size, limit-loaded)
+		}
+
+		orders, err := db.GetRecentOrders(after, size)
+		if err != nil {
+			return loaded, err
+		}
+
+		n, more := load(orders)
+		loaded += n
+		if !more || len(orders) < size {
+			break
+		}
+		after = interfaces.CursorAfter(orders[len(orders)-1])
+	}
+	return loaded, nil
+}
+
+// WarmupOptions задает параметры фонового прогрева кэша
+type WarmupOptions struct {
+	Limit    int      // максимум заказов, 0 - до заполнения кэша
+	PageSize int      // размер страницы при чтении из БД
+	HotKeys  []string // ключи, которые загружаются первыми
+}
+
+// WarmupProgress - состояние фонового прогрева кэша
+type WarmupProgress struct {
+	State      string        `json:"state"`
+	Target     int           `json:"target"`   // сколько заказов планируется загрузить, 0 - неизвестно
+	Loaded     int           `json:"loaded"`   // загружено всего
+	HotKeys    int           `json:"hot_keys"` // из них по списку горячих ключей
+	Pages      int           `json:"pages"`    // прочитано страниц из БД
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt time.Time     `json:"finished_at"`
+	Duration   time.Duration `json:"duration_ns"`
+	Skipped    bool          `json:"skipped,omitempty"` // общий кэш (L2) уже прогрет другой репликой
+	Error      string        `json:"error,omitempty"`
+}
+
+// Warmer прогревает кэш в фоне: сначала заказы из списка горячих ключей,
+// затем самые свежие заказы из БД, страница за страницей, пока кэш
+// не заполнится. Работает с любой реализацией interfaces.Cache.
+type Warmer struct {
+	cache interfaces.Cache
+	db    interfaces.Database
+	opts  WarmupOptions
+
+	mutex    sync.RWMutex
+	progress WarmupProgress
+
+	seen      map[string]struct{}
+	evictions uint64 // вытеснений до начала прогрева
+	exhausted bool   // очередной заказ не поместился в лимит объема кэша
+}
+
+// NewWarmer создает прогрев кэша c заказами из db
+func NewWarmer(cache interfaces.Cache, db interfaces.Database, opts WarmupOptions) *Warmer {
+	if opts.PageSize <= 0 {
+		opts.PageSize = DefaultWarmupPageSize
+	}
+
+	return &Warmer{
+		cache:    cache,
+		db:       db,
+		opts:     opts,
+		progress: WarmupProgress{State: WarmupPending},
+	}
+}
+
+// Progress возвращает текущее состояние прогрева
+func (w *Warmer) Progress() WarmupProgress {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	progress := w.progress
+	if progress.State == WarmupRunning {
+		progress.Duration = time.Since(progress.StartedAt)
+	}
+	return progress
+}
+
+// Run выполняет прогрев до конца или до отмены ctx
+func (w *Warmer) Run(ctx context.Context) error {
+	stats := w.cache.Stats()
+	w.evictions = stats.Evictions
+	w.seen = make(map[string]struct{})
+	w.exhausted = false
+
+	target := w.opts.Limit
+	if target <= 0 || (stats.Capacity > 0 && stats.Capacity < target) {
+		target = stats.Capacity
+	}
+
+	w.update(func(p *WarmupProgress) {
+		p.State = WarmupRunning
+		p.Target = target
+		p.StartedAt = time.Now()
+	})
+
+	// Общий кэш второго уровня уже заполнен другой репликой: локальный
+	// уровень заполнится из него по запросам, БД не нужна
+	if stats.L2 != nil && stats.L2.Size > 0 {
+		w.update(func(p *WarmupProgress) {
+			p.State = WarmupDone
+			p.Skipped = true
+			p.FinishedAt = time.Now()
+			p.Duration = p.FinishedAt.Sub(p.StartedAt)
+		})
+		log.Printf("Прогрев кэша пропущен: общий кэш уже содержит %d заказов", stats.L2.Size)
+		return nil
+	}
+	log.Printf("Прогрев кэша запущен: горячих ключей %d, цель %d заказов", len(w.opts.HotKeys), target)
+
+	err := w.loadHotKeys(ctx)
+	if err == nil {
+		err = w.loadRecent(ctx)
+	}
+
+	state := WarmupDone
+	switch {
+	case ctx.Err() != nil:
+		state = WarmupCanceled
+		err = ctx.Err()
+	case err != nil:
+		state = WarmupFailed
+	}
+
+	w.update(func(p *WarmupProgress) {
+		p.State = state
+		p.FinishedAt = time.Now()
+		p.Duration = p.FinishedAt.Sub(p.StartedAt)
+		if err != nil {
+			p.Error = err.Error()
+		}
+	})
+
+	progress := w.Progress()
+	if err != nil {
+		log.Printf("Прогрев кэша прерван (%s): загружено %d заказов: %v", state, progress.Loaded, err)
+		return err
+	}
+
+	log.Printf("Прогрев кэша завершен: загружено %d заказов (горячих %d) за %v",
+		progress.Loaded, progress.HotKeys, progress.Duration)
+	return nil
+}
+
+// loadHotKeys загружает заказы из списка горячих ключей пачками по PageSize
+func (w *Warmer) loadHotKeys(ctx context.Context) error {
+	keys := w.opts.HotKeys
+
+	for start := 0; start < len(keys) && !w.full(); start += w.opts.PageSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		orders, err := w.db.GetOrdersByUIDs(keys[start:min(start+w.opts.PageSize, len(keys))])
+		if err != nil {
+			return err
+		}
+
+		n := w.store(orders)
+		w.update(func(p *WarmupProgress) {
+			p.HotKeys += n
+			p.Loaded += n
+		})
+	}
+	return nil
+}
+
+// loadRecent дозагружает самые свежие заказы, пропуская уже загруженные.
+// Страницы читаются по курсору, как в функции loadRecent.
+func (w *Warmer) loadRecent(ctx context.Context) error {
+	var after interfaces.OrderCursor
+	for !w.full() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		orders, err := w.db.GetRecentOrders(after, w.opts.PageSize)
+		if err != nil {
+			return err
+		}
+
+		n := w.store(orders)
+		w.update(func(p *WarmupProgress) {
+			p.Loaded += n
+			p.Pages++
+		})
+
+		progress := w.Progress()
+		log.Printf("Прогрев кэша: страница %d, загружено %d из %d", progress.Pages, progress.Loaded, progress.Target)
+
+		if len(orders) < w.opts.PageSize {
+			return nil
+		}
+		after = interfaces.CursorAfter(orders[len(orders)-1])
+	}
+	return nil
+}
+
+// store кладет в кэш заказы, которых в нем еще нет, пока они помещаются
+// в лимиты кэша, и возвращает количество добавленных. Заказ, который уже
+// есть в кэше, не перезаписывается: его мог записать consumer во время
+// прогрева, и строка из БД может быть старее. Статистика кэша
+// запрашивается один раз на страницу: у распределенного кэша это может
+// быть сетевой запрос.
+func (w *Warmer) store(orders []models.Order) int {
+	room, free := w.room()
+
+	stored := 0
+	for i := range orders {
+		if room >= 0 && stored >= room {
+			break
+		}
+
+		order := &orders[i]
+		if _, ok := w.seen[order.OrderUID]; ok {
+			continue
+		}
+
+		// Кэш хранит копию заказа: у нее массив товаров без запаса емкости,
+		// который оставляет чтение из БД
+		size := entrySize(order.OrderUID, order.Clone())
+		if free >= 0 && size > free {
+			w.exhausted = true
+			break
+		}
+
+		w.seen[order.OrderUID] = struct{}{}
+		if w.cache.SetIfAbsent(order.OrderUID, order) {
+			stored++
+			if free >= 0 {
+				free -= size
+			}
+		}
+	}
+	return stored
+}
+
+// full сообщает, что прогрев пора остановить: достигнут лимит или кэш
+// заполнен и начал вытеснять записи
+func (w *Warmer) full() bool {
+	room, free := w.room()
+	return room == 0 || free == 0
+}
+
+// room возвращает, сколько еще заказов и байт можно загрузить; -1 - без
+// ограничения
+func (w *Warmer) room() (int, int64) {
+	if w.exhausted {
+		return 0, 0
+	}
+
+	room := -1
+	if w.opts.Limit > 0 {
+		room = max(0, w.opts.Limit-len(w.seen))
+	}
+
+	stats := w.cache.Stats()
+	if stats.Evictions > w.evictions {
+		return 0, 0
+	}
+	if stats.Capacity > 0 {
+		free := max(0, stats.Capacity-stats.Size)
+		if room < 0 || free < room {
+			room = free
+		}
+	}
+
+	free := int64(-1)
+	if stats.MaxBytes > 0 {
+		free = max(0, stats.MaxBytes-stats.Bytes)
+	}
+	return room, free
+}
+
+func (w *Warmer) update(fn func(p *WarmupProgress)) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	fn(&w.progress)
+}
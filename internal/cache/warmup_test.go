@@ -0,0 +1,291 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+	"wb-service/internal/interfaces"
+	"wb-service/models"
+)
+
+// setupWarmupDatabase создает n заказов; warm0 - самый старый
+func setupWarmupDatabase(t *testing.T, n int) *mockRepository {
+	db := setupTestDatabase()
+	base := time.Now().Add(-time.Hour)
+	for i := 0; i < n; i++ {
+		order := createTestOrderForLoadFromDB(fmt.Sprintf("warm%d", i))
+		order.DateCreated = base.Add(time.Duration(i) * time.Second)
+		order.Items = nil
+		if err := db.CreateOrder(order); err != nil {
+			t.Fatalf("Failed to create order: %v", err)
+		}
+	}
+	return db
+}
+
+func TestLRUCache_LoadFromDBRecentFirst(t *testing.T) {
+	db := setupWarmupDatabase(t, 10)
+	c := NewLRUCache(3, time.Hour)
+	defer c.Close()
+
+	if err := c.LoadFromDB(db); err != nil {
+		t.Fatalf("LoadFromDB failed: %v", err)
+	}
+
+	if c.Size() != 3 {
+		t.Fatalf("Expected 3 orders, got %d", c.Size())
+	}
+	for _, uid := range []string{"warm9", "warm8", "warm7"} {
+		if _, found := c.Get(uid); !found {
+			t.Errorf("Expected recent order %s to be loaded", uid)
+		}
+	}
+	if stats := c.Stats(); stats.LoadedEntries != 3 || stats.Evictions != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+// growingDatabase добавляет новый заказ после каждой прочитанной страницы
+// и запоминает UID всех прочитанных заказов
+type growingDatabase struct {
+	*mockRepository
+	t     *testing.T
+	added int
+	read  []string
+}
+
+func (d *growingDatabase) GetRecentOrders(after interfaces.OrderCursor, limit int) ([]models.Order, error) {
+	orders, err := d.mockRepository.GetRecentOrders(after, limit)
+	for _, order := range orders {
+		d.read = append(d.read, order.OrderUID)
+	}
+
+	d.added++
+	order := createTestOrderForLoadFromDB(fmt.Sprintf("new%d", d.added))
+	order.DateCreated = time.Now()
+	order.Items = nil
+	if err := d.CreateOrder(order); err != nil {
+		d.t.Fatalf("Failed to create order: %v", err)
+	}
+	return orders, err
+}
+
+func TestLoadRecent_OrdersAddedDuringLoad(t *testing.T) {
+	db := &growingDatabase{mockRepository: setupWarmupDatabase(t, 10), t: t}
+
+	loaded, err := loadRecent(db, 0, 3, func(orders []models.Order) (int, bool) {
+		return len(orders), true
+	})
+	if err != nil {
+		t.Fatalf("loadRecent failed: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, uid := range db.read {
+		if seen[uid] {
+			t.Errorf("Order %s read twice: %v", uid, db.read)
+		}
+		seen[uid] = true
+	}
+	if loaded != 10 || len(seen) != 10 {
+		t.Errorf("Expected the 10 original orders once each, got %d: %v", loaded, db.read)
+	}
+}
+
+func TestShardedCache_LoadFromDBRecentFirst(t *testing.T) {
+	db := setupWarmupDatabase(t, 20)
+	c := NewShardedCache(context.Background(), 2, Options{Capacity: 30})
+	defer c.Close()
+
+	if err := c.LoadFromDB(db); err != nil {
+		t.Fatalf("LoadFromDB failed: %v", err)
+	}
+
+	if stats := c.Stats(); stats.Size != 20 || stats.LoadedEntries != 20 {
+		t.Errorf("Expected all 20 orders to be loaded, got %+v", stats)
+	}
+}
+
+func TestWarmer_RecentOrders(t *testing.T) {
+	db := setupWarmupDatabase(t, 10)
+	c := NewLRUCache(100, time.Hour)
+	defer c.Close()
+
+	w := NewWarmer(c, db, WarmupOptions{Limit: 4, PageSize: 3})
+	if progress := w.Progress(); progress.State != WarmupPending {
+		t.Errorf("Expected pending state, got %s", progress.State)
+	}
+
+	if err := w.Run(context.Background()); err != nil {
+		t.Fatalf("Warmup failed: %v", err)
+	}
+
+	progress := w.Progress()
+	if progress.State != WarmupDone || progress.Loaded != 4 || progress.Target != 4 {
+		t.Errorf("Unexpected progress: %+v", progress)
+	}
+	if progress.Pages != 2 {
+		t.Errorf("Expected 2 pages, got %d", progress.Pages)
+	}
+	for _, uid := range []string{"warm9", "warm8", "warm7", "warm6"} {
+		if _, found := c.Get(uid); !found {
+			t.Errorf("Expected %s to be warmed", uid)
+		}
+	}
+	if c.Size() != 4 {
+		t.Errorf("Expected 4 orders, got %d", c.Size())
+	}
+}
+
+func TestWarmer_HotKeysFirst(t *testing.T) {
+	db := setupWarmupDatabase(t, 10)
+	c := NewLRUCache(3, time.Hour)
+	defer c.Close()
+
+	w := NewWarmer(c, db, WarmupOptions{PageSize: 2, HotKeys: []string{"warm1", "missing", "warm2"}})
+	if err := w.Run(context.Background()); err != nil {
+		t.Fatalf("Warmup failed: %v", err)
+	}
+
+	progress := w.Progress()
+	if progress.HotKeys != 2 || progress.Loaded != 3 {
+		t.Errorf("Unexpected progress: %+v", progress)
+	}
+	for _, uid := range []string{"warm1", "warm2", "warm9"} {
+		if _, found := c.Get(uid); !found {
+			t.Errorf("Expected %s to be warmed", uid)
+		}
+	}
+	if stats := c.Stats(); stats.Evictions != 0 {
+		t.Errorf("Expected warmup to stop before evicting, got %d evictions", stats.Evictions)
+	}
+}
+
+func TestWarmer_KeepsExistingEntries(t *testing.T) {
+	db := setupWarmupDatabase(t, 5)
+	c := NewLRUCache(100, time.Hour)
+	defer c.Close()
+
+	// consumer успел записать более новую версию заказа до прогрева
+	newer := createTestOrderForLoadFromDB("warm4")
+	newer.TrackNumber = "FROM_CONSUMER"
+	c.Set("warm4", newer)
+
+	w := NewWarmer(c, db, WarmupOptions{PageSize: 2})
+	if err := w.Run(context.Background()); err != nil {
+		t.Fatalf("Warmup failed: %v", err)
+	}
+
+	if got, found := c.Get("warm4"); !found || got.TrackNumber != "FROM_CONSUMER" {
+		t.Errorf("Expected warmup to keep the consumer's order, got %v", got)
+	}
+	if progress := w.Progress(); progress.Loaded != 4 {
+		t.Errorf("Expected 4 orders loaded, got %+v", progress)
+	}
+	if c.Size() != 5 {
+		t.Errorf("Expected 5 orders, got %d", c.Size())
+	}
+}
+
+func TestWarmer_ByteLimit(t *testing.T) {
+	db := setupWarmupDatabase(t, 10)
+	orders, err := db.GetRecentOrders(interfaces.OrderCursor{}, 1)
+	if err != nil || len(orders) != 1 {
+		t.Fatalf("GetRecentOrders failed: %v", err)
+	}
+	size := entrySize(orders[0].OrderUID, orders[0].Clone())
+
+	// Кэш без ограничения по количеству вмещает три заказа
+	c := NewLRUCacheWithOptions(context.Background(), Options{MaxBytes: 3*size + size/2, TTL: time.Hour})
+	defer c.Close()
+
+	w := NewWarmer(c, db, WarmupOptions{PageSize: 2})
+	if err := w.Run(context.Background()); err != nil {
+		t.Fatalf("Warmup failed: %v", err)
+	}
+
+	progress := w.Progress()
+	if progress.Loaded != 3 || progress.Pages != 2 {
+		t.Errorf("Expected 3 orders from 2 pages, got %+v", progress)
+	}
+	if stats := c.Stats(); stats.Evictions != 0 || stats.Size != 3 {
+		t.Errorf("Expected warmup to stop before evicting, got %+v", stats)
+	}
+}
+
+func TestWarmer_Canceled(t *testing.T) {
+	db := setupWarmupDatabase(t, 3)
+	c := NewLRUCache(100, time.Hour)
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	w := NewWarmer(c, db, WarmupOptions{})
+	if err := w.Run(ctx); err == nil {
+		t.Error("Expected error for canceled warmup")
+	}
+	if progress := w.Progress(); progress.State != WarmupCanceled {
+		t.Errorf("Expected canceled state, got %s", progress.State)
+	}
+}
+
+func TestHotKeys(t *testing.T) {
+	c := NewLRUCache(10, time.Hour)
+	defer c.Close()
+
+	for _, key := range []string{"a", "b", "c"} {
+		c.Set(key, &models.Order{OrderUID: key})
+	}
+	c.Get("a")
+
+	if keys := c.HotKeys(2); !reflect.DeepEqual(keys, []string{"a", "c"}) {
+		t.Errorf("Expected [a c], got %v", keys)
+	}
+
+	sharded := NewShardedCache(context.Background(), 4, Options{Capacity: 40})
+	defer sharded.Close()
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("order%d", i)
+		sharded.Set(key, &models.Order{OrderUID: key})
+	}
+	if keys := sharded.HotKeys(0); len(keys) != 10 {
+		t.Errorf("Expected 10 keys from sharded cache, got %d", len(keys))
+	}
+	if keys := sharded.HotKeys(5); len(keys) != 5 {
+		t.Errorf("Expected 5 keys from sharded cache, got %d", len(keys))
+	}
+}
+
+func TestSaveLoadHotKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hot_keys.txt")
+
+	keys, err := LoadHotKeys(path)
+	if err != nil || keys != nil {
+		t.Fatalf("Expected no keys for missing file, got %v, %v", keys, err)
+	}
+
+	if err := SaveHotKeys(path, []string{"a", "b"}); err != nil {
+		t.Fatalf("SaveHotKeys failed: %v", err)
+	}
+	if err := SaveHotKeys(path, []string{"c", "d", "e"}); err != nil {
+		t.Fatalf("SaveHotKeys failed: %v", err)
+	}
+
+	keys, err = LoadHotKeys(path)
+	if err != nil {
+		t.Fatalf("LoadHotKeys failed: %v", err)
+	}
+	if !reflect.DeepEqual(keys, []string{"c", "d", "e"}) {
+		t.Errorf("Expected [c d e], got %v", keys)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("Expected temporary files to be removed, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,234 @@
+package codec
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"time"
+	"wb-service/models"
+
+	"github.com/hamba/avro/v2"
+	"github.com/hamba/avro/v2/ocf"
+)
+
+//go:embed order.avsc
+var orderSchemaJSON string
+
+// OrderSchema - Avro-схема заказа (order.avsc)
+var OrderSchema = avro.MustParse(orderSchemaJSON)
+
+// AvroCodec кодирует заказы в Avro Object Container File: каждое сообщение
+// несет схему писателя, поэтому потребителю не нужен реестр схем
+type AvroCodec struct{}
+
+// ContentType возвращает content-type формата
+func (AvroCodec) ContentType() string {
+	return ContentTypeAvro
+}
+
+// Encode сериализует заказ в Avro с встроенной схемой
+func (AvroCodec) Encode(order *models.Order) ([]byte, error) {
+	if order == nil {
+		return nil, errors.New("order cannot be nil")
+	}
+
+	var buf bytes.Buffer
+	enc, err := ocf.NewEncoderWithSchema(OrderSchema, &buf)
+	if err != nil {
+		return nil, err
+	}
+	if err := enc.Encode(toAvroOrder(order)); err != nil {
+		return nil, fmt.Errorf("avro encode: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("avro encode: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode десериализует заказ из Avro, используя схему из сообщения
+func (AvroCodec) Decode(data []byte) (*models.Order, error) {
+	dec, err := ocf.NewDecoder(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("avro decode: %w", err)
+	}
+
+	if !dec.HasNext() {
+		if err := dec.Error(); err != nil {
+			return nil, fmt.Errorf("avro decode: %w", err)
+		}
+		return nil, errors.New("avro decode: message contains no records")
+	}
+
+	var rec avroOrder
+	if err := dec.Decode(&rec); err != nil {
+		return nil, fmt.Errorf("avro decode: %w", err)
+	}
+	return rec.toModel(), nil
+}
+
+// avroOrder и вложенные типы повторяют order.avsc
+type avroOrder struct {
+	OrderUID          string       `avro:"order_uid"`
+	TrackNumber       string       `avro:"track_number"`
+	Entry             string       `avro:"entry"`
+	Delivery          avroDelivery `avro:"delivery"`
+	Payment           avroPayment  `avro:"payment"`
+	Items             []avroItem   `avro:"items"`
+	Locale            string       `avro:"locale"`
+	InternalSignature string       `avro:"internal_signature"`
+	CustomerID        string       `avro:"customer_id"`
+	DeliveryService   string       `avro:"delivery_service"`
+	Shardkey          string       `avro:"shardkey"`
+	SmID              int64        `avro:"sm_id"`
+	DateCreated       time.Time    `avro:"date_created"`
+	OofShard          string       `avro:"oof_shard"`
+}
+
+type avroDelivery struct {
+	Name    string `avro:"name"`
+	Phone   string `avro:"phone"`
+	Zip     string `avro:"zip"`
+	City    string `avro:"city"`
+	Address string `avro:"address"`
+	Region  string `avro:"region"`
+	Email   string `avro:"email"`
+}
+
+type avroPayment struct {
+	Transaction  string `avro:"transaction"`
+	RequestID    string `avro:"request_id"`
+	Currency     string `avro:"currency"`
+	Provider     string `avro:"provider"`
+	Amount       int64  `avro:"amount"`
+	PaymentDt    int64  `avro:"payment_dt"`
+	Bank         string `avro:"bank"`
+	DeliveryCost int64  `avro:"delivery_cost"`
+	GoodsTotal   int64  `avro:"goods_total"`
+	CustomFee    int64  `avro:"custom_fee"`
+}
+
+type avroItem struct {
+	ChrtID      int64  `avro:"chrt_id"`
+	TrackNumber string `avro:"track_number"`
+	Price       int64  `avro:"price"`
+	Rid         string `avro:"rid"`
+	Name        string `avro:"name"`
+	Sale        int64  `avro:"sale"`
+	Size        string `avro:"size"`
+	TotalPrice  int64  `avro:"total_price"`
+	NmID        int64  `avro:"nm_id"`
+	Brand       string `avro:"brand"`
+	Status      int64  `avro:"status"`
+}
+
+func toAvroOrder(o *models.Order) *avroOrder {
+	items := make([]avroItem, len(o.Items))
+	for i, it := range o.Items {
+		items[i] = avroItem{
+			ChrtID:      int64(it.ChrtID),
+			TrackNumber: it.TrackNumber,
+			Price:       int64(it.Price),
+			Rid:         it.Rid,
+			Name:        it.Name,
+			Sale:        int64(it.Sale),
+			Size:        it.Size,
+			TotalPrice:  int64(it.TotalPrice),
+			NmID:        int64(it.NmID),
+			Brand:       it.Brand,
+			Status:      int64(it.Status),
+		}
+	}
+
+	return &avroOrder{
+		OrderUID:    o.OrderUID,
+		TrackNumber: o.TrackNumber,
+		Entry:       o.Entry,
+		Delivery: avroDelivery{
+			Name:    o.Delivery.Name,
+			Phone:   o.Delivery.Phone,
+			Zip:     o.Delivery.Zip,
+			City:    o.Delivery.City,
+			Address: o.Delivery.Address,
+			Region:  o.Delivery.Region,
+			Email:   o.Delivery.Email,
+		},
+		Payment: avroPayment{
+			Transaction:  o.Payment.Transaction,
+			RequestID:    o.Payment.RequestID,
+			Currency:     o.Payment.Currency,
+			Provider:     o.Payment.Provider,
+			Amount:       int64(o.Payment.Amount),
+			PaymentDt:    o.Payment.PaymentDt,
+			Bank:         o.Payment.Bank,
+			DeliveryCost: int64(o.Payment.DeliveryCost),
+			GoodsTotal:   int64(o.Payment.GoodsTotal),
+			CustomFee:    int64(o.Payment.CustomFee),
+		},
+		Items:             items,
+		Locale:            o.Locale,
+		InternalSignature: o.InternalSignature,
+		CustomerID:        o.CustomerID,
+		DeliveryService:   o.DeliveryService,
+		Shardkey:          o.Shardkey,
+		SmID:              int64(o.SmID),
+		DateCreated:       o.DateCreated,
+		OofShard:          o.OofShard,
+	}
+}
+
+func (a *avroOrder) toModel() *models.Order {
+	var items []models.Item
+	for _, it := range a.Items {
+		items = append(items, models.Item{
+			ChrtID:      int(it.ChrtID),
+			TrackNumber: it.TrackNumber,
+			Price:       int(it.Price),
+			Rid:         it.Rid,
+			Name:        it.Name,
+			Sale:        int(it.Sale),
+			Size:        it.Size,
+			TotalPrice:  int(it.TotalPrice),
+			NmID:        int(it.NmID),
+			Brand:       it.Brand,
+			Status:      int(it.Status),
+		})
+	}
+
+	return &models.Order{
+		OrderUID:    a.OrderUID,
+		TrackNumber: a.TrackNumber,
+		Entry:       a.Entry,
+		Delivery: models.Delivery{
+			Name:    a.Delivery.Name,
+			Phone:   a.Delivery.Phone,
+			Zip:     a.Delivery.Zip,
+			City:    a.Delivery.City,
+			Address: a.Delivery.Address,
+			Region:  a.Delivery.Region,
+			Email:   a.Delivery.Email,
+		},
+		Payment: models.Payment{
+			Transaction:  a.Payment.Transaction,
+			RequestID:    a.Payment.RequestID,
+			Currency:     a.Payment.Currency,
+			Provider:     a.Payment.Provider,
+			Amount:       int(a.Payment.Amount),
+			PaymentDt:    a.Payment.PaymentDt,
+			Bank:         a.Payment.Bank,
+			DeliveryCost: int(a.Payment.DeliveryCost),
+			GoodsTotal:   int(a.Payment.GoodsTotal),
+			CustomFee:    int(a.Payment.CustomFee),
+		},
+		Items:             items,
+		Locale:            a.Locale,
+		InternalSignature: a.InternalSignature,
+		CustomerID:        a.CustomerID,
+		DeliveryService:   a.DeliveryService,
+		Shardkey:          a.Shardkey,
+		SmID:              int(a.SmID),
+		DateCreated:       a.DateCreated,
+		OofShard:          a.OofShard,
+	}
+}
@@ -0,0 +1,100 @@
+package codec
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+	"wb-service/internal/interfaces"
+	"wb-service/models"
+)
+
+// Поддерживаемые форматы сообщений
+const (
+	FormatJSON     = "json"
+	FormatProtobuf = "protobuf"
+	FormatAvro     = "avro"
+)
+
+// HeaderContentType - заголовок сообщения Kafka с форматом тела. Общий для
+// продюсеров (генератор) и consumer.
+const HeaderContentType = "content-type"
+
+// Значения заголовка content-type для каждого формата
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+	ContentTypeAvro     = "application/avro"
+)
+
+// contentTypeAliases сопоставляет альтернативные content-type с форматами
+var contentTypeAliases = map[string]string{
+	ContentTypeJSON:           FormatJSON,
+	"text/json":               FormatJSON,
+	ContentTypeProtobuf:       FormatProtobuf,
+	"application/protobuf":    FormatProtobuf,
+	"application/x-protobuf3": FormatProtobuf,
+	ContentTypeAvro:           FormatAvro,
+	"avro/binary":             FormatAvro,
+	"application/x-avro":      FormatAvro,
+}
+
+// New возвращает кодек для указанного формата
+func New(format string) (interfaces.OrderCodec, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case FormatJSON, "":
+		return JSONCodec{}, nil
+	case FormatProtobuf, "proto":
+		return ProtobufCodec{}, nil
+	case FormatAvro:
+		return AvroCodec{}, nil
+	default:
+		return nil, fmt.Errorf("unknown message format %q", format)
+	}
+}
+
+// ForContentType возвращает кодек по значению заголовка content-type
+func ForContentType(contentType string) (interfaces.OrderCodec, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+
+	format, ok := contentTypeAliases[mediaType]
+	if !ok {
+		return nil, false
+	}
+
+	c, err := New(format)
+	if err != nil {
+		return nil, false
+	}
+	return c, true
+}
+
+// Selector выбирает декодер по content-type сообщения,
+// а при его отсутствии использует формат из конфигурации
+type Selector struct {
+	fallback interfaces.OrderCodec
+}
+
+// NewSelector создает селектор с форматом по умолчанию
+func NewSelector(defaultFormat string) (*Selector, error) {
+	fallback, err := New(defaultFormat)
+	if err != nil {
+		return nil, err
+	}
+	return &Selector{fallback: fallback}, nil
+}
+
+// Decode декодирует сообщение с учетом его content-type
+func (s *Selector) Decode(contentType string, data []byte) (*models.Order, error) {
+	if contentType == "" {
+		return s.fallback.Decode(data)
+	}
+
+	c, ok := ForContentType(contentType)
+	if !ok {
+		return nil, fmt.Errorf("unsupported content-type %q", contentType)
+	}
+	return c.Decode(data)
+}
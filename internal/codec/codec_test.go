@@ -0,0 +1,254 @@
+package codec
+
+import (
+	"reflect"
+	"testing"
+	"time"
+	"wb-service/models"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func createTestOrder() *models.Order {
+	return &models.Order{
+		OrderUID:          "codec_test_order",
+		TrackNumber:       "CODEC_TRACK",
+		Entry:             "WBIL",
+		Locale:            "en",
+		InternalSignature: "",
+		CustomerID:        "codec_customer",
+		DeliveryService:   "meest",
+		Shardkey:          "9",
+		SmID:              99,
+		DateCreated:       time.Date(2024, 11, 26, 6, 22, 19, 123456000, time.UTC),
+		OofShard:          "1",
+		Delivery: models.Delivery{
+			Name:    "Test Testov",
+			Phone:   "+9720000000",
+			Zip:     "2639809",
+			City:    "Kiryat Mozkin",
+			Address: "Ploshad Mira 15",
+			Region:  "Kraiot",
+			Email:   "test@gmail.com",
+		},
+		Payment: models.Payment{
+			Transaction:  "codec_test_order",
+			RequestID:    "",
+			Currency:     "USD",
+			Provider:     "wbpay",
+			Amount:       1817,
+			PaymentDt:    1637907727,
+			Bank:         "alpha",
+			DeliveryCost: 1500,
+			GoodsTotal:   317,
+			CustomFee:    0,
+		},
+		Items: []models.Item{
+			{
+				ChrtID:      9934930,
+				TrackNumber: "CODEC_TRACK",
+				Price:       453,
+				Rid:         "ab4219087a764ae0btest",
+				Name:        "Mascaras",
+				Sale:        30,
+				Size:        "0",
+				TotalPrice:  317,
+				NmID:        2389232,
+				Brand:       "Vivienne Sabo",
+				Status:      202,
+			},
+			{
+				ChrtID:      1,
+				TrackNumber: "CODEC_TRACK",
+				Price:       -5, // отрицательные значения должны переживать varint
+				Rid:         "rid2",
+				Name:        "Second",
+				Size:        "M",
+				TotalPrice:  10,
+				NmID:        2,
+				Brand:       "Brand",
+				Status:      200,
+			},
+		},
+	}
+}
+
+func TestCodecs_RoundTrip(t *testing.T) {
+	for _, format := range []string{FormatJSON, FormatProtobuf, FormatAvro} {
+		t.Run(format, func(t *testing.T) {
+			c, err := New(format)
+			if err != nil {
+				t.Fatalf("Failed to create codec: %v", err)
+			}
+
+			order := createTestOrder()
+			data, err := c.Encode(order)
+			if err != nil {
+				t.Fatalf("Failed to encode order: %v", err)
+			}
+
+			decoded, err := c.Decode(data)
+			if err != nil {
+				t.Fatalf("Failed to decode order: %v", err)
+			}
+
+			if !decoded.DateCreated.Equal(order.DateCreated) {
+				t.Errorf("Expected date_created %v, got %v", order.DateCreated, decoded.DateCreated)
+			}
+
+			// Время сравнили отдельно, т.к. часовой пояс может отличаться
+			decoded.DateCreated = order.DateCreated
+			if !reflect.DeepEqual(decoded, order) {
+				t.Errorf("Round-trip mismatch:\nexpected %+v\ngot      %+v", order, decoded)
+			}
+		})
+	}
+}
+
+func TestCodecs_DecodeGarbage(t *testing.T) {
+	garbage := []byte{0xff, 0xff, 0xff}
+
+	for _, format := range []string{FormatJSON, FormatProtobuf, FormatAvro} {
+		t.Run(format, func(t *testing.T) {
+			c, _ := New(format)
+			if _, err := c.Decode(garbage); err == nil {
+				t.Error("Expected error for malformed message")
+			}
+		})
+	}
+}
+
+func TestProtobufCodec_SkipsUnknownFields(t *testing.T) {
+	order := createTestOrder()
+	data, _ := ProtobufCodec{}.Encode(order)
+
+	// Поле 100 отсутствует в схеме - новые продюсеры не должны ломать старых потребителей
+	data = protowire.AppendTag(data, 100, protowire.BytesType)
+	data = protowire.AppendString(data, "future field")
+
+	decoded, err := ProtobufCodec{}.Decode(data)
+	if err != nil {
+		t.Fatalf("Expected unknown field to be skipped, got: %v", err)
+	}
+
+	if decoded.OrderUID != order.OrderUID {
+		t.Errorf("Expected OrderUID %s, got %s", order.OrderUID, decoded.OrderUID)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		format      string
+		contentType string
+		wantErr     bool
+	}{
+		{"json", ContentTypeJSON, false},
+		{"", ContentTypeJSON, false},
+		{"protobuf", ContentTypeProtobuf, false},
+		{"PROTO", ContentTypeProtobuf, false},
+		{"avro", ContentTypeAvro, false},
+		{"xml", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			c, err := New(tt.format)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error for unknown format")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if c.ContentType() != tt.contentType {
+				t.Errorf("Expected content-type %s, got %s", tt.contentType, c.ContentType())
+			}
+		})
+	}
+}
+
+func TestForContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		expected    string
+		ok          bool
+	}{
+		{"application/json", ContentTypeJSON, true},
+		{"application/json; charset=utf-8", ContentTypeJSON, true},
+		{"application/x-protobuf", ContentTypeProtobuf, true},
+		{"application/protobuf", ContentTypeProtobuf, true},
+		{"avro/binary", ContentTypeAvro, true},
+		{"application/avro", ContentTypeAvro, true},
+		{"text/plain", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			c, ok := ForContentType(tt.contentType)
+			if ok != tt.ok {
+				t.Fatalf("Expected ok=%v, got %v", tt.ok, ok)
+			}
+
+			if ok && c.ContentType() != tt.expected {
+				t.Errorf("Expected content-type %s, got %s", tt.expected, c.ContentType())
+			}
+		})
+	}
+}
+
+func TestSelector_Decode(t *testing.T) {
+	order := createTestOrder()
+	jsonData, _ := JSONCodec{}.Encode(order)
+	protoData, _ := ProtobufCodec{}.Encode(order)
+	avroData, _ := AvroCodec{}.Encode(order)
+
+	t.Run("header takes precedence over default format", func(t *testing.T) {
+		selector, err := NewSelector(FormatJSON)
+		if err != nil {
+			t.Fatalf("Failed to create selector: %v", err)
+		}
+
+		decoded, err := selector.Decode(ContentTypeProtobuf, protoData)
+		if err != nil {
+			t.Fatalf("Failed to decode protobuf: %v", err)
+		}
+		if decoded.OrderUID != order.OrderUID {
+			t.Errorf("Expected OrderUID %s, got %s", order.OrderUID, decoded.OrderUID)
+		}
+
+		if _, err := selector.Decode(ContentTypeAvro, avroData); err != nil {
+			t.Errorf("Failed to decode avro: %v", err)
+		}
+	})
+
+	t.Run("default format used without header", func(t *testing.T) {
+		selector, _ := NewSelector(FormatAvro)
+
+		if _, err := selector.Decode("", avroData); err != nil {
+			t.Errorf("Failed to decode avro with default format: %v", err)
+		}
+
+		if _, err := selector.Decode("", jsonData); err == nil {
+			t.Error("Expected error decoding JSON with avro default format")
+		}
+	})
+
+	t.Run("unsupported content-type", func(t *testing.T) {
+		selector, _ := NewSelector(FormatJSON)
+
+		if _, err := selector.Decode("text/xml", jsonData); err == nil {
+			t.Error("Expected error for unsupported content-type")
+		}
+	})
+
+	t.Run("unknown default format", func(t *testing.T) {
+		if _, err := NewSelector("xml"); err == nil {
+			t.Error("Expected error for unknown default format")
+		}
+	})
+}
@@ -0,0 +1,28 @@
+package codec
+
+import (
+	"encoding/json"
+	"wb-service/models"
+)
+
+// JSONCodec кодирует заказы в JSON (формат по умолчанию)
+type JSONCodec struct{}
+
+// ContentType возвращает content-type формата
+func (JSONCodec) ContentType() string {
+	return ContentTypeJSON
+}
+
+// Encode сериализует заказ в JSON
+func (JSONCodec) Encode(order *models.Order) ([]byte, error) {
+	return json.Marshal(order)
+}
+
+// Decode десериализует заказ из JSON
+func (JSONCodec) Decode(data []byte) (*models.Order, error) {
+	var order models.Order
+	if err := json.Unmarshal(data, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
@@ -0,0 +1,158 @@
+package codec
+
+//go:generate protoc --go_out=orderpb --go_opt=paths=source_relative order.proto
+
+import (
+	"errors"
+	"fmt"
+	"wb-service/internal/codec/orderpb"
+	"wb-service/models"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ProtobufCodec кодирует заказы в protobuf по схеме order.proto. Типы
+// сообщений сгенерированы в пакет orderpb, здесь они только отображаются
+// на models.Order.
+type ProtobufCodec struct{}
+
+// ContentType возвращает content-type формата
+func (ProtobufCodec) ContentType() string {
+	return ContentTypeProtobuf
+}
+
+// Encode сериализует заказ в protobuf
+func (ProtobufCodec) Encode(order *models.Order) ([]byte, error) {
+	if order == nil {
+		return nil, errors.New("order cannot be nil")
+	}
+
+	data, err := proto.Marshal(orderToProto(order))
+	if err != nil {
+		return nil, fmt.Errorf("protobuf encode: %w", err)
+	}
+	return data, nil
+}
+
+// Decode десериализует заказ из protobuf. Неизвестные поля игнорируются.
+func (ProtobufCodec) Decode(data []byte) (*models.Order, error) {
+	var msg orderpb.Order
+	if err := proto.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("protobuf decode: %w", err)
+	}
+	return orderFromProto(&msg), nil
+}
+
+func orderToProto(o *models.Order) *orderpb.Order {
+	msg := &orderpb.Order{
+		OrderUid:    o.OrderUID,
+		TrackNumber: o.TrackNumber,
+		Entry:       o.Entry,
+		Delivery: &orderpb.Delivery{
+			Name:    o.Delivery.Name,
+			Phone:   o.Delivery.Phone,
+			Zip:     o.Delivery.Zip,
+			City:    o.Delivery.City,
+			Address: o.Delivery.Address,
+			Region:  o.Delivery.Region,
+			Email:   o.Delivery.Email,
+		},
+		Payment: &orderpb.Payment{
+			Transaction:  o.Payment.Transaction,
+			RequestId:    o.Payment.RequestID,
+			Currency:     o.Payment.Currency,
+			Provider:     o.Payment.Provider,
+			Amount:       int64(o.Payment.Amount),
+			PaymentDt:    o.Payment.PaymentDt,
+			Bank:         o.Payment.Bank,
+			DeliveryCost: int64(o.Payment.DeliveryCost),
+			GoodsTotal:   int64(o.Payment.GoodsTotal),
+			CustomFee:    int64(o.Payment.CustomFee),
+		},
+		Locale:            o.Locale,
+		InternalSignature: o.InternalSignature,
+		CustomerId:        o.CustomerID,
+		DeliveryService:   o.DeliveryService,
+		Shardkey:          o.Shardkey,
+		SmId:              int64(o.SmID),
+		OofShard:          o.OofShard,
+	}
+	if !o.DateCreated.IsZero() {
+		msg.DateCreated = timestamppb.New(o.DateCreated)
+	}
+
+	for _, it := range o.Items {
+		msg.Items = append(msg.Items, &orderpb.Item{
+			ChrtId:      int64(it.ChrtID),
+			TrackNumber: it.TrackNumber,
+			Price:       int64(it.Price),
+			Rid:         it.Rid,
+			Name:        it.Name,
+			Sale:        int64(it.Sale),
+			Size:        it.Size,
+			TotalPrice:  int64(it.TotalPrice),
+			NmId:        int64(it.NmID),
+			Brand:       it.Brand,
+			Status:      int64(it.Status),
+		})
+	}
+	return msg
+}
+
+func orderFromProto(msg *orderpb.Order) *models.Order {
+	d, p := msg.GetDelivery(), msg.GetPayment()
+	order := &models.Order{
+		OrderUID:    msg.GetOrderUid(),
+		TrackNumber: msg.GetTrackNumber(),
+		Entry:       msg.GetEntry(),
+		Delivery: models.Delivery{
+			Name:    d.GetName(),
+			Phone:   d.GetPhone(),
+			Zip:     d.GetZip(),
+			City:    d.GetCity(),
+			Address: d.GetAddress(),
+			Region:  d.GetRegion(),
+			Email:   d.GetEmail(),
+		},
+		Payment: models.Payment{
+			Transaction:  p.GetTransaction(),
+			RequestID:    p.GetRequestId(),
+			Currency:     p.GetCurrency(),
+			Provider:     p.GetProvider(),
+			Amount:       int(p.GetAmount()),
+			PaymentDt:    p.GetPaymentDt(),
+			Bank:         p.GetBank(),
+			DeliveryCost: int(p.GetDeliveryCost()),
+			GoodsTotal:   int(p.GetGoodsTotal()),
+			CustomFee:    int(p.GetCustomFee()),
+		},
+		Locale:            msg.GetLocale(),
+		InternalSignature: msg.GetInternalSignature(),
+		CustomerID:        msg.GetCustomerId(),
+		DeliveryService:   msg.GetDeliveryService(),
+		Shardkey:          msg.GetShardkey(),
+		SmID:              int(msg.GetSmId()),
+		OofShard:          msg.GetOofShard(),
+	}
+	if ts := msg.GetDateCreated(); ts != nil {
+		order.DateCreated = ts.AsTime()
+	}
+
+	for _, it := range msg.GetItems() {
+		order.Items = append(order.Items, models.Item{
+			ChrtID:      int(it.GetChrtId()),
+			TrackNumber: it.GetTrackNumber(),
+			Price:       int(it.GetPrice()),
+			Rid:         it.GetRid(),
+			Name:        it.GetName(),
+			Sale:        int(it.GetSale()),
+			Size:        it.GetSize(),
+			TotalPrice:  int(it.GetTotalPrice()),
+			NmID:        int(it.GetNmId()),
+			Brand:       it.GetBrand(),
+			Status:      int(it.GetStatus()),
+		})
+	}
+	return order
+}
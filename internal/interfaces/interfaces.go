@@ -2,24 +2,159 @@ package interfaces
 
 import (
 	"context"
+	"errors"
+	"time"
 	"wb-service/models"
 )
 
+// ErrNotFound возвращается загрузчиком кэша, если заказа нет в источнике
+var ErrNotFound = errors.New("order not found")
+
+// OrderLoader загружает заказ из источника данных при промахе кэша.
+// Если заказа нет, должен вернуть ошибку, для которой errors.Is(err, ErrNotFound).
+type OrderLoader func(key string) (*models.Order, error)
+
 // Database интерфейс для работы с базой данных
 type Database interface {
 	CreateOrder(order *models.Order) error
 	GetOrder(orderUID string) (*models.Order, error)
 	GetAllOrders() ([]models.Order, error)
+	// GetRecentOrders возвращает страницу заказов, отсортированных от новых
+	// к старым, которые идут после курсора after
+	GetRecentOrders(after OrderCursor, limit int) ([]models.Order, error)
+	// GetOrdersByUIDs возвращает найденные заказы в порядке переданных UID
+	GetOrdersByUIDs(orderUIDs []string) ([]models.Order, error)
 	Close() error
 }
 
+// OrderCursor - позиция в списке заказов, отсортированном по date_created
+// от новых к старым и затем по order_uid. Страница после курсора начинается
+// с заказов, созданных раньше DateCreated, а при равной дате - с order_uid
+// больше OrderUID. В отличие от OFFSET, заказы, добавленные во время чтения
+// страниц, не сдвигают следующие страницы. Нулевой курсор - начало списка.
+type OrderCursor struct {
+	DateCreated time.Time
+	OrderUID    string
+}
+
+// CursorAfter возвращает курсор, указывающий на позицию после order
+func CursorAfter(order models.Order) OrderCursor {
+	return OrderCursor{DateCreated: order.DateCreated, OrderUID: order.OrderUID}
+}
+
+// OrderEraser реализуется репозиториями, которые умеют удалять заказы
+// и обезличивать данные клиентов. Каждая операция пишет запись в журнал аудита.
+type OrderEraser interface {
+	// DeleteOrder мягко удаляет заказ: он больше не возвращается, но остается
+	// в БД до очистки по сроку хранения. Если заказа нет, возвращает
+	// gorm.ErrRecordNotFound.
+	DeleteOrder(orderUID string) error
+	// EraseCustomer обезличивает имя, телефон, email и адрес доставки и
+	// идентификатор клиента во всех его заказах, включая удаленные.
+	// Возвращает UID затронутых заказов.
+	EraseCustomer(customerID string) ([]string, error)
+	// PurgeOrders безвозвратно удаляет до limit (<= 0 - без ограничения)
+	// самых старых заказов, созданных раньше before, включая мягко удаленные,
+	// вместе со связанными записями. Возвращает UID удаленных заказов.
+	PurgeOrders(before time.Time, limit int) ([]string, error)
+}
+
 // Cache интерфейс для работы с кэшем
 type Cache interface {
 	Get(key string) (*models.Order, bool)
 	Set(key string, order *models.Order)
+	// SetWithTTL добавляет заказ с собственным временем жизни: 0 - TTL
+	// кэша по умолчанию, отрицательное значение - запись не истекает
+	SetWithTTL(key string, order *models.Order, ttl time.Duration)
+	// SetIfAbsent добавляет заказ с TTL кэша, только если ключа в кэше нет.
+	// Существующая запись и ее место в порядке вытеснения не меняются.
+	// Возвращает true, если заказ добавлен.
+	SetIfAbsent(key string, order *models.Order) bool
+	// GetOrLoad возвращает заказ из кэша, а при промахе загружает его через
+	// load и кладет в кэш. Одновременные промахи по одному ключу выполняют
+	// одну загрузку. Ненайденные ключи кэшируются как отсутствующие на
+	// короткое время, в течение которого load не вызывается.
+	GetOrLoad(key string, load OrderLoader) (*models.Order, error)
+	// Delete удаляет заказ из кэша. Загрузка GetOrLoad, начатая до
+	// удаления, не записывает свой результат в кэш, а следующий промах
+	// выполняет новую загрузку.
+	Delete(key string) bool
 	LoadFromDB(db Database) error
 	Size() int
 	Clear()
+	Stats() CacheStats
+	// HotKeys возвращает до n самых ценных ключей, n <= 0 - все
+	HotKeys(n int) []string
+	Close() error
+}
+
+// CacheEntry - запись кэша вместе с временем добавления и сроком жизни.
+// TTL == 0 означает TTL кэша, отсчитываемый от Timestamp (так
+// сохранялись снимки до появления TTL отдельных записей), отрицательный
+// TTL - запись не истекает.
+type CacheEntry struct {
+	Key       string
+	Order     *models.Order
+	Timestamp time.Time
+	ExpiresAt time.Time
+	TTL       time.Duration
+}
+
+// CacheSnapshotter реализуется кэшами, содержимое которых можно сохранить
+// на диск и восстановить после перезапуска
+type CacheSnapshotter interface {
+	// Entries возвращает актуальные записи от наиболее ценных к наименее ценным
+	Entries() []CacheEntry
+	// Restore добавляет записи в кэш, сохраняя их порядок и время добавления.
+	// Возвращает количество восстановленных записей.
+	Restore(entries []CacheEntry) int
+}
+
+// CacheStats статистика работы кэша
+type CacheStats struct {
+	Hits          uint64        `json:"hits"`
+	Misses        uint64        `json:"misses"`
+	HitRatio      float64       `json:"hit_ratio"`
+	Evictions     uint64        `json:"evictions"`
+	Expirations   uint64        `json:"expirations"`
+	Size          int           `json:"size"`
+	Capacity      int           `json:"capacity"`
+	Bytes         int64         `json:"bytes"`            // оценочный объем записей в памяти
+	MaxBytes      int64         `json:"max_bytes"`        // лимит объема, 0 - без ограничения
+	LoadedEntries int           `json:"loaded_entries"`   // записей загружено последним LoadFromDB
+	LoadDuration  time.Duration `json:"load_duration_ns"` // длительность последнего LoadFromDB
+	LoadedAt      time.Time     `json:"loaded_at"`        // время завершения последнего LoadFromDB
+	Policy        string        `json:"policy"`           // политика вытеснения
+	Expiration    string        `json:"expiration"`       // режим истечения записей: absolute или sliding
+
+	Loads           uint64 `json:"loads"`            // загрузок через GetOrLoad
+	SharedLoads     uint64 `json:"shared_loads"`     // промахов, дождавшихся чужой загрузки
+	NegativeHits    uint64 `json:"negative_hits"`    // обращений к ключам, закэшированным как отсутствующие
+	NegativeEntries int    `json:"negative_entries"` // ключей, закэшированных как отсутствующие
+
+	Errors uint64      `json:"errors,omitempty"` // ошибок внешнего хранилища (Redis)
+	L2     *CacheStats `json:"l2,omitempty"`     // статистика второго уровня многоуровневого кэша
+}
+
+// RateLimit - параметры корзины токенов: Rate токенов в секунду
+// пополняется до Burst
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimitResult - результат запроса токена
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int           // сколько токенов осталось в корзине
+	RetryAfter time.Duration // через сколько появится токен, если запрос отклонен
+}
+
+// RateLimiter ограничивает частоту запросов по алгоритму token bucket
+type RateLimiter interface {
+	// Allow забирает токен из корзины key. Корзина создается полной.
+	Allow(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error)
+	Close() error
 }
 
 // MessageConsumer интерфейс для получения сообщений из очереди
@@ -33,8 +168,15 @@ type OrderValidator interface {
 	Validate(order *models.Order) error
 }
 
+// OrderCodec интерфейс для сериализации заказов в сообщения очереди
+type OrderCodec interface {
+	ContentType() string
+	Encode(order *models.Order) ([]byte, error)
+	Decode(data []byte) (*models.Order, error)
+}
+
 // OrderService основной интерфейс сервиса заказов
 type OrderService interface {
 	GetOrder(orderUID string) (*models.Order, error)
 	ProcessOrder(order *models.Order) error
-}
\ No newline at end of file
+}
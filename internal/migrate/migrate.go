@@ -0,0 +1,407 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// DefaultTable - таблица, в которой хранятся примененные версии схемы
+const DefaultTable = "schema_migrations"
+
+// Ошибки проверки версии схемы
+var (
+	ErrSchemaOutdated = errors.New("database schema is outdated")
+	ErrSchemaTooNew   = errors.New("database schema is newer than the application")
+	ErrUnknownVersion = errors.New("unknown migration version")
+)
+
+// fileName - имя файла миграции: <версия>_<название>.<up|down>.sql
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration - одна версия схемы с SQL применения и отката
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status - состояние одной миграции в базе данных
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Load читает миграции из каталога dir файловой системы fsys (обычно
+// embed.FS) и возвращает их по возрастанию версий. У каждой версии
+// должны быть оба файла: up и down.
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	files, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, file := range files {
+		if file.IsDir() || path.Ext(file.Name()) != ".sql" {
+			continue
+		}
+
+		match := fileName.FindStringSubmatch(file.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration %s: name must be <version>_<name>.<up|down>.sql", file.Name())
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: invalid version", file.Name())
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d: conflicting names %s and %s", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s: both up and down files are required", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// session - общие методы *sql.DB и *sql.Conn, через которые выполняются
+// миграции
+type session interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// Migrator применяет и откатывает миграции. Каждая миграция выполняется
+// в отдельной транзакции вместе с записью в таблицу версий, поэтому
+// в PostgreSQL неудачная миграция не оставляет схему в промежуточном
+// состоянии.
+type Migrator struct {
+	db           *sql.DB
+	migrations   []Migration
+	table        string
+	advisoryLock bool
+}
+
+// New создает Migrator для миграций, отсортированных по версиям (см. Load)
+func New(db *sql.DB, migrations []Migration) *Migrator {
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		table:      DefaultTable,
+	}
+}
+
+// WithAdvisoryLock включает блокировку pg_advisory_lock на время To, Up и
+// Down: реплики, одновременно запущенные с DB_AUTO_MIGRATE=true, меняют
+// схему по очереди, а следующая видит уже примененные версии. Только для
+// PostgreSQL.
+func (m *Migrator) WithAdvisoryLock() *Migrator {
+	m.advisoryLock = true
+	return m
+}
+
+// lock возвращает соединение, через которое нужно менять схему, и функцию
+// его освобождения. С advisory lock это отдельное соединение, которое
+// держит блокировку: миграции выполняются в нем же, чтобы не ждать
+// свободного соединения пула, занятого блокировкой.
+func (m *Migrator) lock(ctx context.Context) (session, func(), error) {
+	if !m.advisoryLock {
+		return m.db, func() {}, nil
+	}
+
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock(hashtext($1))`, m.table); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("lock %s: %w", m.table, err)
+	}
+
+	unlock := func() {
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock(hashtext($1))`, m.table); err != nil {
+			log.Printf("Не удалось снять блокировку миграций: %v", err)
+			// Блокировка принадлежит сессии: соединение с ней нельзя
+			// возвращать в пул, поэтому оно закрывается
+			_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}
+	return conn, unlock, nil
+}
+
+// Latest возвращает последнюю известную версию схемы, 0 - миграций нет
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// ensureTable создает таблицу версий, если ее еще нет. applied_at хранится
+// в UTC без часового пояса: так его одинаково читают драйверы PostgreSQL и SQLite.
+func (m *Migrator) ensureTable(ctx context.Context, db session) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+m.table+` (
+		version BIGINT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`)
+	return err
+}
+
+// applied возвращает время применения каждой примененной версии
+func (m *Migrator) applied(ctx context.Context, db session) (map[int64]time.Time, error) {
+	if err := m.ensureTable(ctx, db); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT version, applied_at FROM `+m.table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var (
+			version   int64
+			appliedAt time.Time
+		)
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// Version возвращает текущую версию схемы: наибольшую примененную, 0 - ни одной
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	applied, err := m.applied(ctx, m.db)
+	if err != nil {
+		return 0, err
+	}
+
+	var version int64
+	for v := range applied {
+		if v > version {
+			version = v
+		}
+	}
+	return version, nil
+}
+
+// Status возвращает состояние всех известных миграций
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, len(m.migrations))
+	for i, migration := range m.migrations {
+		appliedAt, ok := applied[migration.Version]
+		statuses[i] = Status{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+		}
+	}
+	return statuses, nil
+}
+
+// Check проверяет, что схема базы данных соответствует последней
+// известной версии и все миграции применены
+func (m *Migrator) Check(ctx context.Context) error {
+	applied, err := m.applied(ctx, m.db)
+	if err != nil {
+		return err
+	}
+
+	latest := m.Latest()
+	for version := range applied {
+		if version > latest {
+			return fmt.Errorf("%w: version %d, expected %d", ErrSchemaTooNew, version, latest)
+		}
+	}
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			return fmt.Errorf("%w: migration %d_%s is not applied, expected version %d",
+				ErrSchemaOutdated, migration.Version, migration.Name, latest)
+		}
+	}
+	return nil
+}
+
+// Up применяет все непримененные миграции и возвращает их
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	return m.To(ctx, m.Latest())
+}
+
+// Down откатывает steps последних примененных миграций и возвращает их
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	db, unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	applied, err := m.applied(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if err := m.run(ctx, db, migration, false); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// To приводит схему к версии target: применяет непримененные миграции
+// до target включительно и откатывает примененные миграции новее target.
+// target == 0 откатывает все миграции.
+func (m *Migrator) To(ctx context.Context, target int64) ([]Migration, error) {
+	if target != 0 && !m.known(target) {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, target)
+	}
+
+	db, unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	applied, err := m.applied(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+
+	// Сначала откатываем лишние версии, от новых к старым
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok || migration.Version <= target {
+			continue
+		}
+		if err := m.run(ctx, db, migration, false); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+
+	// Затем применяем недостающие, от старых к новым
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok || migration.Version > target {
+			continue
+		}
+		if err := m.run(ctx, db, migration, true); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// known сообщает, есть ли миграция с версией version
+func (m *Migrator) known(version int64) bool {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// run применяет (up) или откатывает миграцию в одной транзакции
+// с изменением таблицы версий
+func (m *Migrator) run(ctx context.Context, db session, migration Migration, up bool) error {
+	direction, script := "down", migration.Down
+	if up {
+		direction, script = "up", migration.Up
+	}
+
+	start := time.Now()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("migration %d_%s %s: %w", migration.Version, migration.Name, direction, err)
+	}
+
+	if up {
+		_, err = tx.ExecContext(ctx, `INSERT INTO `+m.table+` (version, name, applied_at) VALUES ($1, $2, $3)`,
+			migration.Version, migration.Name, time.Now().UTC())
+	} else {
+		_, err = tx.ExecContext(ctx, `DELETE FROM `+m.table+` WHERE version = $1`, migration.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("migration %d_%s %s: record version: %w", migration.Version, migration.Name, direction, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if up {
+		log.Printf("Миграция %d_%s применена за %v", migration.Version, migration.Name, time.Since(start))
+	} else {
+		log.Printf("Миграция %d_%s откачена за %v", migration.Version, migration.Name, time.Since(start))
+	}
+	return nil
+}
@@ -0,0 +1,320 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	gormDB, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	db, err := gormDB.DB()
+	if err != nil {
+		t.Fatalf("Failed to get sql.DB: %v", err)
+	}
+	// Каждое соединение :memory: - отдельная база
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func testMigrations(t *testing.T) []Migration {
+	t.Helper()
+
+	fsys := fstest.MapFS{
+		"sql/0001_create_a.up.sql":   {Data: []byte("CREATE TABLE a (id INT);")},
+		"sql/0001_create_a.down.sql": {Data: []byte("DROP TABLE a;")},
+		"sql/0002_create_b.up.sql":   {Data: []byte("CREATE TABLE b (id INT); CREATE TABLE c (id INT);")},
+		"sql/0002_create_b.down.sql": {Data: []byte("DROP TABLE c; DROP TABLE b;")},
+		"sql/0010_add_a_name.up.sql": {Data: []byte("ALTER TABLE a ADD COLUMN name TEXT;")},
+		"sql/0010_add_a_name.down.sql": {
+			Data: []byte("ALTER TABLE a DROP COLUMN name;"),
+		},
+		"sql/README.md": {Data: []byte("ignored")},
+	}
+
+	migrations, err := Load(fsys, "sql")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	return migrations
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+
+	var count int
+	err := db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&count)
+	if err != nil {
+		t.Fatalf("Failed to query sqlite_master: %v", err)
+	}
+	return count == 1
+}
+
+func TestLoad(t *testing.T) {
+	migrations := testMigrations(t)
+
+	if len(migrations) != 3 {
+		t.Fatalf("Expected 3 migrations, got %d", len(migrations))
+	}
+
+	want := []struct {
+		version int64
+		name    string
+	}{{1, "create_a"}, {2, "create_b"}, {10, "add_a_name"}}
+	for i, w := range want {
+		if migrations[i].Version != w.version || migrations[i].Name != w.name {
+			t.Errorf("Migration %d: expected %d_%s, got %d_%s", i, w.version, w.name, migrations[i].Version, migrations[i].Name)
+		}
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"missing down": {
+			"sql/0001_a.up.sql": {Data: []byte("SELECT 1;")},
+		},
+		"bad name": {
+			"sql/init.sql": {Data: []byte("SELECT 1;")},
+		},
+		"conflicting names": {
+			"sql/0001_a.up.sql":   {Data: []byte("SELECT 1;")},
+			"sql/0001_b.down.sql": {Data: []byte("SELECT 1;")},
+		},
+		"zero version": {
+			"sql/0000_a.up.sql":   {Data: []byte("SELECT 1;")},
+			"sql/0000_a.down.sql": {Data: []byte("SELECT 1;")},
+		},
+	}
+
+	for name, fsys := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Load(fsys, "sql"); err == nil {
+				t.Error("Expected Load to fail")
+			}
+		})
+	}
+}
+
+func TestMigrator_UpDown(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+	m := New(db, testMigrations(t))
+
+	if m.Latest() != 10 {
+		t.Errorf("Expected latest version 10, got %d", m.Latest())
+	}
+
+	if err := m.Check(ctx); !errors.Is(err, ErrSchemaOutdated) {
+		t.Errorf("Expected ErrSchemaOutdated on empty database, got %v", err)
+	}
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if len(applied) != 3 {
+		t.Errorf("Expected 3 applied migrations, got %d", len(applied))
+	}
+	for _, table := range []string{"a", "b", "c", DefaultTable} {
+		if !tableExists(t, db, table) {
+			t.Errorf("Expected table %s after Up", table)
+		}
+	}
+
+	if err := m.Check(ctx); err != nil {
+		t.Errorf("Expected schema to be up to date, got %v", err)
+	}
+
+	// Повторный Up ничего не делает
+	if applied, err := m.Up(ctx); err != nil || len(applied) != 0 {
+		t.Errorf("Expected no-op Up, got %d migrations, err %v", len(applied), err)
+	}
+
+	reverted, err := m.Down(ctx, 2)
+	if err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+	if len(reverted) != 2 || reverted[0].Version != 10 || reverted[1].Version != 2 {
+		t.Errorf("Expected versions 10 and 2 reverted, got %+v", reverted)
+	}
+	if tableExists(t, db, "b") || !tableExists(t, db, "a") {
+		t.Error("Expected only table a after Down 2")
+	}
+
+	if version, err := m.Version(ctx); err != nil || version != 1 {
+		t.Errorf("Expected version 1, got %d (err %v)", version, err)
+	}
+}
+
+func TestMigrator_To(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+	m := New(db, testMigrations(t))
+
+	if _, err := m.To(ctx, 2); err != nil {
+		t.Fatalf("To(2) failed: %v", err)
+	}
+	if version, _ := m.Version(ctx); version != 2 {
+		t.Errorf("Expected version 2, got %d", version)
+	}
+
+	if _, err := m.To(ctx, 5); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("Expected ErrUnknownVersion, got %v", err)
+	}
+
+	if _, err := m.To(ctx, 0); err != nil {
+		t.Fatalf("To(0) failed: %v", err)
+	}
+	if tableExists(t, db, "a") {
+		t.Error("Expected all migrations reverted")
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	for _, status := range statuses {
+		if status.Applied {
+			t.Errorf("Expected migration %d to be reverted", status.Version)
+		}
+	}
+}
+
+func TestMigrator_Status(t *testing.T) {
+	ctx := context.Background()
+	m := New(setupTestDB(t), testMigrations(t))
+
+	if _, err := m.To(ctx, 1); err != nil {
+		t.Fatalf("To(1) failed: %v", err)
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if len(statuses) != 3 {
+		t.Fatalf("Expected 3 statuses, got %d", len(statuses))
+	}
+	if !statuses[0].Applied || statuses[0].AppliedAt.IsZero() {
+		t.Errorf("Expected migration 1 applied, got %+v", statuses[0])
+	}
+	if statuses[1].Applied || statuses[2].Applied {
+		t.Errorf("Expected migrations 2 and 10 pending, got %+v", statuses[1:])
+	}
+}
+
+func TestMigrator_FailedMigrationRollsBack(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+
+	migrations := []Migration{
+		{Version: 1, Name: "ok", Up: "CREATE TABLE ok (id INT);", Down: "DROP TABLE ok;"},
+		{Version: 2, Name: "broken", Up: "CREATE TABLE half (id INT); SELECT * FROM missing;", Down: "SELECT 1;"},
+	}
+	m := New(db, migrations)
+
+	applied, err := m.Up(ctx)
+	if err == nil {
+		t.Fatal("Expected Up to fail on broken migration")
+	}
+	if len(applied) != 1 {
+		t.Errorf("Expected only the first migration applied, got %d", len(applied))
+	}
+	if tableExists(t, db, "half") {
+		t.Error("Expected failed migration to be rolled back")
+	}
+	if version, _ := m.Version(ctx); version != 1 {
+		t.Errorf("Expected version 1 after failure, got %d", version)
+	}
+}
+
+func TestMigrator_SchemaTooNew(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+
+	if _, err := New(db, testMigrations(t)).Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	// Старая версия приложения знает только первую миграцию
+	old := New(db, testMigrations(t)[:1])
+	if err := old.Check(ctx); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("Expected ErrSchemaTooNew, got %v", err)
+	}
+}
+
+// TestMigrator_AdvisoryLock запускает миграции из нескольких горутин
+// одновременно: с блокировкой каждая версия применяется один раз.
+// Без TEST_POSTGRES_DSN тест пропускается.
+func TestMigrator_AdvisoryLock(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+
+	admin, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("Failed to connect to postgres: %v", err)
+	}
+	defer admin.Close()
+
+	schema := fmt.Sprintf("test_lock_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+	defer admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+
+	migrations := testMigrations(t)
+	errs := make(chan error, 4)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			db, err := sql.Open("pgx", dsn)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer db.Close()
+
+			// Одно соединение: миграции не должны ждать соединения пула,
+			// которое держит блокировку
+			db.SetMaxOpenConns(1)
+			if _, err := db.Exec("SET search_path TO " + schema); err != nil {
+				errs <- err
+				return
+			}
+
+			_, err = New(db, migrations).WithAdvisoryLock().Up(context.Background())
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("Concurrent Up failed: %v", err)
+		}
+	}
+
+	var applied int
+	if err := admin.QueryRow("SELECT count(*) FROM " + schema + "." + DefaultTable).Scan(&applied); err != nil {
+		t.Fatalf("Failed to count versions: %v", err)
+	}
+	if applied != len(migrations) {
+		t.Errorf("Expected %d applied versions, got %d", len(migrations), applied)
+	}
+}
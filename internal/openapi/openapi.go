@@ -0,0 +1,105 @@
+// Package openapi отдает спецификацию OpenAPI 3 сервиса и Swagger UI для
+// нее. Спецификация пишется вручную и лежит рядом в openapi.json; тесты
+// сверяют ее с маршрутами gin и моделями заказа.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+// Пути спецификации и Swagger UI
+const (
+	SpecPath    = "/openapi.json"
+	SwaggerPath = "/swagger"
+)
+
+//go:embed openapi.json
+var spec []byte
+
+// initializer заменяет swagger-initializer.js из дистрибутива Swagger UI:
+// тот открывает демонстрационную спецификацию petstore
+//
+//go:embed swagger-initializer.js
+var initializer []byte
+
+// Operation - операция спецификации: метод и путь в нотации OpenAPI
+// (/orders/{order_uid})
+type Operation struct {
+	Method     string
+	Path       string
+	Deprecated bool
+}
+
+// String возвращает операцию в виде "GET /path"
+func (o Operation) String() string {
+	return o.Method + " " + o.Path
+}
+
+// methods - методы HTTP, которые могут быть ключами Path Item в OpenAPI 3
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// Spec возвращает документ OpenAPI
+func Spec() []byte {
+	return spec
+}
+
+// Operations разбирает спецификацию и возвращает ее операции,
+// отсортированные по пути и методу
+func Operations() ([]Operation, error) {
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("parse openapi spec: %w", err)
+	}
+
+	var ops []Operation
+	for path, item := range doc.Paths {
+		for _, method := range methods {
+			raw, ok := item[method]
+			if !ok {
+				continue
+			}
+			var op struct {
+				Deprecated bool `json:"deprecated"`
+			}
+			if err := json.Unmarshal(raw, &op); err != nil {
+				return nil, fmt.Errorf("parse operation %s %s: %w", strings.ToUpper(method), path, err)
+			}
+			ops = append(ops, Operation{Method: strings.ToUpper(method), Path: path, Deprecated: op.Deprecated})
+		}
+	}
+
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].Path != ops[j].Path {
+			return ops[i].Path < ops[j].Path
+		}
+		return ops[i].Method < ops[j].Method
+	})
+	return ops, nil
+}
+
+// Register добавляет маршруты спецификации (/openapi.json) и Swagger UI
+// (/swagger/). Запрос /swagger gin перенаправляет на /swagger/.
+func Register(r gin.IRoutes) {
+	r.GET(SpecPath, func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", spec)
+	})
+
+	files := http.StripPrefix(SwaggerPath, http.FileServer(http.FS(swaggerFiles.FS)))
+	r.GET(SwaggerPath+"/*filepath", func(c *gin.Context) {
+		if c.Param("filepath") == "/swagger-initializer.js" {
+			c.Data(http.StatusOK, "text/javascript; charset=utf-8", initializer)
+			return
+		}
+		files.ServeHTTP(c.Writer, c.Request)
+	})
+}
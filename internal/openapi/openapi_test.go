@@ -0,0 +1,218 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"wb-service/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// schema - часть JSON Schema, которую проверяют тесты
+type schema struct {
+	Ref        string            `json:"$ref"`
+	Type       string            `json:"type"`
+	Properties map[string]schema `json:"properties"`
+	Required   []string          `json:"required"`
+}
+
+// document - часть документа OpenAPI, которую проверяют тесты
+type document struct {
+	OpenAPI    string `json:"openapi"`
+	Components struct {
+		Schemas map[string]schema `json:"schemas"`
+	} `json:"components"`
+}
+
+func loadDocument(t *testing.T) document {
+	t.Helper()
+
+	var doc document
+	if err := json.Unmarshal(Spec(), &doc); err != nil {
+		t.Fatalf("Failed to parse spec: %v", err)
+	}
+	return doc
+}
+
+// TestModelSchemas сверяет схемы заказа с json тегами моделей: поле,
+// добавленное в модель без описания в спецификации, ломает тест
+func TestModelSchemas(t *testing.T) {
+	doc := loadDocument(t)
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Fatalf("Expected OpenAPI 3 document, got %q", doc.OpenAPI)
+	}
+
+	types := map[string]reflect.Type{
+		"Order":    reflect.TypeOf(models.Order{}),
+		"Delivery": reflect.TypeOf(models.Delivery{}),
+		"Payment":  reflect.TypeOf(models.Payment{}),
+		"Item":     reflect.TypeOf(models.Item{}),
+	}
+
+	for name, typ := range types {
+		t.Run(name, func(t *testing.T) {
+			s, ok := doc.Components.Schemas[name]
+			if !ok {
+				t.Fatalf("Schema %s is missing", name)
+			}
+
+			fields := make(map[string]bool)
+			for i := 0; i < typ.NumField(); i++ {
+				field := typ.Field(i)
+				tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+				if tag == "" || tag == "-" {
+					continue
+				}
+				fields[tag] = true
+
+				prop, ok := s.Properties[tag]
+				if !ok {
+					t.Errorf("Field %s.%s (%s) is missing from the schema", name, field.Name, tag)
+					continue
+				}
+				if want := schemaType(field.Type); !matchesType(prop, want) {
+					t.Errorf("Property %s.%s: expected %s, got %+v", name, tag, want, prop)
+				}
+			}
+
+			for prop := range s.Properties {
+				if !fields[prop] {
+					t.Errorf("Schema %s describes %s, but the model has no such field", name, prop)
+				}
+			}
+			for _, prop := range s.Required {
+				if !fields[prop] {
+					t.Errorf("Schema %s requires unknown property %s", name, prop)
+				}
+			}
+		})
+	}
+}
+
+// schemaType возвращает тип JSON Schema для поля модели
+func schemaType(typ reflect.Type) string {
+	if typ == reflect.TypeOf(time.Time{}) {
+		return "string"
+	}
+	switch typ.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Int, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice:
+		return "array"
+	case reflect.Struct:
+		return "#/components/schemas/" + typ.Name()
+	}
+	return typ.Kind().String()
+}
+
+func matchesType(prop schema, want string) bool {
+	if strings.HasPrefix(want, "#/") {
+		return prop.Ref == want
+	}
+	return prop.Type == want
+}
+
+func TestOperations(t *testing.T) {
+	ops, err := Operations()
+	if err != nil {
+		t.Fatalf("Operations failed: %v", err)
+	}
+	if len(ops) == 0 {
+		t.Fatal("Expected operations in the spec")
+	}
+
+	seen := make(map[string]bool)
+	for _, op := range ops {
+		seen[op.String()] = true
+
+		// Маршруты вне /api/v1 - устаревшие псевдонимы, кроме служебных
+		public := op.Path == "/health" || op.Path == SpecPath
+		if !strings.HasPrefix(op.Path, "/api/v1/") && !public && !op.Deprecated {
+			t.Errorf("Expected %s to be deprecated", op)
+		}
+		if strings.HasPrefix(op.Path, "/api/v1/") && op.Deprecated {
+			t.Errorf("Expected %s not to be deprecated", op)
+		}
+	}
+
+	for _, want := range []string{"GET /api/v1/orders/{order_uid}", "GET /order/{order_uid}", "GET " + SpecPath} {
+		if !seen[want] {
+			t.Errorf("Expected operation %s", want)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	r := gin.New()
+	Register(r)
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	t.Run("spec", func(t *testing.T) {
+		w := serve(SpecPath)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+		if !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+			t.Errorf("Unexpected content type %q", w.Header().Get("Content-Type"))
+		}
+		if !json.Valid(w.Body.Bytes()) {
+			t.Error("Expected valid JSON")
+		}
+	})
+
+	t.Run("swagger ui", func(t *testing.T) {
+		w := serve(SwaggerPath + "/")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+		if !strings.Contains(w.Body.String(), "swagger-ui") {
+			t.Error("Expected Swagger UI page")
+		}
+	})
+
+	t.Run("initializer", func(t *testing.T) {
+		w := serve(SwaggerPath + "/swagger-initializer.js")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+		if !strings.Contains(w.Body.String(), `url: "`+SpecPath+`"`) {
+			t.Errorf("Expected initializer to load %s, got %s", SpecPath, w.Body.String())
+		}
+	})
+
+	t.Run("assets", func(t *testing.T) {
+		w := serve(SwaggerPath + "/swagger-ui-bundle.js")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+	})
+
+	t.Run("redirect", func(t *testing.T) {
+		w := serve(SwaggerPath)
+		if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != SwaggerPath+"/" {
+			t.Errorf("Expected redirect to %s/, got %d %q", SwaggerPath, w.Code, w.Header().Get("Location"))
+		}
+	})
+}
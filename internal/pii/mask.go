@@ -0,0 +1,126 @@
+// Package pii скрывает персональные данные заказов: маскирует их в ответах
+// API по политике роли или API ключа и вычищает из сообщений в логах
+package pii
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+	"wb-service/models"
+)
+
+// Policy - политика маскирования персональных данных в ответах API
+type Policy string
+
+const (
+	// PolicyPlain отдает данные без изменений
+	PolicyPlain Policy = "plain"
+	// PolicyPartial оставляет часть символов, по которым данные можно узнать,
+	// но не восстановить: первые буквы имени, последние цифры телефона
+	PolicyPartial Policy = "partial"
+	// PolicyRedacted полностью скрывает данные
+	PolicyRedacted Policy = "redacted"
+)
+
+// hidden заменяет скрытые символы
+const hidden = "***"
+
+// ParsePolicy разбирает название политики. Пустая строка - PolicyPartial.
+func ParsePolicy(s string) (Policy, error) {
+	switch p := Policy(strings.ToLower(strings.TrimSpace(s))); p {
+	case PolicyPlain, PolicyPartial, PolicyRedacted:
+		return p, nil
+	case "":
+		return PolicyPartial, nil
+	default:
+		return PolicyRedacted, fmt.Errorf("unknown PII policy %q", s)
+	}
+}
+
+// MaskOrder возвращает заказ с данными доставки и оплаты, замаскированными
+// по политике. Исходный заказ не изменяется: для PolicyPlain возвращается
+// он сам, иначе - замаскированная копия.
+func MaskOrder(order *models.Order, p Policy) *models.Order {
+	if order == nil || p == PolicyPlain {
+		return order
+	}
+
+	masked := order.Clone()
+	masked.Delivery = MaskDelivery(order.Delivery, p)
+	masked.Payment = MaskPayment(order.Payment, p)
+	return masked
+}
+
+// MaskDelivery маскирует имя, телефон, email и адрес получателя.
+// Город, регион и индекс не скрываются.
+func MaskDelivery(d models.Delivery, p Policy) models.Delivery {
+	switch p {
+	case PolicyPlain:
+		return d
+	case PolicyPartial:
+		d.Name = maskName(d.Name)
+		d.Phone = maskTail(d.Phone, 2)
+		d.Email = maskEmail(d.Email)
+		d.Address = redact(d.Address)
+	default:
+		d.Name = redact(d.Name)
+		d.Phone = redact(d.Phone)
+		d.Email = redact(d.Email)
+		d.Address = redact(d.Address)
+	}
+	return d
+}
+
+// MaskPayment маскирует идентификаторы транзакции и запроса.
+// Суммы, валюта и банк не скрываются.
+func MaskPayment(pm models.Payment, p Policy) models.Payment {
+	switch p {
+	case PolicyPlain:
+		return pm
+	case PolicyPartial:
+		pm.Transaction = maskTail(pm.Transaction, 4)
+		pm.RequestID = maskTail(pm.RequestID, 4)
+	default:
+		pm.Transaction = redact(pm.Transaction)
+		pm.RequestID = redact(pm.RequestID)
+	}
+	return pm
+}
+
+// redact скрывает непустое значение целиком
+func redact(s string) string {
+	if s == "" {
+		return ""
+	}
+	return hidden
+}
+
+// maskName оставляет первую букву каждого слова: "John Doe" -> "J*** D***"
+func maskName(s string) string {
+	words := strings.Fields(s)
+	for i, word := range words {
+		r, _ := utf8.DecodeRuneInString(word)
+		words[i] = string(r) + hidden
+	}
+	return strings.Join(words, " ")
+}
+
+// maskTail оставляет последние n символов, если значение достаточно
+// длинное, чтобы они не раскрывали его: "+79991234567" -> "***67"
+func maskTail(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= 2*n {
+		return redact(s)
+	}
+	return hidden + string(runes[len(runes)-n:])
+}
+
+// maskEmail оставляет первую букву имени и домен: "john@example.com" -> "j***@example.com"
+func maskEmail(s string) string {
+	local, domain, ok := strings.Cut(s, "@")
+	if !ok || local == "" {
+		return redact(s)
+	}
+	r, _ := utf8.DecodeRuneInString(local)
+	return string(r) + hidden + "@" + domain
+}
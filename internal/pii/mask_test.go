@@ -0,0 +1,151 @@
+package pii
+
+import (
+	"testing"
+	"wb-service/config"
+	"wb-service/models"
+)
+
+func testOrder() *models.Order {
+	return &models.Order{
+		OrderUID: "order_1",
+		Delivery: models.Delivery{
+			Name:    "Иван Петров",
+			Phone:   "+79991234567",
+			Zip:     "123456",
+			City:    "Москва",
+			Address: "ул. Ленина, 1",
+			Email:   "ivan@example.com",
+		},
+		Payment: models.Payment{
+			Transaction: "b563feb7b2b84b6test",
+			RequestID:   "",
+			Amount:      1817,
+			Bank:        "alpha",
+		},
+		Items: []models.Item{{Name: "Mascaras"}},
+	}
+}
+
+func TestMaskOrder(t *testing.T) {
+	tests := []struct {
+		policy   Policy
+		delivery models.Delivery
+		payment  string
+	}{
+		{PolicyPlain, testOrder().Delivery, "b563feb7b2b84b6test"},
+		{PolicyPartial, models.Delivery{
+			Name: "И*** П***", Phone: "***67", Zip: "123456", City: "Москва",
+			Address: "***", Email: "i***@example.com",
+		}, "***test"},
+		{PolicyRedacted, models.Delivery{
+			Name: "***", Phone: "***", Zip: "123456", City: "Москва",
+			Address: "***", Email: "***",
+		}, "***"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			order := testOrder()
+			masked := MaskOrder(order, tt.policy)
+
+			if masked.Delivery != tt.delivery {
+				t.Errorf("Expected delivery %+v, got %+v", tt.delivery, masked.Delivery)
+			}
+			if masked.Payment.Transaction != tt.payment || masked.Payment.RequestID != "" {
+				t.Errorf("Unexpected payment identifiers: %+v", masked.Payment)
+			}
+			if masked.Payment.Amount != 1817 || masked.Payment.Bank != "alpha" || len(masked.Items) != 1 {
+				t.Errorf("Expected non-personal fields to stay, got %+v", masked)
+			}
+
+			// Исходный заказ (например, из кэша) не изменяется
+			if order.Delivery != testOrder().Delivery || order.Payment != testOrder().Payment {
+				t.Errorf("Source order was modified: %+v", order)
+			}
+		})
+	}
+
+	if MaskOrder(nil, PolicyRedacted) != nil {
+		t.Error("Expected nil for nil order")
+	}
+}
+
+func TestMaskShortValues(t *testing.T) {
+	// Короткие значения скрываются целиком, иначе видимая часть раскрыла бы их
+	if got := maskTail("+712", 2); got != "***" {
+		t.Errorf("Expected short phone to be redacted, got %q", got)
+	}
+	if got := maskEmail("not-an-email"); got != "***" {
+		t.Errorf("Expected invalid email to be redacted, got %q", got)
+	}
+	if got := maskName(""); got != "" {
+		t.Errorf("Expected empty name to stay empty, got %q", got)
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected Policy
+		wantErr  bool
+	}{
+		{"plain", PolicyPlain, false},
+		{" Partial ", PolicyPartial, false},
+		{"redacted", PolicyRedacted, false},
+		{"", PolicyPartial, false},
+		{"none", PolicyRedacted, true},
+	}
+
+	for _, tt := range tests {
+		policy, err := ParsePolicy(tt.value)
+		if policy != tt.expected || (err != nil) != tt.wantErr {
+			t.Errorf("ParsePolicy(%q) = %q, %v", tt.value, policy, err)
+		}
+	}
+}
+
+func TestPolicies(t *testing.T) {
+	policies, err := NewPolicies(config.PIIConfig{
+		DefaultPolicy: "redacted",
+		RolePolicies:  []string{"admin=plain", "support=partial"},
+		KeyPolicies:   []string{"partner-key=partial", "ops-key = plain"},
+	})
+	if err != nil {
+		t.Fatalf("NewPolicies failed: %v", err)
+	}
+
+	tests := []struct {
+		role, key string
+		expected  Policy
+	}{
+		{"", "", PolicyRedacted},
+		{"admin", "", PolicyPlain},
+		{"support", "", PolicyPartial},
+		{"unknown", "", PolicyRedacted},
+		{"", "partner-key", PolicyPartial},
+		{"", "ops-key", PolicyPlain},
+		// Политика ключа важнее политики роли
+		{"admin", "partner-key", PolicyPartial},
+		{"support", "unknown-key", PolicyPartial},
+	}
+
+	for _, tt := range tests {
+		if got := policies.For(tt.role, tt.key); got != tt.expected {
+			t.Errorf("For(%q, %q) = %q, expected %q", tt.role, tt.key, got, tt.expected)
+		}
+	}
+}
+
+func TestNewPoliciesInvalid(t *testing.T) {
+	for _, cfg := range []config.PIIConfig{
+		{DefaultPolicy: "open"},
+		{RolePolicies: []string{"admin"}},
+		{RolePolicies: []string{"=plain"}},
+		{KeyPolicies: []string{"secret-key=everything"}},
+	} {
+		if _, err := NewPolicies(cfg); err == nil {
+			t.Errorf("Expected error for %+v", cfg)
+		}
+	}
+}
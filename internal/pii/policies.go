@@ -0,0 +1,68 @@
+package pii
+
+import (
+	"fmt"
+	"strings"
+	"wb-service/config"
+)
+
+// Policies выбирает политику маскирования для запроса: сначала по API
+// ключу, затем по роли, а если ни то ни другое не задано - Default
+type Policies struct {
+	Default Policy
+	Roles   map[string]Policy
+	Keys    map[string]Policy
+}
+
+// NewPolicies создает политики из конфигурации. Роли и ключи задаются
+// парами "имя=политика"; некорректная пара или неизвестная политика - ошибка,
+// чтобы опечатка в конфигурации не открыла данные.
+func NewPolicies(cfg config.PIIConfig) (*Policies, error) {
+	def, err := ParsePolicy(cfg.DefaultPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := parsePairs(cfg.RolePolicies)
+	if err != nil {
+		return nil, fmt.Errorf("role policies: %w", err)
+	}
+	keys, err := parsePairs(cfg.KeyPolicies)
+	if err != nil {
+		return nil, fmt.Errorf("key policies: %w", err)
+	}
+
+	return &Policies{Default: def, Roles: roles, Keys: keys}, nil
+}
+
+// For возвращает политику для роли и API ключа. Пустые значения
+// означают, что роль или ключ неизвестны.
+func (p *Policies) For(role, apiKey string) Policy {
+	if policy, ok := p.Keys[apiKey]; ok && apiKey != "" {
+		return policy
+	}
+	if policy, ok := p.Roles[role]; ok && role != "" {
+		return policy
+	}
+	return p.Default
+}
+
+// parsePairs разбирает пары "имя=политика". Сама пара в ошибку не попадает:
+// в ней может быть API ключ.
+func parsePairs(pairs []string) (map[string]Policy, error) {
+	policies := make(map[string]Policy, len(pairs))
+	for i, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.TrimSpace(value) == "" {
+			return nil, fmt.Errorf("pair %d: expected name=policy", i+1)
+		}
+
+		policy, err := ParsePolicy(value)
+		if err != nil {
+			return nil, fmt.Errorf("pair %d: %w", i+1, err)
+		}
+		policies[name] = policy
+	}
+	return policies, nil
+}
@@ -0,0 +1,21 @@
+package pii
+
+import "regexp"
+
+var (
+	// personalField находит строковые значения полей с персональными данными в JSON
+	personalField = regexp.MustCompile(`(?i)("(?:name|phone|email|address|customer_id|transaction|request_id)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	// email и phone находят адреса и телефоны в любом тексте. Телефон
+	// должен начинаться с "+", чтобы не скрывать суммы и временные метки.
+	email = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	phone = regexp.MustCompile(`\+\d[\d\s\-()]{6,}\d`)
+)
+
+// Sanitize убирает из текста для лога персональные данные: значения полей
+// name, phone, email, address, customer_id, transaction и request_id
+// в JSON, а также email и телефоны в любом месте текста
+func Sanitize(s string) string {
+	s = personalField.ReplaceAllString(s, `${1}"`+hidden+`"`)
+	s = email.ReplaceAllString(s, hidden)
+	return phone.ReplaceAllString(s, hidden)
+}
@@ -0,0 +1,45 @@
+package pii
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	message := `{"order_uid":"b563feb7b2b84b6test","customer_id":"test","delivery":{"name":"Test \"Testov\"",` +
+		`"phone":"+9720000000","zip":"2639809","address":"Ploshad Mira 15","email":"test@gmail.com"},` +
+		`"payment":{"transaction":"b563feb7b2b84b6test","amount":1817,"payment_dt":1637907727}}`
+
+	sanitized := Sanitize(message)
+
+	for _, secret := range []string{"Testov", "+9720000000", "Ploshad Mira", "test@gmail.com", `"customer_id":"test"`, `"transaction":"b563`} {
+		if strings.Contains(sanitized, secret) {
+			t.Errorf("Expected %q to be removed from %s", secret, sanitized)
+		}
+	}
+
+	// Остальные данные нужны для разбора ошибки
+	for _, kept := range []string{`"order_uid":"b563feb7b2b84b6test"`, `"zip":"2639809"`, `"amount":1817`, `"payment_dt":1637907727`} {
+		if !strings.Contains(sanitized, kept) {
+			t.Errorf("Expected %q to stay in %s", kept, sanitized)
+		}
+	}
+}
+
+func TestSanitizeText(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"invalid email john.doe@example.com", "invalid email ***"},
+		{"phone +7 (999) 123-45-67 is invalid", "phone *** is invalid"},
+		{"order 123456789 failed", "order 123456789 failed"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Sanitize(tt.input); got != tt.expected {
+			t.Errorf("Sanitize(%q) = %q, expected %q", tt.input, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Package ratelimit ограничивает частоту запросов к HTTP API по алгоритму
+// token bucket: в памяти процесса или в Redis, общем для всех реплик
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+	"wb-service/internal/interfaces"
+)
+
+// cleanupInterval - период удаления простаивающих корзин
+const cleanupInterval = time.Minute
+
+// bucket - корзина токенов одного клиента
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Duration // за сколько пустая корзина наполняется целиком
+}
+
+// MemoryLimiter хранит корзины в памяти процесса. У каждой реплики сервиса
+// свои корзины, поэтому общий лимит клиента растет с числом реплик.
+type MemoryLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewMemoryLimiter создает ограничитель и запускает фоновое удаление
+// простаивающих корзин, которое останавливается отменой ctx или Close
+func NewMemoryLimiter(ctx context.Context) *MemoryLimiter {
+	ctx, cancel := context.WithCancel(ctx)
+	l := &MemoryLimiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+
+	go func() {
+		defer close(l.done)
+
+		ticker := time.NewTicker(cleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				l.cleanup()
+			}
+		}
+	}()
+
+	return l
+}
+
+// Allow забирает токен из корзины key
+func (l *MemoryLimiter) Allow(_ context.Context, key string, limit interfaces.RateLimit) (interfaces.RateLimitResult, error) {
+	now := l.now()
+	burst := float64(limit.Burst)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst}
+		l.buckets[key] = b
+	} else if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens = math.Min(burst, b.tokens+elapsed.Seconds()*limit.Rate)
+	}
+	b.updated = now
+	b.full = time.Duration(burst / limit.Rate * float64(time.Second))
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+		return interfaces.RateLimitResult{RetryAfter: wait}, nil
+	}
+
+	b.tokens--
+	return interfaces.RateLimitResult{Allowed: true, Remaining: int(b.tokens)}, nil
+}
+
+// cleanup удаляет корзины, которые успели наполниться: новая корзина
+// создается полной, так что результат Allow не меняется
+func (l *MemoryLimiter) cleanup() {
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, b := range l.buckets {
+		if now.Sub(b.updated) >= b.full {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Len возвращает количество корзин в памяти
+func (l *MemoryLimiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
+
+// Close останавливает фоновое удаление корзин
+func (l *MemoryLimiter) Close() error {
+	l.cancel()
+	<-l.done
+	return nil
+}
@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wb-service/internal/interfaces"
+)
+
+// newTestLimiter создает ограничитель в памяти с управляемыми часами
+func newTestLimiter(t *testing.T) (*MemoryLimiter, *time.Time) {
+	t.Helper()
+
+	l := NewMemoryLimiter(context.Background())
+	t.Cleanup(func() { l.Close() })
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestMemoryLimiter_Implements(t *testing.T) {
+	var _ interfaces.RateLimiter = (*MemoryLimiter)(nil)
+	var _ interfaces.RateLimiter = (*RedisLimiter)(nil)
+}
+
+func TestMemoryLimiter_Allow(t *testing.T) {
+	l, now := newTestLimiter(t)
+	limit := interfaces.RateLimit{Rate: 2, Burst: 3}
+	ctx := context.Background()
+
+	// Новая корзина полная: три запроса подряд проходят
+	for i := 0; i < 3; i++ {
+		result, _ := l.Allow(ctx, "client", limit)
+		if !result.Allowed || result.Remaining != 2-i {
+			t.Fatalf("Request %d: expected allowed with %d remaining, got %+v", i+1, 2-i, result)
+		}
+	}
+
+	result, _ := l.Allow(ctx, "client", limit)
+	if result.Allowed || result.RetryAfter != 500*time.Millisecond {
+		t.Errorf("Expected rejection with 500ms retry, got %+v", result)
+	}
+
+	// Другой клиент не зависит от первого
+	if result, _ := l.Allow(ctx, "other", limit); !result.Allowed {
+		t.Error("Expected other client to be allowed")
+	}
+
+	// За полсекунды появляется один токен
+	*now = now.Add(500 * time.Millisecond)
+	if result, _ := l.Allow(ctx, "client", limit); !result.Allowed {
+		t.Error("Expected request to be allowed after refill")
+	}
+	if result, _ := l.Allow(ctx, "client", limit); result.Allowed {
+		t.Error("Expected bucket to be empty again")
+	}
+
+	// Корзина не наполняется сверх Burst
+	*now = now.Add(time.Hour)
+	if result, _ := l.Allow(ctx, "client", limit); result.Remaining != 2 {
+		t.Errorf("Expected refill capped at burst, got %+v", result)
+	}
+}
+
+func TestMemoryLimiter_Cleanup(t *testing.T) {
+	l, now := newTestLimiter(t)
+	ctx := context.Background()
+
+	l.Allow(ctx, "slow", interfaces.RateLimit{Rate: 1, Burst: 10})
+	l.Allow(ctx, "fast", interfaces.RateLimit{Rate: 10, Burst: 10})
+
+	// Через 2 секунды наполнилась только корзина с быстрым пополнением
+	*now = now.Add(2 * time.Second)
+	l.cleanup()
+
+	if l.Len() != 1 {
+		t.Fatalf("Expected 1 bucket after cleanup, got %d", l.Len())
+	}
+	if result, _ := l.Allow(ctx, "slow", interfaces.RateLimit{Rate: 1, Burst: 10}); result.Remaining != 9 {
+		t.Errorf("Expected slow bucket to keep its state, got %+v", result)
+	}
+}
@@ -0,0 +1,149 @@
+package ratelimit
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"wb-service/internal/apierror"
+	"wb-service/internal/auth"
+	"wb-service/internal/interfaces"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Группы маршрутов с собственными лимитами
+const (
+	GroupOrders = "orders" // чтение заказов
+	GroupAdmin  = "admin"  // служебные маршруты, удаление и обезличивание
+	GroupAuth   = "auth"   // все запросы к API с одного IP до проверки учетных данных
+)
+
+// Stats - статистика ограничения группы маршрутов
+type Stats struct {
+	Rate    float64 `json:"rate"`  // токенов в секунду
+	Burst   int     `json:"burst"` // емкость корзины
+	Allowed uint64  `json:"allowed"`
+	Limited uint64  `json:"limited"` // запросов, получивших 429
+	Errors  uint64  `json:"errors"`  // ошибок хранилища; такие запросы пропускаются
+}
+
+// group - лимит и счетчики группы маршрутов
+type group struct {
+	limit interfaces.RateLimit
+
+	allowed atomic.Uint64
+	limited atomic.Uint64
+	errors  atomic.Uint64
+}
+
+// Middleware ограничивает запросы к группам маршрутов. Корзина выбирается
+// по клиенту, которого определила аутентификация (API ключ или subject
+// JWT), а для анонимных запросов - по IP адресу.
+type Middleware struct {
+	limiter interfaces.RateLimiter
+	backend string
+
+	mu     sync.Mutex
+	groups map[string]*group
+}
+
+// NewMiddleware создает middleware поверх ограничителя. backend - название
+// хранилища корзин для статистики. Middleware владеет ограничителем и
+// закрывает его в Close.
+func NewMiddleware(limiter interfaces.RateLimiter, backend string) *Middleware {
+	return &Middleware{
+		limiter: limiter,
+		backend: backend,
+		groups:  make(map[string]*group),
+	}
+}
+
+// Limit возвращает middleware группы name. Маршруты одной группы делят
+// корзину клиента; лимит задается при первом вызове для группы. Лимит с
+// нулевой скоростью не ограничивает запросы.
+func (m *Middleware) Limit(name string, limit interfaces.RateLimit) gin.HandlerFunc {
+	if limit.Rate <= 0 || limit.Burst <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	m.mu.Lock()
+	g, ok := m.groups[name]
+	if !ok {
+		g = &group{limit: limit}
+		m.groups[name] = g
+	}
+	m.mu.Unlock()
+
+	return func(c *gin.Context) {
+		result, err := m.limiter.Allow(c.Request.Context(), name+":"+clientKey(c), g.limit)
+		if err != nil {
+			// Недоступное хранилище корзин не должно останавливать API
+			g.errors.Add(1)
+			log.Printf("Ошибка ограничителя запросов: %v", err)
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(g.limit.Burst))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+
+		if !result.Allowed {
+			g.limited.Add(1)
+			retryAfter := retryAfterSeconds(result)
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			apierror.AbortWithDetails(c, http.StatusTooManyRequests, apierror.CodeRateLimited, "rate limit exceeded",
+				gin.H{"group": name, "retry_after": retryAfter})
+			return
+		}
+
+		g.allowed.Add(1)
+		c.Next()
+	}
+}
+
+// Stats возвращает статистику по группам
+func (m *Middleware) Stats() map[string]Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := make(map[string]Stats, len(m.groups))
+	for name, g := range m.groups {
+		stats[name] = Stats{
+			Rate:    g.limit.Rate,
+			Burst:   g.limit.Burst,
+			Allowed: g.allowed.Load(),
+			Limited: g.limited.Load(),
+			Errors:  g.errors.Load(),
+		}
+	}
+	return stats
+}
+
+// Backend возвращает название хранилища корзин
+func (m *Middleware) Backend() string {
+	return m.backend
+}
+
+// Close закрывает ограничитель
+func (m *Middleware) Close() error {
+	return m.limiter.Close()
+}
+
+// clientKey возвращает ключ корзины клиента. Заголовок X-API-Key сам по
+// себе не используется: иначе случайный ключ в каждом запросе давал бы
+// новую корзину.
+func clientKey(c *gin.Context) string {
+	if subject := c.GetString(auth.SubjectContextKey); subject != "" && subject != auth.AnonymousSubject {
+		return subject
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// retryAfterSeconds округляет ожидание вверх до целых секунд, как того
+// требует заголовок Retry-After
+func retryAfterSeconds(result interfaces.RateLimitResult) int {
+	return max(1, int(math.Ceil(result.RetryAfter.Seconds())))
+}
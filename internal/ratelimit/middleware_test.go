@@ -0,0 +1,127 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"wb-service/internal/auth"
+	"wb-service/internal/interfaces"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// failingLimiter имитирует недоступное хранилище корзин
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(context.Context, string, interfaces.RateLimit) (interfaces.RateLimitResult, error) {
+	return interfaces.RateLimitResult{}, errors.New("connection refused")
+}
+
+func (failingLimiter) Close() error { return nil }
+
+// request выполняет запрос от клиента с адресом addr и, если задан,
+// проверенным subject
+func request(r *gin.Engine, path, addr, subject string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	req.RemoteAddr = addr + ":1234"
+	if subject != "" {
+		req.Header.Set("X-Subject", subject)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func newTestRouter(m *Middleware, limit interfaces.RateLimit) *gin.Engine {
+	r := gin.New()
+	// Вместо аутентификации subject берется из заголовка
+	r.Use(func(c *gin.Context) {
+		if subject := c.GetHeader("X-Subject"); subject != "" {
+			c.Set(auth.SubjectContextKey, subject)
+		}
+	})
+	r.GET("/orders", m.Limit(GroupOrders, limit), func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/admin", m.Limit(GroupAdmin, limit), func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+func TestMiddleware_Limit(t *testing.T) {
+	l, _ := newTestLimiter(t)
+	m := NewMiddleware(l, "memory")
+	r := newTestRouter(m, interfaces.RateLimit{Rate: 0.5, Burst: 2})
+
+	for i := 0; i < 2; i++ {
+		if w := request(r, "/orders", "10.0.0.1", ""); w.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected 200, got %d", i+1, w.Code)
+		}
+	}
+
+	w := request(r, "/orders", "10.0.0.1", "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Expected Retry-After 2, got %q", got)
+	}
+	if w.Header().Get("X-RateLimit-Limit") != "2" || w.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("Unexpected rate limit headers: %v", w.Header())
+	}
+
+	// Корзины раздельные для групп, адресов и проверенных клиентов
+	if w := request(r, "/admin", "10.0.0.1", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected other group to be allowed, got %d", w.Code)
+	}
+	if w := request(r, "/orders", "10.0.0.2", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected other address to be allowed, got %d", w.Code)
+	}
+	if w := request(r, "/orders", "10.0.0.1", "api-key:0001"); w.Code != http.StatusOK {
+		t.Errorf("Expected authenticated client to be allowed, got %d", w.Code)
+	}
+	// Анонимный клиент ограничивается по адресу
+	if w := request(r, "/orders", "10.0.0.1", auth.AnonymousSubject); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected anonymous client to share the address bucket, got %d", w.Code)
+	}
+
+	stats := m.Stats()[GroupOrders]
+	if stats.Allowed != 4 || stats.Limited != 2 || stats.Burst != 2 || stats.Rate != 0.5 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestMiddleware_Disabled(t *testing.T) {
+	l, _ := newTestLimiter(t)
+	m := NewMiddleware(l, "memory")
+	r := newTestRouter(m, interfaces.RateLimit{})
+
+	for i := 0; i < 10; i++ {
+		if w := request(r, "/orders", "10.0.0.1", ""); w.Code != http.StatusOK {
+			t.Fatalf("Expected zero limit to allow all requests, got %d", w.Code)
+		}
+	}
+	if len(m.Stats()) != 0 {
+		t.Errorf("Expected no groups for zero limit, got %v", m.Stats())
+	}
+}
+
+func TestMiddleware_LimiterError(t *testing.T) {
+	m := NewMiddleware(failingLimiter{}, "redis")
+	r := newTestRouter(m, interfaces.RateLimit{Rate: 1, Burst: 1})
+
+	// Недоступное хранилище не блокирует API
+	for i := 0; i < 3; i++ {
+		if w := request(r, "/orders", "10.0.0.1", ""); w.Code != http.StatusOK {
+			t.Fatalf("Expected request to pass on limiter error, got %d", w.Code)
+		}
+	}
+	if stats := m.Stats()[GroupOrders]; stats.Errors != 3 || stats.Allowed != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"wb-service/internal/interfaces"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// allowScript атомарно пополняет корзину и забирает из нее токен. Время
+// берется из Redis (TIME), чтобы расхождение часов реплик сервиса не влияло
+// на лимит. Корзина хранится в хэше (tokens, ts в мс) и удаляется, когда
+// успевает наполниться. Возвращает {разрешено, осталось токенов, ждать мс}.
+var allowScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local clock = redis.call('TIME')
+local now = tonumber(clock[1]) * 1000 + math.floor(tonumber(clock[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+
+local elapsed = math.max(0, now - ts)
+tokens = math.min(burst, tokens + elapsed * rate / 1000)
+
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) * 1000 / rate)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+return {allowed, math.floor(tokens), wait}
+`)
+
+// RedisOptions задает параметры ограничителя в Redis
+type RedisOptions struct {
+	Prefix  string        // префикс ключей корзин, по умолчанию "ratelimit:"
+	Timeout time.Duration // таймаут одной операции с Redis, по умолчанию 100мс
+}
+
+// RedisLimiter хранит корзины в Redis, поэтому лимит клиента общий для
+// всех реплик сервиса
+type RedisLimiter struct {
+	client  redis.UniversalClient
+	prefix  string
+	timeout time.Duration
+}
+
+// NewRedisLimiter создает ограничитель поверх клиента Redis. Ограничитель
+// владеет клиентом и закрывает его в Close.
+func NewRedisLimiter(client redis.UniversalClient, opts RedisOptions) *RedisLimiter {
+	if opts.Prefix == "" {
+		opts.Prefix = "ratelimit:"
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 100 * time.Millisecond
+	}
+	return &RedisLimiter{client: client, prefix: opts.Prefix, timeout: opts.Timeout}
+}
+
+// Allow забирает токен из корзины key
+func (l *RedisLimiter) Allow(ctx context.Context, key string, limit interfaces.RateLimit) (interfaces.RateLimitResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+
+	values, err := allowScript.Run(ctx, l.client, []string{l.prefix + key}, limit.Rate, limit.Burst).Int64Slice()
+	if err != nil {
+		return interfaces.RateLimitResult{}, err
+	}
+	if len(values) != 3 {
+		return interfaces.RateLimitResult{}, fmt.Errorf("unexpected rate limit script result %v", values)
+	}
+
+	return interfaces.RateLimitResult{
+		Allowed:    values[0] == 1,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+	}, nil
+}
+
+// Close закрывает клиент Redis
+func (l *RedisLimiter) Close() error {
+	return l.client.Close()
+}
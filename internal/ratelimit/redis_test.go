@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wb-service/internal/interfaces"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func setupRedisLimiter(t *testing.T) (*RedisLimiter, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	l := NewRedisLimiter(redis.NewClient(&redis.Options{Addr: server.Addr()}), RedisOptions{})
+	t.Cleanup(func() { l.Close() })
+
+	return l, server
+}
+
+func TestRedisLimiter_Allow(t *testing.T) {
+	l, server := setupRedisLimiter(t)
+	limit := interfaces.RateLimit{Rate: 2, Burst: 2}
+	ctx := context.Background()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	server.SetTime(now)
+
+	for i := 0; i < 2; i++ {
+		result, err := l.Allow(ctx, "client", limit)
+		if err != nil {
+			t.Fatalf("Allow failed: %v", err)
+		}
+		if !result.Allowed || result.Remaining != 1-i {
+			t.Fatalf("Request %d: expected allowed with %d remaining, got %+v", i+1, 1-i, result)
+		}
+	}
+
+	result, err := l.Allow(ctx, "client", limit)
+	if err != nil {
+		t.Fatalf("Allow failed: %v", err)
+	}
+	if result.Allowed || result.RetryAfter != 500*time.Millisecond {
+		t.Errorf("Expected rejection with 500ms retry, got %+v", result)
+	}
+
+	if !server.Exists("ratelimit:client") {
+		t.Error("Expected bucket with default prefix in Redis")
+	}
+	if ttl := server.TTL("ratelimit:client"); ttl <= 0 || ttl > 3*time.Second {
+		t.Errorf("Expected bucket to expire once refilled, got TTL %v", ttl)
+	}
+
+	// Время берется из Redis: после пополнения токен снова есть
+	server.SetTime(now.Add(500 * time.Millisecond))
+	if result, _ := l.Allow(ctx, "client", limit); !result.Allowed {
+		t.Errorf("Expected request to be allowed after refill, got %+v", result)
+	}
+}
+
+func TestRedisLimiter_Unavailable(t *testing.T) {
+	l, server := setupRedisLimiter(t)
+	server.Close()
+
+	if _, err := l.Allow(context.Background(), "client", interfaces.RateLimit{Rate: 1, Burst: 1}); err == nil {
+		t.Error("Expected error when Redis is unavailable")
+	}
+}
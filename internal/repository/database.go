@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"context"
+	"time"
 	"wb-service/internal/interfaces"
 	"wb-service/models"
 
@@ -50,6 +52,165 @@ func (g *GormDatabase) GetAllOrders() ([]models.Order, error) {
 	return orders, err
 }
 
+// GetRecentOrders получает страницу заказов после курсора, от новых к
+// старым. order_uid добавлен в сортировку, чтобы страницы не пересекались
+// при одинаковом date_created.
+func (g *GormDatabase) GetRecentOrders(after interfaces.OrderCursor, limit int) ([]models.Order, error) {
+	query := g.db.
+		Preload("Delivery").
+		Preload("Payment").
+		Preload("Items")
+	if after.OrderUID != "" {
+		query = query.Where("date_created < ? OR (date_created = ? AND order_uid > ?)",
+			after.DateCreated, after.DateCreated, after.OrderUID)
+	}
+
+	var orders []models.Order
+	err := query.
+		Order("date_created DESC, order_uid").
+		Limit(limit).
+		Find(&orders).Error
+
+	return orders, err
+}
+
+// GetOrdersByUIDs получает заказы по списку UID. Отсутствующие в базе
+// UID пропускаются, порядок результата совпадает с порядком orderUIDs.
+func (g *GormDatabase) GetOrdersByUIDs(orderUIDs []string) ([]models.Order, error) {
+	if len(orderUIDs) == 0 {
+		return nil, nil
+	}
+
+	var found []models.Order
+	err := g.db.
+		Preload("Delivery").
+		Preload("Payment").
+		Preload("Items").
+		Where("order_uid IN ?", orderUIDs).
+		Find(&found).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byUID := make(map[string]models.Order, len(found))
+	for _, order := range found {
+		byUID[order.OrderUID] = order
+	}
+
+	orders := make([]models.Order, 0, len(found))
+	for _, uid := range orderUIDs {
+		if order, ok := byUID[uid]; ok {
+			orders = append(orders, order)
+			delete(byUID, uid)
+		}
+	}
+	return orders, nil
+}
+
+// DeleteOrder мягко удаляет заказ и записывает это в журнал аудита
+func (g *GormDatabase) DeleteOrder(orderUID string) error {
+	return g.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.Order{}, "order_uid = ?", orderUID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Create(&models.AuditRecord{Action: AuditSoftDelete, Subject: orderUID, Orders: 1}).Error
+	})
+}
+
+// EraseCustomer обезличивает данные клиента во всех его заказах. Запрос
+// записывается в журнал аудита под псевдонимом клиента, даже если заказов нет.
+func (g *GormDatabase) EraseCustomer(customerID string) ([]string, error) {
+	pseudonym, err := customerPseudonym()
+	if err != nil {
+		return nil, err
+	}
+
+	var uids []string
+	err = g.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Model(&models.Order{}).
+			Where("customer_id = ?", customerID).
+			Pluck("order_uid", &uids).Error
+		if err != nil {
+			return err
+		}
+
+		if len(uids) > 0 {
+			err = tx.Model(&models.Delivery{}).
+				Where("order_uid IN ?", uids).
+				Updates(map[string]any{
+					"name":    ErasedValue,
+					"phone":   ErasedValue,
+					"email":   ErasedValue,
+					"address": ErasedValue,
+				}).Error
+			if err != nil {
+				return err
+			}
+
+			err = tx.Unscoped().Model(&models.Order{}).
+				Where("order_uid IN ?", uids).
+				Update("customer_id", pseudonym).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(&models.AuditRecord{Action: AuditEraseCustomer, Subject: pseudonym, Orders: len(uids)}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return uids, nil
+}
+
+// PurgeOrders безвозвратно удаляет самые старые заказы, созданные раньше
+// before. Связанные записи удаляются явно, не полагаясь на ON DELETE CASCADE.
+func (g *GormDatabase) PurgeOrders(before time.Time, limit int) ([]string, error) {
+	if limit <= 0 {
+		limit = -1 // без ограничения
+	}
+
+	var uids []string
+	err := g.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Model(&models.Order{}).
+			Where("date_created < ?", before).
+			Order("date_created").
+			Limit(limit).
+			Pluck("order_uid", &uids).Error
+		if err != nil || len(uids) == 0 {
+			return err
+		}
+
+		for _, model := range []any{&models.Item{}, &models.Payment{}, &models.Delivery{}} {
+			if err := tx.Where("order_uid IN ?", uids).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Unscoped().Where("order_uid IN ?", uids).Delete(&models.Order{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.AuditRecord{Action: AuditPurge, Orders: len(uids)}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return uids, nil
+}
+
+// Ping проверяет соединение с базой данных
+func (g *GormDatabase) Ping(ctx context.Context) error {
+	sqlDB, err := g.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
 // Close закрывает соединение с базой данных
 func (g *GormDatabase) Close() error {
 	sqlDB, err := g.db.DB()
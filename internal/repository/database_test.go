@@ -1,31 +1,134 @@
 package repository
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
+	"os"
+	"strings"
 	"testing"
 	"time"
+	"wb-service/internal/interfaces"
+	"wb-service/internal/migrate"
+	"wb-service/migrations"
 	"wb-service/models"
 
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
-func setupTestDB(t *testing.T) *gorm.DB {
-	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+// testDialect - база данных, на которой выполняются тесты репозитория
+type testDialect struct {
+	name string
+	open func(t *testing.T) *gorm.DB
+}
+
+// testDialects возвращает доступные базы данных: SQLite всегда,
+// PostgreSQL - если задана TEST_POSTGRES_DSN
+func testDialects() []testDialect {
+	dialects := []testDialect{{name: "sqlite", open: openSQLite}}
+	if dsn := os.Getenv("TEST_POSTGRES_DSN"); dsn != "" {
+		dialects = append(dialects, testDialect{
+			name: "postgres",
+			open: func(t *testing.T) *gorm.DB { return openPostgres(t, dsn) },
+		})
+	}
+	return dialects
+}
+
+// forEachDialect выполняет тест на каждой доступной базе данных.
+// setupTestDB создает пустую базу со схемой из миграций.
+func forEachDialect(t *testing.T, test func(t *testing.T, setupTestDB func(*testing.T) *gorm.DB)) {
+	for _, dialect := range testDialects() {
+		t.Run(dialect.name, func(t *testing.T) {
+			test(t, dialect.open)
+		})
+	}
+}
+
+// openSQLite создает отдельную SQLite базу в памяти
+func openSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:?_foreign_keys=1"), &gorm.Config{})
 	if err != nil {
 		t.Fatalf("Failed to connect to test database: %v", err)
 	}
 
-	// Migrate the schema
-	err = db.AutoMigrate(&models.Order{}, &models.Delivery{}, &models.Payment{}, &models.Item{})
+	// Каждое соединение с :memory: открывает новую пустую базу
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	migrateTestDB(t, db)
+	return db
+}
+
+// openPostgres создает для теста отдельную схему в базе TEST_POSTGRES_DSN
+// и удаляет ее по завершении
+func openPostgres(t testing.TB, dsn string) *gorm.DB {
+	t.Helper()
+
+	gormConfig := &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}
+	admin, err := gorm.Open(postgres.Open(dsn), gormConfig)
 	if err != nil {
-		t.Fatalf("Failed to migrate test database: %v", err)
+		t.Fatalf("Failed to connect to postgres: %v", err)
+	}
+
+	schema := fmt.Sprintf("test_%d_%d", time.Now().UnixNano(), rand.Intn(10000))
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
 	}
 
+	db, err := gorm.Open(postgres.Open(withSearchPath(dsn, schema)), gormConfig)
+	if err != nil {
+		t.Fatalf("Failed to connect to postgres: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	migrateTestDB(t, db)
 	return db
 }
 
+// withSearchPath добавляет к DSN (URL или key=value) схему по умолчанию
+func withSearchPath(dsn, schema string) string {
+	if !strings.Contains(dsn, "://") {
+		return dsn + " search_path=" + schema
+	}
+	if strings.Contains(dsn, "?") {
+		return dsn + "&search_path=" + schema
+	}
+	return dsn + "?search_path=" + schema
+}
+
+// migrateTestDB создает схему теми же миграциями, что и в работе сервиса
+func migrateTestDB(t testing.TB, db *gorm.DB) {
+	t.Helper()
+
+	list, err := migrations.For(db.Dialector.Name())
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("Failed to get sql.DB: %v", err)
+	}
+	if _, err := migrate.New(sqlDB, list).Up(context.Background()); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+}
+
 func generateUniqueOrderUID() string {
 	return fmt.Sprintf("test_order_%d_%d", time.Now().UnixNano(), rand.Intn(10000))
 }
@@ -83,192 +186,363 @@ func createTestOrder() *models.Order {
 }
 
 func TestGormDatabase_CreateOrder(t *testing.T) {
-	db := setupTestDB(t)
-	repo := NewGormDatabase(db)
+	forEachDialect(t, func(t *testing.T, setupTestDB func(*testing.T) *gorm.DB) {
+		db := setupTestDB(t)
+		repo := NewGormDatabase(db)
 
-	t.Run("create order successfully", func(t *testing.T) {
-		order := createTestOrder()
+		t.Run("create order successfully", func(t *testing.T) {
+			order := createTestOrder()
 
-		err := repo.CreateOrder(order)
-		if err != nil {
-			t.Errorf("Expected no error, got: %v", err)
-		}
+			err := repo.CreateOrder(order)
+			if err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+			}
 
-		// Verify order was created
-		var count int64
-		db.Model(&models.Order{}).Where("order_uid = ?", order.OrderUID).Count(&count)
-		if count != 1 {
-			t.Errorf("Expected 1 order, got %d", count)
-		}
+			// Verify order was created
+			var count int64
+			db.Model(&models.Order{}).Where("order_uid = ?", order.OrderUID).Count(&count)
+			if count != 1 {
+				t.Errorf("Expected 1 order, got %d", count)
+			}
+		})
+
+		t.Run("create order with duplicate UID should fail", func(t *testing.T) {
+			db := setupTestDB(t)
+			repo := NewGormDatabase(db)
+
+			order1 := createTestOrder()
+			order2 := createTestOrder()
+			order2.OrderUID = order1.OrderUID // Same UID
+
+			// Create first order
+			err := repo.CreateOrder(order1)
+			if err != nil {
+				t.Errorf("Expected no error for first order, got: %v", err)
+			}
+
+			// Try to create second order with same UID
+			err = repo.CreateOrder(order2)
+			if err == nil {
+				t.Error("Expected error for duplicate UID, got nil")
+			}
+		})
 	})
+}
 
-	t.Run("create order with duplicate UID should fail", func(t *testing.T) {
+func TestGormDatabase_GetOrder(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, setupTestDB func(*testing.T) *gorm.DB) {
 		db := setupTestDB(t)
 		repo := NewGormDatabase(db)
 
-		order1 := createTestOrder()
-		order2 := createTestOrder()
-		order2.OrderUID = order1.OrderUID // Same UID
+		t.Run("get existing order", func(t *testing.T) {
+			// Create test order
+			order := createTestOrder()
+			err := repo.CreateOrder(order)
+			if err != nil {
+				t.Fatalf("Failed to create test order: %v", err)
+			}
 
-		// Create first order
-		err := repo.CreateOrder(order1)
-		if err != nil {
-			t.Errorf("Expected no error for first order, got: %v", err)
-		}
+			// Get the order
+			retrieved, err := repo.GetOrder(order.OrderUID)
+			if err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+			}
 
-		// Try to create second order with same UID
-		err = repo.CreateOrder(order2)
-		if err == nil {
-			t.Error("Expected error for duplicate UID, got nil")
-		}
+			if retrieved == nil {
+				t.Fatal("Expected order, got nil")
+			}
+
+			if retrieved.OrderUID != order.OrderUID {
+				t.Errorf("Expected OrderUID %s, got %s", order.OrderUID, retrieved.OrderUID)
+			}
+
+			if retrieved.TrackNumber != order.TrackNumber {
+				t.Errorf("Expected TrackNumber %s, got %s", order.TrackNumber, retrieved.TrackNumber)
+			}
+
+			// Check relations are loaded
+			if retrieved.Delivery.Name != order.Delivery.Name {
+				t.Errorf("Expected delivery name %s, got %s", order.Delivery.Name, retrieved.Delivery.Name)
+			}
+
+			if retrieved.Payment.Amount != order.Payment.Amount {
+				t.Errorf("Expected payment amount %d, got %d", order.Payment.Amount, retrieved.Payment.Amount)
+			}
+
+			if len(retrieved.Items) != len(order.Items) {
+				t.Errorf("Expected %d items, got %d", len(order.Items), len(retrieved.Items))
+			}
+		})
+
+		t.Run("get non-existing order", func(t *testing.T) {
+			_, err := repo.GetOrder("non_existing_order")
+			if err == nil {
+				t.Error("Expected error for non-existing order, got nil")
+			}
+		})
+
+		t.Run("get order with empty UID", func(t *testing.T) {
+			_, err := repo.GetOrder("")
+			if err == nil {
+				t.Error("Expected error for empty UID, got nil")
+			}
+		})
 	})
 }
 
-func TestGormDatabase_GetOrder(t *testing.T) {
-	db := setupTestDB(t)
-	repo := NewGormDatabase(db)
+func TestGormDatabase_GetAllOrders(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, setupTestDB func(*testing.T) *gorm.DB) {
+		db := setupTestDB(t)
+		repo := NewGormDatabase(db)
 
-	t.Run("get existing order", func(t *testing.T) {
-		// Create test order
-		order := createTestOrder()
-		err := repo.CreateOrder(order)
-		if err != nil {
-			t.Fatalf("Failed to create test order: %v", err)
-		}
+		t.Run("get all orders when none exist", func(t *testing.T) {
+			orders, err := repo.GetAllOrders()
+			if err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+			}
 
-		// Get the order
-		retrieved, err := repo.GetOrder(order.OrderUID)
-		if err != nil {
-			t.Errorf("Expected no error, got: %v", err)
-		}
+			if len(orders) != 0 {
+				t.Errorf("Expected 0 orders, got %d", len(orders))
+			}
+		})
 
-		if retrieved == nil {
-			t.Fatal("Expected order, got nil")
-		}
+		t.Run("get all orders with multiple orders", func(t *testing.T) {
+			db := setupTestDB(t)
+			repo := NewGormDatabase(db)
 
-		if retrieved.OrderUID != order.OrderUID {
-			t.Errorf("Expected OrderUID %s, got %s", order.OrderUID, retrieved.OrderUID)
-		}
+			// Create multiple test orders
+			order1 := createTestOrder()
+			order2 := createTestOrder()
+			order3 := createTestOrder()
 
-		if retrieved.TrackNumber != order.TrackNumber {
-			t.Errorf("Expected TrackNumber %s, got %s", order.TrackNumber, retrieved.TrackNumber)
-		}
+			// Insert orders
+			err := repo.CreateOrder(order1)
+			if err != nil {
+				t.Fatalf("Failed to create order1: %v", err)
+			}
 
-		// Check relations are loaded
-		if retrieved.Delivery.Name != order.Delivery.Name {
-			t.Errorf("Expected delivery name %s, got %s", order.Delivery.Name, retrieved.Delivery.Name)
-		}
+			err = repo.CreateOrder(order2)
+			if err != nil {
+				t.Fatalf("Failed to create order2: %v", err)
+			}
 
-		if retrieved.Payment.Amount != order.Payment.Amount {
-			t.Errorf("Expected payment amount %d, got %d", order.Payment.Amount, retrieved.Payment.Amount)
-		}
+			err = repo.CreateOrder(order3)
+			if err != nil {
+				t.Fatalf("Failed to create order3: %v", err)
+			}
 
-		if len(retrieved.Items) != len(order.Items) {
-			t.Errorf("Expected %d items, got %d", len(order.Items), len(retrieved.Items))
-		}
-	})
+			// Get all orders
+			orders, err := repo.GetAllOrders()
+			if err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+			}
 
-	t.Run("get non-existing order", func(t *testing.T) {
-		_, err := repo.GetOrder("non_existing_order")
-		if err == nil {
-			t.Error("Expected error for non-existing order, got nil")
-		}
-	})
+			if len(orders) != 3 {
+				t.Errorf("Expected 3 orders, got %d", len(orders))
+			}
 
-	t.Run("get order with empty UID", func(t *testing.T) {
-		_, err := repo.GetOrder("")
-		if err == nil {
-			t.Error("Expected error for empty UID, got nil")
-		}
+			// Check that relations are loaded
+			for _, order := range orders {
+				if order.Delivery.Name == "" {
+					t.Error("Expected delivery to be loaded")
+				}
+				if order.Payment.Amount == 0 {
+					t.Error("Expected payment to be loaded")
+				}
+				if len(order.Items) == 0 {
+					t.Error("Expected items to be loaded")
+				}
+			}
+		})
 	})
 }
 
-func TestGormDatabase_GetAllOrders(t *testing.T) {
-	db := setupTestDB(t)
-	repo := NewGormDatabase(db)
+func TestGormDatabase_GetRecentOrders(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, setupTestDB func(*testing.T) *gorm.DB) {
+		db := setupTestDB(t)
+		repo := NewGormDatabase(db)
 
-	t.Run("get all orders when none exist", func(t *testing.T) {
-		orders, err := repo.GetAllOrders()
-		if err != nil {
-			t.Errorf("Expected no error, got: %v", err)
+		base := time.Now().Add(-time.Hour)
+		var uids []string
+		for i := 0; i < 5; i++ {
+			order := createTestOrder()
+			order.DateCreated = base.Add(time.Duration(i) * time.Minute)
+			if err := repo.CreateOrder(order); err != nil {
+				t.Fatalf("Failed to create order: %v", err)
+			}
+			uids = append(uids, order.OrderUID)
 		}
 
-		if len(orders) != 0 {
-			t.Errorf("Expected 0 orders, got %d", len(orders))
-		}
+		t.Run("pages are ordered from newest to oldest", func(t *testing.T) {
+			first, err := repo.GetRecentOrders(interfaces.OrderCursor{}, 2)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			second, err := repo.GetRecentOrders(interfaces.CursorAfter(first[1]), 2)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			last, err := repo.GetRecentOrders(interfaces.CursorAfter(second[1]), 2)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			got := append(append(first, second...), last...)
+			if len(got) != 5 {
+				t.Fatalf("Expected 5 orders across pages, got %d", len(got))
+			}
+			for i, order := range got {
+				if want := uids[4-i]; order.OrderUID != want {
+					t.Errorf("Position %d: expected %s, got %s", i, want, order.OrderUID)
+				}
+			}
+
+			if len(got[0].Items) == 0 || got[0].Delivery.Name == "" {
+				t.Error("Expected relations to be loaded")
+			}
+		})
+
+		t.Run("cursor past the end returns empty page", func(t *testing.T) {
+			oldest, err := repo.GetOrder(uids[0])
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			orders, err := repo.GetRecentOrders(interfaces.CursorAfter(*oldest), 2)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if len(orders) != 0 {
+				t.Errorf("Expected empty page, got %d orders", len(orders))
+			}
+		})
+
+		t.Run("orders added between pages do not repeat", func(t *testing.T) {
+			first, err := repo.GetRecentOrders(interfaces.OrderCursor{}, 2)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			// Новый заказ сдвинул бы страницы OFFSET на одну позицию
+			newer := createTestOrder()
+			newer.DateCreated = time.Now()
+			if err := repo.CreateOrder(newer); err != nil {
+				t.Fatalf("Failed to create order: %v", err)
+			}
+
+			second, err := repo.GetRecentOrders(interfaces.CursorAfter(first[1]), 2)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if len(second) != 2 || second[0].OrderUID != uids[2] || second[1].OrderUID != uids[1] {
+				t.Errorf("Expected %s, %s on second page, got %v", uids[2], uids[1], second)
+			}
+		})
 	})
+}
 
-	t.Run("get all orders with multiple orders", func(t *testing.T) {
+func TestGormDatabase_GetOrdersByUIDs(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, setupTestDB func(*testing.T) *gorm.DB) {
 		db := setupTestDB(t)
 		repo := NewGormDatabase(db)
 
-		// Create multiple test orders
 		order1 := createTestOrder()
 		order2 := createTestOrder()
-		order3 := createTestOrder()
+		for _, order := range []*models.Order{order1, order2} {
+			if err := repo.CreateOrder(order); err != nil {
+				t.Fatalf("Failed to create order: %v", err)
+			}
+		}
 
-		// Insert orders
-		err := repo.CreateOrder(order1)
+		orders, err := repo.GetOrdersByUIDs([]string{order2.OrderUID, "missing", order1.OrderUID})
 		if err != nil {
-			t.Fatalf("Failed to create order1: %v", err)
+			t.Fatalf("Expected no error, got: %v", err)
 		}
 
-		err = repo.CreateOrder(order2)
-		if err != nil {
-			t.Fatalf("Failed to create order2: %v", err)
+		if len(orders) != 2 {
+			t.Fatalf("Expected 2 orders, got %d", len(orders))
+		}
+		if orders[0].OrderUID != order2.OrderUID || orders[1].OrderUID != order1.OrderUID {
+			t.Errorf("Expected orders in requested order, got %s, %s", orders[0].OrderUID, orders[1].OrderUID)
+		}
+		if orders[0].Payment.Amount == 0 {
+			t.Error("Expected payment to be loaded")
 		}
 
-		err = repo.CreateOrder(order3)
-		if err != nil {
-			t.Fatalf("Failed to create order3: %v", err)
+		empty, err := repo.GetOrdersByUIDs(nil)
+		if err != nil || len(empty) != 0 {
+			t.Errorf("Expected no orders for empty list, got %d, %v", len(empty), err)
 		}
+	})
+}
 
-		// Get all orders
-		orders, err := repo.GetAllOrders()
-		if err != nil {
-			t.Errorf("Expected no error, got: %v", err)
+// TestOrderWithoutItems проверяет, что заказ без товаров читается всеми
+// реализациями репозитория одинаково, с пустым срезом Items: от этого
+// зависят JSON ответа API и его ETag. На PostgreSQL GORM сравнивается с pgx.
+func TestOrderWithoutItems(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, setupTestDB func(*testing.T) *gorm.DB) {
+		db := setupTestDB(t)
+		repos := map[string]interfaces.Database{"gorm": NewGormDatabase(db)}
+		if db.Dialector.Name() == "postgres" {
+			repos["pgx"] = pgxOver(t, db)
 		}
 
-		if len(orders) != 3 {
-			t.Errorf("Expected 3 orders, got %d", len(orders))
+		order := createTestOrder()
+		order.Items = nil
+		if err := repos["gorm"].CreateOrder(order); err != nil {
+			t.Fatalf("Failed to create order: %v", err)
 		}
 
-		// Check that relations are loaded
-		for _, order := range orders {
-			if order.Delivery.Name == "" {
-				t.Error("Expected delivery to be loaded")
+		for name, repo := range repos {
+			got, err := repo.GetOrder(order.OrderUID)
+			if err != nil {
+				t.Fatalf("%s: GetOrder failed: %v", name, err)
 			}
-			if order.Payment.Amount == 0 {
-				t.Error("Expected payment to be loaded")
+			if got.Items == nil || len(got.Items) != 0 {
+				t.Errorf("%s: expected empty items from GetOrder, got %#v", name, got.Items)
+			}
+
+			page, err := repo.GetRecentOrders(interfaces.OrderCursor{}, 10)
+			if err != nil || len(page) != 1 {
+				t.Fatalf("%s: GetRecentOrders returned %d orders, %v", name, len(page), err)
+			}
+			if page[0].Items == nil || len(page[0].Items) != 0 {
+				t.Errorf("%s: expected empty items from GetRecentOrders, got %#v", name, page[0].Items)
+			}
+
+			orders, err := repo.GetOrdersByUIDs([]string{order.OrderUID})
+			if err != nil || len(orders) != 1 {
+				t.Fatalf("%s: GetOrdersByUIDs returned %d orders, %v", name, len(orders), err)
 			}
-			if len(order.Items) == 0 {
-				t.Error("Expected items to be loaded")
+			if orders[0].Items == nil || len(orders[0].Items) != 0 {
+				t.Errorf("%s: expected empty items from GetOrdersByUIDs, got %#v", name, orders[0].Items)
 			}
 		}
 	})
 }
 
 func TestGormDatabase_Close(t *testing.T) {
-	db := setupTestDB(t)
-	repo := NewGormDatabase(db)
+	forEachDialect(t, func(t *testing.T, setupTestDB func(*testing.T) *gorm.DB) {
+		db := setupTestDB(t)
+		repo := NewGormDatabase(db)
 
-	t.Run("close database connection", func(t *testing.T) {
-		err := repo.Close()
-		if err != nil {
-			t.Errorf("Expected no error when closing database, got: %v", err)
-		}
+		t.Run("close database connection", func(t *testing.T) {
+			err := repo.Close()
+			if err != nil {
+				t.Errorf("Expected no error when closing database, got: %v", err)
+			}
 
-		// Try to use database after close - should fail
-		_, err = repo.GetAllOrders()
-		if err == nil {
-			t.Error("Expected error when using database after close, got nil")
-		}
+			// Try to use database after close - should fail
+			_, err = repo.GetAllOrders()
+			if err == nil {
+				t.Error("Expected error when using database after close, got nil")
+			}
+		})
 	})
 }
 
 func TestNewGormDatabase(t *testing.T) {
 	t.Run("create new gorm database instance", func(t *testing.T) {
-		db := setupTestDB(t)
+		db := openSQLite(t)
 		repo := NewGormDatabase(db)
 
 		if repo == nil {
@@ -280,6 +554,8 @@ func TestNewGormDatabase(t *testing.T) {
 			CreateOrder(*models.Order) error
 			GetOrder(string) (*models.Order, error)
 			GetAllOrders() ([]models.Order, error)
+			GetRecentOrders(interfaces.OrderCursor, int) ([]models.Order, error)
+			GetOrdersByUIDs([]string) ([]models.Order, error)
 			Close() error
 		})
 
@@ -290,58 +566,60 @@ func TestNewGormDatabase(t *testing.T) {
 }
 
 func TestGormDatabase_Integration(t *testing.T) {
-	db := setupTestDB(t)
-	repo := NewGormDatabase(db)
+	forEachDialect(t, func(t *testing.T, setupTestDB func(*testing.T) *gorm.DB) {
+		db := setupTestDB(t)
+		repo := NewGormDatabase(db)
 
-	t.Run("full integration test", func(t *testing.T) {
-		// 1. Initially no orders
-		orders, err := repo.GetAllOrders()
-		if err != nil {
-			t.Errorf("Expected no error, got: %v", err)
-		}
-		if len(orders) != 0 {
-			t.Errorf("Expected 0 initial orders, got %d", len(orders))
-		}
+		t.Run("full integration test", func(t *testing.T) {
+			// 1. Initially no orders
+			orders, err := repo.GetAllOrders()
+			if err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+			}
+			if len(orders) != 0 {
+				t.Errorf("Expected 0 initial orders, got %d", len(orders))
+			}
 
-		// 2. Create an order
-		order := createTestOrder()
-		err = repo.CreateOrder(order)
-		if err != nil {
-			t.Errorf("Expected no error creating order, got: %v", err)
-		}
+			// 2. Create an order
+			order := createTestOrder()
+			err = repo.CreateOrder(order)
+			if err != nil {
+				t.Errorf("Expected no error creating order, got: %v", err)
+			}
 
-		// 3. Get the order
-		retrieved, err := repo.GetOrder(order.OrderUID)
-		if err != nil {
-			t.Errorf("Expected no error getting order, got: %v", err)
-		}
-		if retrieved.OrderUID != order.OrderUID {
-			t.Errorf("Retrieved order UID mismatch")
-		}
+			// 3. Get the order
+			retrieved, err := repo.GetOrder(order.OrderUID)
+			if err != nil {
+				t.Errorf("Expected no error getting order, got: %v", err)
+			}
+			if retrieved.OrderUID != order.OrderUID {
+				t.Errorf("Retrieved order UID mismatch")
+			}
 
-		// 4. Get all orders should return 1
-		orders, err = repo.GetAllOrders()
-		if err != nil {
-			t.Errorf("Expected no error getting all orders, got: %v", err)
-		}
-		if len(orders) != 1 {
-			t.Errorf("Expected 1 order after creation, got %d", len(orders))
-		}
+			// 4. Get all orders should return 1
+			orders, err = repo.GetAllOrders()
+			if err != nil {
+				t.Errorf("Expected no error getting all orders, got: %v", err)
+			}
+			if len(orders) != 1 {
+				t.Errorf("Expected 1 order after creation, got %d", len(orders))
+			}
 
-		// 5. Create another order
-		order2 := createTestOrder()
-		err = repo.CreateOrder(order2)
-		if err != nil {
-			t.Errorf("Expected no error creating second order, got: %v", err)
-		}
+			// 5. Create another order
+			order2 := createTestOrder()
+			err = repo.CreateOrder(order2)
+			if err != nil {
+				t.Errorf("Expected no error creating second order, got: %v", err)
+			}
 
-		// 6. Get all orders should return 2
-		orders, err = repo.GetAllOrders()
-		if err != nil {
-			t.Errorf("Expected no error getting all orders, got: %v", err)
-		}
-		if len(orders) != 2 {
-			t.Errorf("Expected 2 orders after second creation, got %d", len(orders))
-		}
+			// 6. Get all orders should return 2
+			orders, err = repo.GetAllOrders()
+			if err != nil {
+				t.Errorf("Expected no error getting all orders, got: %v", err)
+			}
+			if len(orders) != 2 {
+				t.Errorf("Expected 2 orders after second creation, got %d", len(orders))
+			}
+		})
 	})
-}
\ No newline at end of file
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// Действия в журнале аудита (models.AuditRecord.Action)
+const (
+	AuditSoftDelete    = "soft_delete"
+	AuditEraseCustomer = "erase_customer"
+	AuditPurge         = "purge"
+)
+
+// ErasedValue заменяет персональные данные доставки при обезличивании
+const ErasedValue = "[erased]"
+
+// customerPseudonym создает случайный псевдоним клиента. Обезличенные
+// заказы клиента получают общий псевдоним, но по нему нельзя восстановить
+// исходный идентификатор, в отличие от хэша.
+func customerPseudonym() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "erased-" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,250 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"wb-service/internal/interfaces"
+	"wb-service/models"
+
+	"gorm.io/gorm"
+)
+
+// erasableDatabase - репозиторий с удалением и обезличиванием заказов
+type erasableDatabase interface {
+	interfaces.Database
+	interfaces.OrderEraser
+}
+
+// forEachEraser выполняет тест на каждой реализации репозитория: GORM на
+// каждой доступной базе и pgx, если задана TEST_POSTGRES_DSN. db - та же
+// база через GORM для проверки результата.
+func forEachEraser(t *testing.T, test func(t *testing.T, repo erasableDatabase, db *gorm.DB)) {
+	forEachDialect(t, func(t *testing.T, setupTestDB func(*testing.T) *gorm.DB) {
+		db := setupTestDB(t)
+		test(t, &GormDatabase{db: db}, db)
+	})
+	t.Run("pgx", func(t *testing.T) {
+		repo, gormRepo := setupPgx(t)
+		test(t, repo, gormRepo.db)
+	})
+}
+
+// lastAudit возвращает последнюю запись журнала аудита
+func lastAudit(t *testing.T, db *gorm.DB) models.AuditRecord {
+	t.Helper()
+
+	var record models.AuditRecord
+	if err := db.Order("id DESC").First(&record).Error; err != nil {
+		t.Fatalf("Failed to load audit record: %v", err)
+	}
+	return record
+}
+
+func TestOrderEraser_DeleteOrder(t *testing.T) {
+	var _ interfaces.OrderEraser = (*GormDatabase)(nil)
+	var _ interfaces.OrderEraser = (*PgxDatabase)(nil)
+	var _ interfaces.OrderEraser = (*ReplicatedDatabase)(nil)
+
+	forEachEraser(t, func(t *testing.T, repo erasableDatabase, db *gorm.DB) {
+		order := createTestOrder()
+		if err := repo.CreateOrder(order); err != nil {
+			t.Fatalf("CreateOrder failed: %v", err)
+		}
+
+		if err := repo.DeleteOrder(order.OrderUID); err != nil {
+			t.Fatalf("DeleteOrder failed: %v", err)
+		}
+
+		// Удаленный заказ не возвращается ни одним методом чтения
+		if _, err := repo.GetOrder(order.OrderUID); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("Expected deleted order to be not found, got %v", err)
+		}
+		if orders, _ := repo.GetRecentOrders(interfaces.OrderCursor{}, 10); len(orders) != 0 {
+			t.Errorf("Expected no recent orders, got %d", len(orders))
+		}
+		if orders, _ := repo.GetOrdersByUIDs([]string{order.OrderUID}); len(orders) != 0 {
+			t.Errorf("Expected no orders by UID, got %d", len(orders))
+		}
+		if orders, _ := repo.GetAllOrders(); len(orders) != 0 {
+			t.Errorf("Expected no orders, got %d", len(orders))
+		}
+
+		// Но данные остаются в БД до очистки
+		var count int64
+		db.Unscoped().Model(&models.Order{}).Where("order_uid = ?", order.OrderUID).Count(&count)
+		if count != 1 {
+			t.Errorf("Expected soft deleted order to stay in database, got %d rows", count)
+		}
+
+		audit := lastAudit(t, db)
+		if audit.Action != AuditSoftDelete || audit.Subject != order.OrderUID || audit.Orders != 1 {
+			t.Errorf("Unexpected audit record: %+v", audit)
+		}
+
+		if err := repo.DeleteOrder(order.OrderUID); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("Expected repeated delete to return ErrRecordNotFound, got %v", err)
+		}
+		if err := repo.DeleteOrder("missing"); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("Expected ErrRecordNotFound for missing order, got %v", err)
+		}
+	})
+}
+
+func TestOrderEraser_EraseCustomer(t *testing.T) {
+	forEachEraser(t, func(t *testing.T, repo erasableDatabase, db *gorm.DB) {
+		var uids []string
+		for i := 0; i < 2; i++ {
+			order := createTestOrder()
+			order.CustomerID = "customer_erase"
+			if err := repo.CreateOrder(order); err != nil {
+				t.Fatalf("CreateOrder failed: %v", err)
+			}
+			uids = append(uids, order.OrderUID)
+		}
+		other := createTestOrder()
+		if err := repo.CreateOrder(other); err != nil {
+			t.Fatalf("CreateOrder failed: %v", err)
+		}
+
+		// Обезличиваются и удаленные заказы
+		if err := repo.DeleteOrder(uids[1]); err != nil {
+			t.Fatalf("DeleteOrder failed: %v", err)
+		}
+
+		erased, err := repo.EraseCustomer("customer_erase")
+		if err != nil {
+			t.Fatalf("EraseCustomer failed: %v", err)
+		}
+		if len(erased) != 2 {
+			t.Fatalf("Expected 2 erased orders, got %v", erased)
+		}
+
+		var orders []models.Order
+		db.Unscoped().Preload("Delivery").Where("order_uid IN ?", uids).Find(&orders)
+		pseudonym := orders[0].CustomerID
+		if !strings.HasPrefix(pseudonym, "erased-") {
+			t.Errorf("Expected customer pseudonym, got %q", pseudonym)
+		}
+		for _, order := range orders {
+			d := order.Delivery
+			if order.CustomerID != pseudonym || d.Name != ErasedValue || d.Phone != ErasedValue ||
+				d.Email != ErasedValue || d.Address != ErasedValue {
+				t.Errorf("Order %s is not anonymized: %s %+v", order.OrderUID, order.CustomerID, d)
+			}
+			if d.City != "New York" {
+				t.Errorf("Expected non-personal fields to stay, got %+v", d)
+			}
+		}
+
+		kept, err := repo.GetOrder(other.OrderUID)
+		if err != nil || kept.CustomerID != "customer_123" || kept.Delivery.Name != "John Doe" {
+			t.Errorf("Expected other customer to stay unchanged, got %+v, %v", kept, err)
+		}
+
+		audit := lastAudit(t, db)
+		if audit.Action != AuditEraseCustomer || audit.Subject != pseudonym || audit.Orders != 2 {
+			t.Errorf("Unexpected audit record: %+v", audit)
+		}
+
+		// Запрос для клиента без заказов тоже попадает в журнал
+		if erased, err := repo.EraseCustomer("customer_erase"); err != nil || len(erased) != 0 {
+			t.Errorf("Expected nothing to erase, got %v, %v", erased, err)
+		}
+		if audit := lastAudit(t, db); audit.Action != AuditEraseCustomer || audit.Orders != 0 {
+			t.Errorf("Unexpected audit record: %+v", audit)
+		}
+	})
+}
+
+func TestOrderEraser_PurgeOrders(t *testing.T) {
+	forEachEraser(t, func(t *testing.T, repo erasableDatabase, db *gorm.DB) {
+		now := time.Now()
+		var uids []string
+		for _, age := range []time.Duration{72 * time.Hour, 48 * time.Hour, time.Hour} {
+			order := createTestOrder()
+			order.DateCreated = now.Add(-age)
+			if err := repo.CreateOrder(order); err != nil {
+				t.Fatalf("CreateOrder failed: %v", err)
+			}
+			uids = append(uids, order.OrderUID)
+		}
+		if err := repo.DeleteOrder(uids[1]); err != nil {
+			t.Fatalf("DeleteOrder failed: %v", err)
+		}
+
+		before := now.Add(-24 * time.Hour)
+
+		// Сначала удаляются самые старые заказы
+		purged, err := repo.PurgeOrders(before, 1)
+		if err != nil {
+			t.Fatalf("PurgeOrders failed: %v", err)
+		}
+		if len(purged) != 1 || purged[0] != uids[0] {
+			t.Errorf("Expected oldest order to be purged, got %v", purged)
+		}
+
+		// Мягко удаленные заказы тоже удаляются
+		purged, err = repo.PurgeOrders(before, 0)
+		if err != nil {
+			t.Fatalf("PurgeOrders failed: %v", err)
+		}
+		if len(purged) != 1 || purged[0] != uids[1] {
+			t.Errorf("Expected soft deleted order to be purged, got %v", purged)
+		}
+
+		audit := lastAudit(t, db)
+		if audit.Action != AuditPurge || audit.Orders != 1 {
+			t.Errorf("Unexpected audit record: %+v", audit)
+		}
+
+		for _, table := range []string{"orders", "deliveries", "payments", "items"} {
+			var count int64
+			db.Table(table).Where("order_uid IN ?", uids[:2]).Count(&count)
+			if count != 0 {
+				t.Errorf("Expected purged rows to be removed from %s, got %d", table, count)
+			}
+		}
+
+		if _, err := repo.GetOrder(uids[2]); err != nil {
+			t.Errorf("Expected recent order to stay: %v", err)
+		}
+
+		if purged, err := repo.PurgeOrders(before, 0); err != nil || len(purged) != 0 {
+			t.Errorf("Expected nothing to purge, got %v, %v", purged, err)
+		}
+	})
+}
+
+func TestReplicatedDatabase_OrderEraser(t *testing.T) {
+	r, primary, replicas := setupReplicated(t, 1, ReplicaOptions{ReadAfterWrite: time.Minute})
+
+	order := createTestOrder()
+	if err := r.CreateOrder(order); err != nil {
+		t.Fatalf("CreateOrder failed: %v", err)
+	}
+	if err := replicas[0].Create(createTestOrder()).Error; err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+
+	// Удаление выполняется на primary, и заказ сразу читается оттуда
+	if err := r.DeleteOrder(order.OrderUID); err != nil {
+		t.Fatalf("DeleteOrder failed: %v", err)
+	}
+	if _, err := r.GetOrder(order.OrderUID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Expected deleted order to be not found, got %v", err)
+	}
+	if audit := lastAudit(t, primary); audit.Action != AuditSoftDelete {
+		t.Errorf("Expected audit record on primary, got %+v", audit)
+	}
+
+	// Без поддержки на primary операции недоступны
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	unsupported := NewReplicatedDatabase(ctx, &flakyReplica{Database: NewGormDatabase(primary)}, nil, ReplicaOptions{})
+	if err := unsupported.DeleteOrder(order.OrderUID); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported, got %v", err)
+	}
+}
@@ -0,0 +1,350 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+	"wb-service/config"
+	"wb-service/internal/interfaces"
+	"wb-service/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"gorm.io/gorm"
+)
+
+// orderSelect собирает заказ вместе с доставкой, оплатой и товарами в один
+// JSON объект, поэтому заказ читается одним запросом вместо четырех
+// (заказ и три Preload в GORM). Ключи совпадают с JSON тегами моделей.
+const orderSelect = `
+SELECT json_build_object(
+    'order_uid', o.order_uid,
+    'track_number', o.track_number,
+    'entry', o.entry,
+    'locale', o.locale,
+    'internal_signature', o.internal_signature,
+    'customer_id', o.customer_id,
+    'delivery_service', o.delivery_service,
+    'shardkey', o.shardkey,
+    'sm_id', o.sm_id,
+    'date_created', o.date_created,
+    'oof_shard', o.oof_shard,
+    'delivery', (
+        SELECT row_to_json(d) FROM (
+            SELECT name, phone, zip, city, address, region, email
+            FROM deliveries WHERE order_uid = o.order_uid
+            ORDER BY id LIMIT 1
+        ) d
+    ),
+    'payment', (
+        SELECT row_to_json(p) FROM (
+            SELECT "transaction", request_id, currency, provider, amount,
+                   payment_dt, bank, delivery_cost, goods_total, custom_fee
+            FROM payments WHERE order_uid = o.order_uid
+            ORDER BY id LIMIT 1
+        ) p
+    ),
+    'items', (
+        SELECT COALESCE(json_agg(i ORDER BY i.id), '[]'::json) FROM (
+            SELECT id, chrt_id, track_number, price, rid, name, sale, size,
+                   total_price, nm_id, brand, status
+            FROM items WHERE order_uid = o.order_uid
+        ) i
+    )
+)
+FROM orders o`
+
+// PgxDatabase реализует интерфейс Database напрямую через pgx. Заказы
+// читаются одним запросом с JSON агрегацией связанных таблиц. ID записей
+// доставки, оплаты и товаров не загружаются: в API они не отдаются. У заказа
+// без товаров Items - пустой срез, как после Preload в GORM: иначе JSON ответа
+// API и его ETag зависели бы от реализации.
+// Работает только с PostgreSQL.
+type PgxDatabase struct {
+	pool *pgxpool.Pool
+}
+
+// NewPgxDatabase создает репозиторий поверх пула соединений pgx
+func NewPgxDatabase(pool *pgxpool.Pool) *PgxDatabase {
+	return &PgxDatabase{pool: pool}
+}
+
+// OpenPgx создает пул соединений pgx к PostgreSQL из конфигурации
+// с теми же ограничениями пула, что и у GORM, и проверяет подключение
+func OpenPgx(ctx context.Context, cfg *config.Config) (*PgxDatabase, error) {
+	poolConfig, err := pgxPoolConfig(cfg.DatabaseDSN(), cfg.Database)
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, err
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("connect to database: %w", err)
+	}
+
+	return NewPgxDatabase(pool), nil
+}
+
+// OpenPgxReplica создает пул соединений pgx к реплике по DSN. Подключение
+// не проверяется: доступность реплики отслеживает ReplicatedDatabase.
+func OpenPgxReplica(ctx context.Context, dsn string, cfg config.DatabaseConfig) (*PgxDatabase, error) {
+	poolConfig, err := pgxPoolConfig(dsn, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, err
+	}
+	return NewPgxDatabase(pool), nil
+}
+
+// pgxPoolConfig разбирает DSN и применяет настройки пула из конфигурации
+func pgxPoolConfig(dsn string, cfg config.DatabaseConfig) (*pgxpool.Config, error) {
+	poolConfig, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.MaxOpenConns > 0 {
+		poolConfig.MaxConns = int32(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		poolConfig.MinConns = int32(min(cfg.MaxIdleConns, int(poolConfig.MaxConns)))
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		poolConfig.MaxConnLifetime = time.Duration(cfg.ConnMaxLifetime) * time.Second
+	}
+	return poolConfig, nil
+}
+
+// CreateOrder создает заказ со связанными записями в одной транзакции
+func (p *PgxDatabase) CreateOrder(order *models.Order) error {
+	ctx := context.Background()
+
+	return pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		batch := &pgx.Batch{}
+		batch.Queue(`INSERT INTO orders (order_uid, track_number, entry, locale, internal_signature,
+			customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+			order.OrderUID, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature,
+			order.CustomerID, order.DeliveryService, order.Shardkey, order.SmID, order.DateCreated, order.OofShard)
+
+		d := order.Delivery
+		batch.Queue(`INSERT INTO deliveries (order_uid, name, phone, zip, city, address, region, email)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			order.OrderUID, d.Name, d.Phone, d.Zip, d.City, d.Address, d.Region, d.Email)
+
+		pm := order.Payment
+		batch.Queue(`INSERT INTO payments (order_uid, "transaction", request_id, currency, provider,
+			amount, payment_dt, bank, delivery_cost, goods_total, custom_fee)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+			order.OrderUID, pm.Transaction, pm.RequestID, pm.Currency, pm.Provider,
+			pm.Amount, pm.PaymentDt, pm.Bank, pm.DeliveryCost, pm.GoodsTotal, pm.CustomFee)
+
+		for _, item := range order.Items {
+			batch.Queue(`INSERT INTO items (order_uid, chrt_id, track_number, price, rid, name,
+				sale, size, total_price, nm_id, brand, status)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+				order.OrderUID, item.ChrtID, item.TrackNumber, item.Price, item.Rid, item.Name,
+				item.Sale, item.Size, item.TotalPrice, item.NmID, item.Brand, item.Status)
+		}
+
+		return tx.SendBatch(ctx, batch).Close()
+	})
+}
+
+// GetOrder получает заказ по UID. Если заказа нет, возвращает
+// gorm.ErrRecordNotFound, как и GormDatabase.
+func (p *PgxDatabase) GetOrder(orderUID string) (*models.Order, error) {
+	orders, err := p.query(orderSelect+` WHERE o.order_uid = $1 AND o.deleted_at IS NULL`, orderUID)
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &orders[0], nil
+}
+
+// GetAllOrders получает все заказы из базы данных
+func (p *PgxDatabase) GetAllOrders() ([]models.Order, error) {
+	return p.query(orderSelect + ` WHERE o.deleted_at IS NULL ORDER BY o.order_uid`)
+}
+
+// GetRecentOrders получает страницу заказов после курсора, от новых к старым
+func (p *PgxDatabase) GetRecentOrders(after interfaces.OrderCursor, limit int) ([]models.Order, error) {
+	if after.OrderUID == "" {
+		return p.query(orderSelect+` WHERE o.deleted_at IS NULL
+			ORDER BY o.date_created DESC, o.order_uid LIMIT $1`, limit)
+	}
+	return p.query(orderSelect+` WHERE o.deleted_at IS NULL
+		AND (o.date_created < $1 OR (o.date_created = $1 AND o.order_uid > $2))
+		ORDER BY o.date_created DESC, o.order_uid LIMIT $3`, after.DateCreated, after.OrderUID, limit)
+}
+
+// GetOrdersByUIDs получает заказы по списку UID. Отсутствующие в базе
+// UID пропускаются, порядок результата совпадает с порядком orderUIDs.
+func (p *PgxDatabase) GetOrdersByUIDs(orderUIDs []string) ([]models.Order, error) {
+	if len(orderUIDs) == 0 {
+		return nil, nil
+	}
+
+	// Повторы UID возвращают заказ один раз, как и в GormDatabase
+	seen := make(map[string]bool, len(orderUIDs))
+	uids := make([]string, 0, len(orderUIDs))
+	for _, uid := range orderUIDs {
+		if !seen[uid] {
+			seen[uid] = true
+			uids = append(uids, uid)
+		}
+	}
+
+	// WITH ORDINALITY сохраняет порядок переданных UID
+	return p.query(orderSelect+`
+		JOIN unnest($1::text[]) WITH ORDINALITY AS uids(uid, n) ON uids.uid = o.order_uid
+		WHERE o.deleted_at IS NULL
+		ORDER BY uids.n`, uids)
+}
+
+// DeleteOrder мягко удаляет заказ и записывает это в журнал аудита
+func (p *PgxDatabase) DeleteOrder(orderUID string) error {
+	ctx := context.Background()
+
+	return pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `UPDATE orders SET deleted_at = now()
+			WHERE order_uid = $1 AND deleted_at IS NULL`, orderUID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return insertAudit(ctx, tx, AuditSoftDelete, orderUID, 1)
+	})
+}
+
+// EraseCustomer обезличивает данные клиента во всех его заказах. Запрос
+// записывается в журнал аудита под псевдонимом клиента, даже если заказов нет.
+func (p *PgxDatabase) EraseCustomer(customerID string) ([]string, error) {
+	pseudonym, err := customerPseudonym()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	var uids []string
+	err = pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `UPDATE orders SET customer_id = $2
+			WHERE customer_id = $1 RETURNING order_uid`, customerID, pseudonym)
+		if err != nil {
+			return err
+		}
+		if uids, err = pgx.CollectRows(rows, pgx.RowTo[string]); err != nil {
+			return err
+		}
+
+		if len(uids) > 0 {
+			_, err = tx.Exec(ctx, `UPDATE deliveries SET name = $2, phone = $2, email = $2, address = $2
+				WHERE order_uid = ANY($1)`, uids, ErasedValue)
+			if err != nil {
+				return err
+			}
+		}
+
+		return insertAudit(ctx, tx, AuditEraseCustomer, pseudonym, len(uids))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return uids, nil
+}
+
+// PurgeOrders безвозвратно удаляет самые старые заказы, созданные раньше
+// before. Связанные записи удаляются через ON DELETE CASCADE.
+func (p *PgxDatabase) PurgeOrders(before time.Time, limit int) ([]string, error) {
+	// LIMIT NULL - без ограничения
+	var maxRows any
+	if limit > 0 {
+		maxRows = limit
+	}
+
+	ctx := context.Background()
+	var uids []string
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `DELETE FROM orders WHERE order_uid IN (
+				SELECT order_uid FROM orders WHERE date_created < $1
+				ORDER BY date_created LIMIT $2
+			) RETURNING order_uid`, before, maxRows)
+		if err != nil {
+			return err
+		}
+		if uids, err = pgx.CollectRows(rows, pgx.RowTo[string]); err != nil || len(uids) == 0 {
+			return err
+		}
+
+		return insertAudit(ctx, tx, AuditPurge, "", len(uids))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return uids, nil
+}
+
+// Ping проверяет соединение с базой данных
+func (p *PgxDatabase) Ping(ctx context.Context) error {
+	return p.pool.Ping(ctx)
+}
+
+// Close закрывает пул соединений
+func (p *PgxDatabase) Close() error {
+	p.pool.Close()
+	return nil
+}
+
+// query выполняет запрос на основе orderSelect и разбирает заказы из JSON
+func (p *PgxDatabase) query(sql string, args ...any) ([]models.Order, error) {
+	rows, err := p.pool.Query(context.Background(), sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []models.Order
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+
+		var order models.Order
+		if err := json.Unmarshal(data, &order); err != nil {
+			return nil, fmt.Errorf("decode order: %w", err)
+		}
+		linkOrder(&order)
+		orders = append(orders, order)
+	}
+	return orders, rows.Err()
+}
+
+// insertAudit добавляет запись в журнал аудита в транзакции tx
+func insertAudit(ctx context.Context, tx pgx.Tx, action, subject string, orders int) error {
+	_, err := tx.Exec(ctx, `INSERT INTO audit_records (action, subject, orders, created_at)
+		VALUES ($1, $2, $3, now())`, action, subject, orders)
+	return err
+}
+
+// linkOrder заполняет OrderUID связанных записей, который не входит в JSON
+func linkOrder(order *models.Order) {
+	order.Delivery.OrderUID = order.OrderUID
+	order.Payment.OrderUID = order.OrderUID
+	for i := range order.Items {
+		order.Items[i].OrderUID = order.OrderUID
+	}
+}
@@ -0,0 +1,218 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+	"wb-service/config"
+	"wb-service/internal/interfaces"
+	"wb-service/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"gorm.io/gorm"
+)
+
+// setupPgx создает PgxDatabase и GormDatabase поверх одной пустой схемы
+// PostgreSQL. Без TEST_POSTGRES_DSN тест пропускается.
+func setupPgx(t testing.TB) (*PgxDatabase, *GormDatabase) {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+
+	db := openPostgres(t, dsn)
+	return pgxOver(t, db), &GormDatabase{db: db}
+}
+
+// pgxOver создает PgxDatabase, подключенный к той же схеме PostgreSQL
+// из TEST_POSTGRES_DSN, что и db
+func pgxOver(t testing.TB, db *gorm.DB) *PgxDatabase {
+	t.Helper()
+
+	var schema string
+	if err := db.Raw("SELECT current_schema()").Scan(&schema).Error; err != nil {
+		t.Fatalf("Failed to get schema: %v", err)
+	}
+
+	pool, err := pgxpool.New(context.Background(), withSearchPath(os.Getenv("TEST_POSTGRES_DSN"), schema))
+	if err != nil {
+		t.Fatalf("Failed to connect pgx: %v", err)
+	}
+	repo := NewPgxDatabase(pool)
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+// normalize убирает различия, не влияющие на содержимое заказа:
+// ID связанных записей и часовой пояс даты
+func normalize(order models.Order) models.Order {
+	order.DateCreated = order.DateCreated.UTC().Truncate(time.Microsecond)
+	order.Delivery.ID = 0
+	order.Payment.ID = 0
+	items := make([]models.Item, len(order.Items))
+	for i, item := range order.Items {
+		item.ID = 0
+		items[i] = item
+	}
+	order.Items = items
+	return order
+}
+
+func TestPgxDatabase(t *testing.T) {
+	repo, gormRepo := setupPgx(t)
+
+	base := time.Now().Add(-time.Hour)
+	var uids []string
+	for i := 0; i < 3; i++ {
+		order := createTestOrder()
+		order.DateCreated = base.Add(time.Duration(i) * time.Minute)
+		order.Items = append(order.Items, models.Item{ChrtID: i, Name: fmt.Sprintf("Extra %d", i)})
+		if err := repo.CreateOrder(order); err != nil {
+			t.Fatalf("CreateOrder failed: %v", err)
+		}
+		uids = append(uids, order.OrderUID)
+	}
+
+	t.Run("get order matches gorm", func(t *testing.T) {
+		got, err := repo.GetOrder(uids[0])
+		if err != nil {
+			t.Fatalf("GetOrder failed: %v", err)
+		}
+		want, err := gormRepo.GetOrder(uids[0])
+		if err != nil {
+			t.Fatalf("GORM GetOrder failed: %v", err)
+		}
+
+		if fmt.Sprintf("%+v", normalize(*got)) != fmt.Sprintf("%+v", normalize(*want)) {
+			t.Errorf("Orders differ:\npgx:  %+v\ngorm: %+v", normalize(*got), normalize(*want))
+		}
+	})
+
+	t.Run("missing order", func(t *testing.T) {
+		if _, err := repo.GetOrder("missing"); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("Expected gorm.ErrRecordNotFound, got %v", err)
+		}
+	})
+
+	t.Run("recent orders", func(t *testing.T) {
+		newest, err := repo.GetOrder(uids[2])
+		if err != nil {
+			t.Fatalf("GetOrder failed: %v", err)
+		}
+		page, err := repo.GetRecentOrders(interfaces.CursorAfter(*newest), 2)
+		if err != nil {
+			t.Fatalf("GetRecentOrders failed: %v", err)
+		}
+		if len(page) != 2 || page[0].OrderUID != uids[1] || page[1].OrderUID != uids[0] {
+			t.Errorf("Unexpected page: %v", page)
+		}
+	})
+
+	t.Run("orders by UIDs keep order", func(t *testing.T) {
+		orders, err := repo.GetOrdersByUIDs([]string{uids[2], "missing", uids[0], uids[2]})
+		if err != nil {
+			t.Fatalf("GetOrdersByUIDs failed: %v", err)
+		}
+		if len(orders) != 2 || orders[0].OrderUID != uids[2] || orders[1].OrderUID != uids[0] {
+			t.Errorf("Unexpected orders: %v", orders)
+		}
+	})
+
+	t.Run("all orders", func(t *testing.T) {
+		orders, err := repo.GetAllOrders()
+		if err != nil {
+			t.Fatalf("GetAllOrders failed: %v", err)
+		}
+		if len(orders) != len(uids) {
+			t.Errorf("Expected %d orders, got %d", len(uids), len(orders))
+		}
+	})
+
+	t.Run("duplicate order rolls back", func(t *testing.T) {
+		order, _ := gormRepo.GetOrder(uids[0])
+		if err := repo.CreateOrder(order); err == nil {
+			t.Fatal("Expected error for duplicate UID")
+		}
+
+		var count int64
+		gormRepo.db.Model(&models.Item{}).Where("order_uid = ?", uids[0]).Count(&count)
+		if count != 2 {
+			t.Errorf("Expected failed insert to leave 2 items, got %d", count)
+		}
+	})
+}
+
+func TestOpenPgxUnreachable(t *testing.T) {
+	cfg := config.Load()
+	cfg.Database.Host = "127.0.0.1"
+	cfg.Database.Port = "1" // на этом порту никто не слушает
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := OpenPgx(ctx, cfg); err == nil {
+		t.Error("Expected OpenPgx to fail for unreachable database")
+	}
+}
+
+// seedBenchmark создает n заказов по три товара и возвращает их UID
+func seedBenchmark(b *testing.B, repo *PgxDatabase, n int) []string {
+	b.Helper()
+
+	uids := make([]string, n)
+	for i := range uids {
+		order := createTestOrder()
+		order.DateCreated = time.Now().Add(-time.Duration(i) * time.Second)
+		order.Items = append(order.Items, order.Items[0], order.Items[0])
+		if err := repo.CreateOrder(order); err != nil {
+			b.Fatalf("CreateOrder failed: %v", err)
+		}
+		uids[i] = order.OrderUID
+	}
+	return uids
+}
+
+// Бенчмарки сравнивают GORM (четыре запроса на заказ) и pgx (один запрос).
+// Запуск: TEST_POSTGRES_DSN=... go test -bench . ./internal/repository/
+func BenchmarkGetOrder(b *testing.B) {
+	repo, gormRepo := setupPgx(b)
+	uids := seedBenchmark(b, repo, 200)
+
+	for _, impl := range []struct {
+		name string
+		repo interfaces.Database
+	}{{"gorm", gormRepo}, {"pgx", repo}} {
+		b.Run(impl.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := impl.repo.GetOrder(uids[i%len(uids)]); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGetRecentOrders(b *testing.B) {
+	repo, gormRepo := setupPgx(b)
+	seedBenchmark(b, repo, 200)
+
+	for _, impl := range []struct {
+		name string
+		repo interfaces.Database
+	}{{"gorm", gormRepo}, {"pgx", repo}} {
+		b.Run(impl.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := impl.repo.GetRecentOrders(interfaces.OrderCursor{}, 100); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,340 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+	"wb-service/internal/interfaces"
+	"wb-service/models"
+
+	"gorm.io/gorm"
+)
+
+// defaultCheckInterval - период проверки реплик, если он не задан
+const defaultCheckInterval = 5 * time.Second
+
+// ReplicaOptions настраивает маршрутизацию чтения по репликам
+type ReplicaOptions struct {
+	// CheckInterval - период проверки доступности реплик, 0 - 5 секунд
+	CheckInterval time.Duration
+	// ReadAfterWrite - сколько после записи заказа читать его с primary,
+	// пока запись не дошла до реплик. 0 - всегда читать с реплик.
+	ReadAfterWrite time.Duration
+}
+
+// pinger реализуется репозиториями, соединение которых можно проверить
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// replica - репозиторий реплики и результат последней проверки
+type replica struct {
+	db      interfaces.Database
+	healthy atomic.Bool
+}
+
+// ReplicatedDatabase реализует интерфейс Database поверх primary и реплик.
+// Запись всегда выполняется на primary, чтение распределяется по доступным
+// репликам по кругу. Доступность реплик проверяется в фоне через Ping;
+// реплика, на которой запрос завершился ошибкой, исключается до следующей
+// успешной проверки, а запрос повторяется на primary. Если доступных реплик
+// нет, чтение идет с primary.
+//
+// Для чтения своих записей заказ в течение ReadAfterWrite после записи
+// читается с primary; списки заказов в это время тоже читаются с primary.
+type ReplicatedDatabase struct {
+	primary  interfaces.Database
+	replicas []*replica
+	next     atomic.Uint64
+	opts     ReplicaOptions
+
+	mu        sync.Mutex
+	written   map[string]time.Time // UID заказа -> время записи
+	lastWrite time.Time
+	lastPrune time.Time
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewReplicatedDatabase создает репозиторий с чтением с реплик и запускает
+// фоновую проверку реплик, которая работает до отмены ctx или вызова Close.
+// До первой проверки реплики считаются доступными.
+func NewReplicatedDatabase(ctx context.Context, primary interfaces.Database, replicas []interfaces.Database, opts ReplicaOptions) *ReplicatedDatabase {
+	if opts.CheckInterval <= 0 {
+		opts.CheckInterval = defaultCheckInterval
+	}
+
+	r := &ReplicatedDatabase{
+		primary: primary,
+		opts:    opts,
+		written: make(map[string]time.Time),
+		done:    make(chan struct{}),
+	}
+	for _, db := range replicas {
+		rep := &replica{db: db}
+		rep.healthy.Store(true)
+		r.replicas = append(r.replicas, rep)
+	}
+
+	ctx, r.cancel = context.WithCancel(ctx)
+	go r.checkReplicas(ctx)
+
+	return r
+}
+
+// CreateOrder создает заказ на primary
+func (r *ReplicatedDatabase) CreateOrder(order *models.Order) error {
+	if err := r.primary.CreateOrder(order); err != nil {
+		return err
+	}
+	r.recordWrite(order.OrderUID)
+	return nil
+}
+
+// GetOrder получает заказ с реплики, а недавно записанный - с primary
+func (r *ReplicatedDatabase) GetOrder(orderUID string) (*models.Order, error) {
+	var order *models.Order
+	err := r.read(r.writtenRecently(orderUID), func(db interfaces.Database) error {
+		var err error
+		order, err = db.GetOrder(orderUID)
+		return err
+	})
+	return order, err
+}
+
+// GetAllOrders получает все заказы с реплики
+func (r *ReplicatedDatabase) GetAllOrders() ([]models.Order, error) {
+	var orders []models.Order
+	err := r.read(r.writtenRecently(), func(db interfaces.Database) error {
+		var err error
+		orders, err = db.GetAllOrders()
+		return err
+	})
+	return orders, err
+}
+
+// GetRecentOrders получает страницу заказов с реплики
+func (r *ReplicatedDatabase) GetRecentOrders(after interfaces.OrderCursor, limit int) ([]models.Order, error) {
+	var orders []models.Order
+	err := r.read(r.writtenRecently(), func(db interfaces.Database) error {
+		var err error
+		orders, err = db.GetRecentOrders(after, limit)
+		return err
+	})
+	return orders, err
+}
+
+// GetOrdersByUIDs получает заказы с реплики, а если среди них есть
+// недавно записанные - с primary
+func (r *ReplicatedDatabase) GetOrdersByUIDs(orderUIDs []string) ([]models.Order, error) {
+	var orders []models.Order
+	err := r.read(r.writtenRecently(orderUIDs...), func(db interfaces.Database) error {
+		var err error
+		orders, err = db.GetOrdersByUIDs(orderUIDs)
+		return err
+	})
+	return orders, err
+}
+
+// DeleteOrder мягко удаляет заказ на primary
+func (r *ReplicatedDatabase) DeleteOrder(orderUID string) error {
+	eraser, ok := r.primary.(interfaces.OrderEraser)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	if err := eraser.DeleteOrder(orderUID); err != nil {
+		return err
+	}
+	r.recordWrite(orderUID)
+	return nil
+}
+
+// EraseCustomer обезличивает данные клиента на primary
+func (r *ReplicatedDatabase) EraseCustomer(customerID string) ([]string, error) {
+	eraser, ok := r.primary.(interfaces.OrderEraser)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	uids, err := eraser.EraseCustomer(customerID)
+	for _, uid := range uids {
+		r.recordWrite(uid)
+	}
+	return uids, err
+}
+
+// PurgeOrders удаляет старые заказы на primary
+func (r *ReplicatedDatabase) PurgeOrders(before time.Time, limit int) ([]string, error) {
+	eraser, ok := r.primary.(interfaces.OrderEraser)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	uids, err := eraser.PurgeOrders(before, limit)
+	for _, uid := range uids {
+		r.recordWrite(uid)
+	}
+	return uids, err
+}
+
+// HealthyReplicas возвращает количество доступных реплик
+func (r *ReplicatedDatabase) HealthyReplicas() int {
+	n := 0
+	for _, rep := range r.replicas {
+		if rep.healthy.Load() {
+			n++
+		}
+	}
+	return n
+}
+
+// Close останавливает проверку реплик и закрывает реплики и primary
+func (r *ReplicatedDatabase) Close() error {
+	r.cancel()
+	<-r.done
+
+	var errs []error
+	for _, rep := range r.replicas {
+		if err := rep.db.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := r.primary.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// read выполняет запрос на следующей доступной реплике или, если
+// usePrimary или доступных реплик нет, на primary. Ошибка реплики
+// (кроме отсутствия записи) исключает ее, и запрос повторяется на primary.
+func (r *ReplicatedDatabase) read(usePrimary bool, query func(db interfaces.Database) error) error {
+	var rep *replica
+	if !usePrimary {
+		rep = r.pick()
+	}
+	if rep == nil {
+		return query(r.primary)
+	}
+
+	err := query(rep.db)
+	if err == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	if rep.healthy.CompareAndSwap(true, false) {
+		log.Printf("Ошибка чтения с реплики, она исключена до следующей проверки: %v", err)
+	}
+	return query(r.primary)
+}
+
+// pick выбирает следующую по кругу доступную реплику или nil
+func (r *ReplicatedDatabase) pick() *replica {
+	n := uint64(len(r.replicas))
+	if n == 0 {
+		return nil
+	}
+
+	start := r.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		if rep := r.replicas[(start+i)%n]; rep.healthy.Load() {
+			return rep
+		}
+	}
+	return nil
+}
+
+// recordWrite запоминает время записи заказа для ReadAfterWrite
+func (r *ReplicatedDatabase) recordWrite(orderUID string) {
+	if r.opts.ReadAfterWrite <= 0 || len(r.replicas) == 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.written[orderUID] = now
+	r.lastWrite = now
+
+	// Устаревшие записи удаляем не чаще раза в окно
+	if now.Sub(r.lastPrune) < r.opts.ReadAfterWrite {
+		return
+	}
+	for uid, at := range r.written {
+		if now.Sub(at) >= r.opts.ReadAfterWrite {
+			delete(r.written, uid)
+		}
+	}
+	r.lastPrune = now
+}
+
+// writtenRecently сообщает, записан ли какой-либо из заказов orderUIDs
+// в течение ReadAfterWrite. Без UID проверяется любая запись.
+func (r *ReplicatedDatabase) writtenRecently(orderUIDs ...string) bool {
+	if r.opts.ReadAfterWrite <= 0 {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if len(orderUIDs) == 0 {
+		return now.Sub(r.lastWrite) < r.opts.ReadAfterWrite
+	}
+	for _, uid := range orderUIDs {
+		if at, ok := r.written[uid]; ok && now.Sub(at) < r.opts.ReadAfterWrite {
+			return true
+		}
+	}
+	return false
+}
+
+// checkReplicas проверяет реплики сразу и затем каждые CheckInterval
+func (r *ReplicatedDatabase) checkReplicas(ctx context.Context) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.opts.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		for i, rep := range r.replicas {
+			r.checkReplica(ctx, i, rep)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkReplica проверяет одну реплику и логирует изменение ее состояния
+func (r *ReplicatedDatabase) checkReplica(ctx context.Context, n int, rep *replica) {
+	p, ok := rep.db.(pinger)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.opts.CheckInterval)
+	defer cancel()
+
+	err := p.Ping(ctx)
+	if errors.Is(err, context.Canceled) {
+		// Проверка прервана остановкой, состояние не меняем
+		return
+	}
+
+	healthy := err == nil
+	if rep.healthy.Swap(healthy) != healthy {
+		if healthy {
+			log.Printf("Реплика %d снова доступна", n+1)
+		} else {
+			log.Printf("Реплика %d недоступна: %v", n+1, err)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+	"wb-service/internal/interfaces"
+
+	"gorm.io/gorm"
+)
+
+// flakyReplica - реплика, доступностью которой управляет тест
+type flakyReplica struct {
+	interfaces.Database
+
+	mu  sync.Mutex
+	err error
+}
+
+func (f *flakyReplica) Ping(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.err
+}
+
+func (f *flakyReplica) setErr(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+// setupReplicated создает primary и n реплик в отдельных SQLite базах
+func setupReplicated(t *testing.T, n int, opts ReplicaOptions) (*ReplicatedDatabase, *gorm.DB, []*gorm.DB) {
+	t.Helper()
+
+	primary := openSQLite(t)
+	var replicaDBs []*gorm.DB
+	var replicas []interfaces.Database
+	for i := 0; i < n; i++ {
+		db := openSQLite(t)
+		replicaDBs = append(replicaDBs, db)
+		replicas = append(replicas, NewGormDatabase(db))
+	}
+
+	r := NewReplicatedDatabase(context.Background(), NewGormDatabase(primary), replicas, opts)
+	t.Cleanup(func() { r.Close() })
+	return r, primary, replicaDBs
+}
+
+// waitHealthy ждет, пока число доступных реплик не станет равным want
+func waitHealthy(t *testing.T, r *ReplicatedDatabase, want int) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for r.HealthyReplicas() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d healthy replicas, got %d", want, r.HealthyReplicas())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestReplicatedDatabase_ReadsFromReplicas(t *testing.T) {
+	r, primary, replicas := setupReplicated(t, 2, ReplicaOptions{})
+
+	// Заказ есть только на первой реплике: чтения чередуются между репликами
+	order := createTestOrder()
+	if err := replicas[0].Create(order).Error; err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+
+	found := 0
+	for i := 0; i < 4; i++ {
+		_, err := r.GetOrder(order.OrderUID)
+		switch {
+		case err == nil:
+			found++
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			t.Fatalf("GetOrder failed: %v", err)
+		}
+	}
+	if found != 2 {
+		t.Errorf("Expected round-robin to hit the replica with the order 2 times, got %d", found)
+	}
+
+	// Заказ только на primary с реплик не читается
+	other := createTestOrder()
+	if err := primary.Create(other).Error; err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+	if orders, err := r.GetOrdersByUIDs([]string{other.OrderUID}); err != nil || len(orders) != 0 {
+		t.Errorf("Expected no orders from replicas, got %v, %v", orders, err)
+	}
+}
+
+func TestReplicatedDatabase_WritesToPrimary(t *testing.T) {
+	r, primary, replicas := setupReplicated(t, 1, ReplicaOptions{})
+
+	order := createTestOrder()
+	if err := r.CreateOrder(order); err != nil {
+		t.Fatalf("CreateOrder failed: %v", err)
+	}
+
+	var count int64
+	primary.Table("orders").Where("order_uid = ?", order.OrderUID).Count(&count)
+	if count != 1 {
+		t.Errorf("Expected order on primary, got %d", count)
+	}
+	if _, err := r.GetOrder(order.OrderUID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Expected read from replica without the order, got %v", err)
+	}
+
+	replicas[0].Table("orders").Count(&count)
+	if count != 0 {
+		t.Errorf("Expected no orders on replica, got %d", count)
+	}
+}
+
+func TestReplicatedDatabase_ReadAfterWrite(t *testing.T) {
+	r, _, replicas := setupReplicated(t, 1, ReplicaOptions{ReadAfterWrite: time.Minute})
+
+	written := createTestOrder()
+	if err := r.CreateOrder(written); err != nil {
+		t.Fatalf("CreateOrder failed: %v", err)
+	}
+
+	// Только что записанный заказ читается с primary
+	if _, err := r.GetOrder(written.OrderUID); err != nil {
+		t.Errorf("Expected written order to be read from primary: %v", err)
+	}
+	if orders, err := r.GetRecentOrders(interfaces.OrderCursor{}, 10); err != nil || len(orders) != 1 {
+		t.Errorf("Expected lists to be read from primary after write, got %v, %v", orders, err)
+	}
+
+	// Остальные заказы по-прежнему читаются с реплики
+	other := createTestOrder()
+	if err := replicas[0].Create(other).Error; err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+	if _, err := r.GetOrder(other.OrderUID); err != nil {
+		t.Errorf("Expected other order to be read from replica: %v", err)
+	}
+}
+
+func TestReplicatedDatabase_ReadAfterWriteExpires(t *testing.T) {
+	r, _, _ := setupReplicated(t, 1, ReplicaOptions{ReadAfterWrite: 20 * time.Millisecond})
+
+	order := createTestOrder()
+	if err := r.CreateOrder(order); err != nil {
+		t.Fatalf("CreateOrder failed: %v", err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	if _, err := r.GetOrder(order.OrderUID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Expected read from replica after the window, got %v", err)
+	}
+}
+
+func TestReplicatedDatabase_HealthCheck(t *testing.T) {
+	primary := openSQLite(t)
+	replicaDB := openSQLite(t)
+	flaky := &flakyReplica{Database: NewGormDatabase(replicaDB)}
+
+	order := createTestOrder()
+	if err := replicaDB.Create(order).Error; err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+
+	r := NewReplicatedDatabase(context.Background(), NewGormDatabase(primary),
+		[]interfaces.Database{flaky}, ReplicaOptions{CheckInterval: 10 * time.Millisecond})
+	defer r.Close()
+
+	// Недоступная реплика исключается, чтение идет с primary
+	flaky.setErr(errors.New("replica is down"))
+	waitHealthy(t, r, 0)
+	if _, err := r.GetOrder(order.OrderUID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Expected read from primary, got %v", err)
+	}
+
+	// После восстановления реплика снова используется
+	flaky.setErr(nil)
+	waitHealthy(t, r, 1)
+	if _, err := r.GetOrder(order.OrderUID); err != nil {
+		t.Errorf("Expected read from replica: %v", err)
+	}
+}
+
+func TestReplicatedDatabase_FallbackOnError(t *testing.T) {
+	r, primary, replicas := setupReplicated(t, 1, ReplicaOptions{CheckInterval: time.Hour})
+
+	order := createTestOrder()
+	if err := primary.Create(order).Error; err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+
+	// Запрос к закрытой реплике завершается ошибкой и повторяется на primary
+	sqlDB, _ := replicas[0].DB()
+	sqlDB.Close()
+
+	if _, err := r.GetOrder(order.OrderUID); err != nil {
+		t.Errorf("Expected fallback to primary: %v", err)
+	}
+	if r.HealthyReplicas() != 0 {
+		t.Error("Expected failed replica to be excluded")
+	}
+}
+
+func TestReplicatedDatabase_Close(t *testing.T) {
+	primary := openSQLite(t)
+	replicaDB := openSQLite(t)
+
+	r := NewReplicatedDatabase(context.Background(), NewGormDatabase(primary),
+		[]interfaces.Database{NewGormDatabase(replicaDB)}, ReplicaOptions{})
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	for _, db := range []*gorm.DB{primary, replicaDB} {
+		sqlDB, _ := db.DB()
+		if err := sqlDB.Ping(); err == nil {
+			t.Error("Expected connections to be closed")
+		}
+	}
+}
@@ -2,60 +2,218 @@ package kafka
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"log"
+	"strings"
 	"time"
 	"wb-service/config"
-	"wb-service/database"
 	"wb-service/internal/cache"
+	"wb-service/internal/codec"
 	"wb-service/internal/interfaces"
-	"wb-service/internal/validator"
+	"wb-service/internal/pii"
 	"wb-service/models"
 
+	"github.com/redis/go-redis/v9"
 	"github.com/segmentio/kafka-go"
 )
 
-// OrderCache - кэш для хранения заказов в памяти.
-var OrderCache interfaces.Cache
+// HeaderContentType - заголовок сообщения Kafka с форматом тела
+const HeaderContentType = codec.HeaderContentType
 
-// InitCache инициализирует кэш
-func InitCache(cfg *config.Config) {
-	ttl := time.Duration(cfg.Cache.TTL) * time.Second
-	OrderCache = cache.NewLRUCache(cfg.Cache.MaxSize, ttl)
+// Повторы сохранения заказа при ошибке БД: saveAttempts попыток, задержка
+// между ними начинается с saveBackoff и удваивается
+const (
+	saveAttempts = 3
+	saveBackoff  = 250 * time.Millisecond
+)
+
+// NewCache создает кэш заказов по конфигурации. Если Redis недоступен,
+// используется кэш в памяти процесса.
+func NewCache(cfg *config.Config) interfaces.Cache {
+	opts := cache.Options{
+		Capacity: cfg.Cache.MaxSize,
+		MaxBytes: cfg.Cache.MaxBytes,
+		TTL:      time.Duration(cfg.Cache.TTL) * time.Second,
+		Policy:   cfg.Cache.Policy,
+
+		Expiration:      strings.ToLower(cfg.Cache.Expiration),
+		CleanupInterval: time.Duration(cfg.Cache.CleanupInterval) * time.Second,
+
+		NegativeTTL: time.Duration(cfg.Cache.NegativeTTL) * time.Second,
+	}
+
+	if _, err := cache.NewPolicy(opts.Policy, opts.Capacity); err != nil {
+		log.Printf("Неизвестная политика кэша %q, используется LRU", opts.Policy)
+		opts.Policy = cache.PolicyLRU
+	}
+
+	switch opts.Expiration {
+	case cache.ExpirationAbsolute, cache.ExpirationSliding:
+	case "":
+		opts.Expiration = cache.ExpirationAbsolute
+	default:
+		log.Printf("Неизвестный режим истечения кэша %q, используется absolute", cfg.Cache.Expiration)
+		opts.Expiration = cache.ExpirationAbsolute
+	}
+
+	switch backend := strings.ToLower(cfg.Cache.Backend); backend {
+	case cache.BackendRedis, cache.BackendTiered:
+		c, err := newRedisCache(cfg, opts, backend == cache.BackendTiered)
+		if err == nil {
+			return c
+		}
+		log.Printf("Redis недоступен (%v), используется кэш в памяти", err)
+	case cache.BackendMemory, "":
+	default:
+		log.Printf("Неизвестный тип кэша %q, используется кэш в памяти", cfg.Cache.Backend)
+	}
+
+	return newLocalCache(cfg, opts)
 }
 
-// LoadCacheFromDB загружает все заказы из базы данных в кэш
-func LoadCacheFromDB(db interfaces.Database) error {
-	if OrderCache == nil {
-		log.Println("Кэш не инициализирован")
-		return nil
+// newLocalCache создает кэш в памяти процесса
+func newLocalCache(cfg *config.Config, opts cache.Options) interfaces.Cache {
+	if cfg.Cache.Shards > 1 {
+		return cache.NewShardedCache(context.Background(), cfg.Cache.Shards, opts)
+	}
+	return cache.NewLRUCacheWithOptions(context.Background(), opts)
+}
+
+// newRedisCache подключается к Redis и создает кэш в Redis, а при
+// tiered - двухуровневый кэш с локальным кэшем перед Redis
+func newRedisCache(cfg *config.Config, opts cache.Options, tiered bool) (interfaces.Cache, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Addr,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
 	}
 
-	if err := OrderCache.LoadFromDB(db); err != nil {
+	l2, err := cache.NewRedisCache(client, cache.RedisOptions{
+		Prefix:      cfg.Redis.KeyPrefix,
+		TTL:         opts.TTL,
+		Format:      cfg.Redis.Format,
+		Capacity:    opts.Capacity,
+		NegativeTTL: opts.NegativeTTL,
+	})
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	if !tiered {
+		log.Printf("Кэш заказов хранится в Redis %s", cfg.Redis.Addr)
+		return l2, nil
+	}
+
+	l1 := newLocalCache(cfg, opts)
+	tieredCache, err := cache.NewTieredCache(l1, l2, cfg.Redis.Channel, opts)
+	if err != nil {
+		l1.Close()
+		l2.Close()
+		return nil, err
+	}
+
+	log.Printf("Двухуровневый кэш: память + Redis %s", cfg.Redis.Addr)
+	return tieredCache, nil
+}
+
+// LoadCacheFromDB загружает все заказы из базы данных в кэш
+func LoadCacheFromDB(c interfaces.Cache, db interfaces.Database) error {
+	if err := c.LoadFromDB(db); err != nil {
 		log.Printf("Ошибка при загрузке кэша из БД: %v", err)
 		return err
 	}
 
-	log.Printf("Кэш успешно загружен. %d записей.", OrderCache.Size())
+	log.Printf("Кэш успешно загружен. %d записей.", c.Size())
 	return nil
 }
 
-// StartConsumer запускает процесс прослушивания топика Kafka.
-func StartConsumer(cfg *config.Config, ctx context.Context) {
-	r := kafka.NewReader(kafka.ReaderConfig{
-		Brokers:        cfg.Kafka.Brokers,
-		Topic:          cfg.Kafka.Topic,
-		GroupID:        cfg.Kafka.GroupID,
-		MinBytes:       cfg.Kafka.MinBytes,
-		MaxBytes:       cfg.Kafka.MaxBytes,
+// Consumer читает заказы из топика Kafka, сохраняет их в БД и добавляет в кэш
+type Consumer struct {
+	cfg       config.KafkaConfig
+	db        interfaces.Database
+	cache     interfaces.Cache
+	validator interfaces.OrderValidator
+	decoder   *codec.Selector
+	backoff   time.Duration
+
+	reader *kafka.Reader
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewConsumer создает Consumer. Декодер выбирается по content-type
+// сообщения, иначе по KAFKA_MESSAGE_FORMAT. Если db равен nil, заказы
+// только добавляются в кэш.
+func NewConsumer(cfg config.KafkaConfig, db interfaces.Database, c interfaces.Cache, v interfaces.OrderValidator) (*Consumer, error) {
+	decoder, err := codec.NewSelector(cfg.MessageFormat)
+	if err != nil {
+		return nil, fmt.Errorf("message format %q: %w", cfg.MessageFormat, err)
+	}
+
+	return &Consumer{
+		cfg:       cfg,
+		db:        db,
+		cache:     c,
+		validator: v,
+		decoder:   decoder,
+		backoff:   saveBackoff,
+	}, nil
+}
+
+// Start подключается к Kafka и обрабатывает сообщения в отдельной горутине
+// до вызова Stop или отмены ctx
+func (c *Consumer) Start(ctx context.Context) error {
+	if c.reader != nil {
+		return errors.New("consumer already started")
+	}
+
+	c.reader = kafka.NewReader(kafka.ReaderConfig{
+		Brokers:        c.cfg.Brokers,
+		Topic:          c.cfg.Topic,
+		GroupID:        c.cfg.GroupID,
+		MinBytes:       c.cfg.MinBytes,
+		MaxBytes:       c.cfg.MaxBytes,
 		CommitInterval: time.Second,
 	})
-	defer r.Close()
 
-	// Создаем валидатор
-	orderValidator := validator.NewOrderValidator()
+	ctx, c.cancel = context.WithCancel(ctx)
+	c.done = make(chan struct{})
+	go c.run(ctx)
 
 	log.Println("Kafka Consumer запущен...")
+	return nil
+}
+
+// Stop останавливает обработку, дожидается текущего сообщения
+// и закрывает подключение к Kafka
+func (c *Consumer) Stop() error {
+	if c.reader == nil {
+		return nil
+	}
+
+	c.cancel()
+	<-c.done
+
+	err := c.reader.Close()
+	c.reader = nil
+	log.Println("Kafka Consumer остановлен")
+	return err
+}
+
+// run читает сообщения до отмены ctx
+func (c *Consumer) run(ctx context.Context) {
+	defer close(c.done)
 
 	for {
 		select {
@@ -65,47 +223,114 @@ func StartConsumer(cfg *config.Config, ctx context.Context) {
 		default:
 			// Устанавливаем таймаут для чтения сообщения
 			msgCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
-			m, err := r.FetchMessage(msgCtx)
+			m, err := c.reader.FetchMessage(msgCtx)
 			cancel()
 
 			if err != nil {
-				if err == context.DeadlineExceeded || err == context.Canceled {
+				if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
 					continue
 				}
 				log.Printf("ошибка при чтении сообщения: %v", err)
 				continue
 			}
 
-			var order models.Order
-			if err := json.Unmarshal(m.Value, &order); err != nil {
-				log.Printf("Ошибка десериализации JSON: %v. Сообщение: %s", err, string(m.Value))
-				// Коммитим сообщение даже если не смогли его распарсить
-				r.CommitMessages(context.Background(), m)
+			if !c.Handle(ctx, m) {
 				continue
 			}
 
-			// Валидируем заказ
-			if err := orderValidator.Validate(&order); err != nil {
-				log.Printf("Ошибка валидации заказа %s: %v", order.OrderUID, err)
-				// Коммитим сообщение даже если валидация не прошла
-				r.CommitMessages(context.Background(), m)
-				continue
+			// Коммитим сообщение после обработки
+			if err := c.reader.CommitMessages(context.Background(), m); err != nil {
+				log.Printf("Ошибка коммита сообщения: %v", err)
 			}
+		}
+	}
+}
 
-			if result := database.DB.Create(&order); result.Error != nil {
-				log.Printf("Ошибка сохранения заказа в БД: %v", result.Error)
-				continue
-			}
+// Handle обрабатывает одно сообщение и сообщает, нужно ли его коммитить.
+// Некорректные сообщения коммитятся, чтобы не читать их повторно. При ошибке
+// БД сохранение повторяется несколько раз, а затем сообщение тоже коммитится:
+// следующее успешное сообщение все равно закоммитило бы более позднее
+// смещение, поэтому заказ теряется и это пишется в лог. Не коммитится только
+// сообщение, обработку которого прервала отмена ctx: после перезапуска оно
+// будет прочитано снова.
+func (c *Consumer) Handle(ctx context.Context, m kafka.Message) bool {
+	order, err := c.decoder.Decode(contentType(m), m.Value)
+	if err != nil {
+		// Тело сообщения не логируется: в protobuf и Avro pii.Sanitize не
+		// находит персональные данные. Сообщение в топике ищется по смещению
+		// и хэшу.
+		logSanitized("Ошибка десериализации сообщения (partition %d, offset %d, content-type %q, %d байт, sha256 %s): %v",
+			m.Partition, m.Offset, contentType(m), len(m.Value), digest(m.Value), err)
+		return true
+	}
 
-			// Добавляем в кэш
-			OrderCache.Set(order.OrderUID, &order)
+	// Валидируем заказ
+	if err := c.validator.Validate(order); err != nil {
+		logSanitized("Ошибка валидации заказа %s: %v", order.OrderUID, err)
+		return true
+	}
 
-			// Коммитим сообщение после успешной обработки
-			if err := r.CommitMessages(context.Background(), m); err != nil {
-				log.Printf("Ошибка коммита сообщения: %v", err)
-			} else {
-				log.Printf("Заказ %s успешно обработан и сохранен.", order.OrderUID)
+	if c.db != nil {
+		if err := c.save(ctx, order); err != nil {
+			if ctx.Err() != nil {
+				return false
 			}
+			logSanitized("Заказ %s не сохранен в БД и пропущен: %v", order.OrderUID, err)
+			return true
 		}
 	}
+
+	// Добавляем в кэш
+	c.cache.Set(order.OrderUID, order)
+
+	if c.db == nil {
+		log.Printf("Заказ %s добавлен в кэш без сохранения в БД.", order.OrderUID)
+		return true
+	}
+	log.Printf("Заказ %s успешно обработан и сохранен.", order.OrderUID)
+	return true
+}
+
+// save сохраняет заказ в БД, повторяя попытку saveAttempts раз с растущей
+// задержкой. Ожидание прерывается отменой ctx.
+func (c *Consumer) save(ctx context.Context, order *models.Order) error {
+	backoff := c.backoff
+	for n := 1; ; n++ {
+		err := c.db.CreateOrder(order)
+		if err == nil || n == saveAttempts {
+			return err
+		}
+
+		logSanitized("Ошибка сохранения заказа %s в БД (попытка %d): %v, повтор через %v", order.OrderUID, n, err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// contentType возвращает значение заголовка content-type сообщения
+func contentType(m kafka.Message) string {
+	for _, h := range m.Headers {
+		if strings.EqualFold(h.Key, HeaderContentType) {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// digest возвращает начало SHA-256 тела сообщения для поиска его в логах
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// logSanitized пишет в лог сообщение об ошибке обработки заказа, убрав из
+// него персональные данные: их может содержать и тело сообщения, и ошибка
+// валидации или БД
+func logSanitized(format string, args ...any) {
+	log.Print(pii.Sanitize(fmt.Sprintf(format, args...)))
 }
@@ -0,0 +1,132 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log"
+	"time"
+	"wb-service/config"
+	"wb-service/internal/cache"
+	"wb-service/internal/interfaces"
+)
+
+// StartCacheWarmup запускает фоновый прогрев кэша: сначала заказы из файла
+// горячих ключей, затем самые свежие заказы из БД. Не блокирует вызывающего.
+func StartCacheWarmup(ctx context.Context, c interfaces.Cache, db interfaces.Database, cfg *config.Config) *cache.Warmer {
+	var hotKeys []string
+	if path := cfg.Cache.HotKeysFile; path != "" {
+		keys, err := cache.LoadHotKeys(path)
+		if err != nil {
+			log.Printf("Ошибка чтения горячих ключей из %s: %v", path, err)
+		}
+		hotKeys = keys
+	}
+
+	warmer := cache.NewWarmer(c, db, cache.WarmupOptions{
+		Limit:    cfg.Cache.WarmupLimit,
+		PageSize: cfg.Cache.WarmupPageSize,
+		HotKeys:  hotKeys,
+	})
+
+	go warmer.Run(ctx)
+	return warmer
+}
+
+// SaveHotKeys сохраняет самые ценные ключи кэша в файл горячих ключей,
+// чтобы следующий запуск прогрел их первыми
+func SaveHotKeys(c interfaces.Cache, cfg *config.Config) {
+	path := cfg.Cache.HotKeysFile
+	if path == "" {
+		return
+	}
+
+	limit := cfg.Cache.WarmupLimit
+	if limit <= 0 {
+		limit = cfg.Cache.MaxSize
+	}
+
+	keys := c.HotKeys(limit)
+	if err := cache.SaveHotKeys(path, keys); err != nil {
+		log.Printf("Ошибка сохранения горячих ключей в %s: %v", path, err)
+		return
+	}
+
+	log.Printf("Сохранено %d горячих ключей в %s", len(keys), path)
+}
+
+// RestoreCacheSnapshot восстанавливает кэш из файла снимка. Возвращает false,
+// если снимок не настроен, отсутствует, устарел или поврежден - тогда кэш
+// нужно прогреть из БД.
+func RestoreCacheSnapshot(c interfaces.Cache, cfg *config.Config) bool {
+	path := cfg.Cache.SnapshotFile
+	if path == "" {
+		return false
+	}
+
+	snapshotter, ok := c.(interfaces.CacheSnapshotter)
+	if !ok {
+		log.Printf("Кэш %T не поддерживает снимки", c)
+		return false
+	}
+
+	maxAge := time.Duration(cfg.Cache.SnapshotMaxAge) * time.Second
+	entries, created, err := cache.LoadSnapshot(path, maxAge)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		log.Printf("Снимок кэша %s не найден", path)
+		return false
+	case err != nil:
+		log.Printf("Снимок кэша %s не используется: %v", path, err)
+		return false
+	}
+
+	restored := snapshotter.Restore(entries)
+	log.Printf("Кэш восстановлен из снимка %s от %s: %d из %d записей",
+		path, created.Format(time.RFC3339), restored, len(entries))
+	return true
+}
+
+// SaveCacheSnapshot сохраняет содержимое кэша в файл снимка
+func SaveCacheSnapshot(c interfaces.Cache, cfg *config.Config) error {
+	path := cfg.Cache.SnapshotFile
+	if path == "" {
+		return nil
+	}
+
+	snapshotter, ok := c.(interfaces.CacheSnapshotter)
+	if !ok {
+		return nil
+	}
+
+	entries := snapshotter.Entries()
+	if err := cache.SaveSnapshot(path, entries); err != nil {
+		log.Printf("Ошибка сохранения снимка кэша в %s: %v", path, err)
+		return err
+	}
+
+	log.Printf("Снимок кэша сохранен в %s: %d записей", path, len(entries))
+	return nil
+}
+
+// StartCacheSnapshots периодически сохраняет снимок кэша до отмены ctx
+func StartCacheSnapshots(ctx context.Context, c interfaces.Cache, cfg *config.Config) {
+	interval := time.Duration(cfg.Cache.SnapshotInterval) * time.Second
+	if cfg.Cache.SnapshotFile == "" || interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				SaveCacheSnapshot(c, cfg)
+			}
+		}
+	}()
+}
@@ -0,0 +1,95 @@
+package kafka
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+	"wb-service/config"
+	"wb-service/internal/cache"
+	"wb-service/models"
+)
+
+func TestCacheWarmupWithHotKeys(t *testing.T) {
+	repo := setupTestDB(t)
+
+	hot := createTestOrderForKafka()
+	hot.OrderUID = "hot_order"
+	hot.DateCreated = time.Now().Add(-24 * time.Hour)
+	recent := createTestOrderForKafka()
+	recent.OrderUID = "recent_order"
+	for _, order := range []*models.Order{hot, recent} {
+		if err := repo.CreateOrder(order); err != nil {
+			t.Fatalf("Failed to create order: %v", err)
+		}
+	}
+
+	cfg := &config.Config{
+		Cache: config.CacheConfig{
+			MaxSize:     10,
+			TTL:         3600,
+			HotKeysFile: filepath.Join(t.TempDir(), "hot_keys.txt"),
+		},
+	}
+	orderCache := NewCache(cfg)
+	defer orderCache.Close()
+
+	// Горячие ключи сохраняются при остановке и загружаются первыми при запуске
+	orderCache.Set(hot.OrderUID, hot)
+	SaveHotKeys(orderCache, cfg)
+	orderCache.Clear()
+
+	warmer := StartCacheWarmup(context.Background(), orderCache, repo, cfg)
+	if warmer == nil {
+		t.Fatal("Expected warmer to be started")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for warmer.Progress().State != cache.WarmupDone {
+		if time.Now().After(deadline) {
+			t.Fatalf("Warmup did not finish: %+v", warmer.Progress())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	progress := warmer.Progress()
+	if progress.HotKeys != 1 || progress.Loaded != 2 {
+		t.Errorf("Unexpected progress: %+v", progress)
+	}
+	if _, found := orderCache.Get(recent.OrderUID); !found {
+		t.Error("Expected recent order to be warmed")
+	}
+}
+
+func TestCacheSnapshotRestore(t *testing.T) {
+	cfg := &config.Config{
+		Cache: config.CacheConfig{
+			MaxSize:        10,
+			TTL:            3600,
+			SnapshotFile:   filepath.Join(t.TempDir(), "cache.snapshot"),
+			SnapshotMaxAge: 3600,
+		},
+	}
+	orderCache := NewCache(cfg)
+	defer orderCache.Close()
+
+	if RestoreCacheSnapshot(orderCache, cfg) {
+		t.Fatal("Expected restore to fail without snapshot file")
+	}
+
+	order := createTestOrderForKafka()
+	orderCache.Set(order.OrderUID, order)
+	if err := SaveCacheSnapshot(orderCache, cfg); err != nil {
+		t.Fatalf("SaveCacheSnapshot failed: %v", err)
+	}
+
+	restored := NewCache(cfg)
+	defer restored.Close()
+
+	if !RestoreCacheSnapshot(restored, cfg) {
+		t.Fatal("Expected cache to be restored from snapshot")
+	}
+	if _, found := restored.Get(order.OrderUID); !found {
+		t.Error("Expected order to be restored from snapshot")
+	}
+}
@@ -12,56 +12,72 @@ import (
 	"time"
 	"wb-service/config"
 	"wb-service/database"
-	"wb-service/internal/repository"
-	"wb-service/kafka"
+	"wb-service/internal/apierror"
+	"wb-service/internal/auth"
+	"wb-service/internal/interfaces"
+	"wb-service/internal/pii"
 	"wb-service/models"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+// errDatabaseNotInitialized возвращается загрузчиком заказа, если БД не подключена
+var errDatabaseNotInitialized = errors.New("database not initialized")
+
 // getOrder обрабатывает запрос на получение заказа по его UID
-func getOrder(c *gin.Context) {
+func (a *App) getOrder(c *gin.Context) {
 	orderUID := c.Param("order_uid")
 
-	// --- Новая логика: сначала ищем в кэше ---
-	if order, found := kafka.OrderCache.Get(orderUID); found {
-		log.Printf("Заказ %s НАЙДЕН В КЭШЕ!", orderUID)
-		c.JSON(http.StatusOK, order)
+	// Ищем в кэше, при промахе кэш сам загрузит заказ из БД. Одновременные
+	// запросы одного заказа выполняют один запрос к БД, а ненайденные
+	// заказы какое-то время не запрашиваются повторно.
+	order, err := a.cache.GetOrLoad(orderUID, a.loadOrderFromDB)
+	if err != nil {
+		switch {
+		case errors.Is(err, interfaces.ErrNotFound):
+			apierror.AbortWithDetails(c, http.StatusNotFound, apierror.CodeNotFound, "record not found",
+				gin.H{"order_uid": orderUID})
+		case errors.Is(err, errDatabaseNotInitialized):
+			apierror.Abort(c, http.StatusInternalServerError, apierror.CodeDatabaseUnavailable, "database not initialized")
+		default:
+			apierror.Abort(c, http.StatusInternalServerError, apierror.CodeDatabaseError, "database error")
+		}
 		return
 	}
 
-	// --- Старая логика: если в кэше нет, идем в БД ---
+	// Отправляем найденный заказ в виде JSON, скрыв персональные данные
+	// по политике роли или API ключа клиента. Если у клиента уже есть
+	// такой ответ (If-None-Match), тело не отправляется.
+	a.writeOrder(c, pii.MaskOrder(order, a.piiPolicy(c)))
+}
+
+// piiPolicy возвращает политику маскирования для запроса по роли, которую
+// записала аутентификация, и API ключу
+func (a *App) piiPolicy(c *gin.Context) pii.Policy {
+	return a.policies.For(c.GetString(auth.RoleContextKey), c.GetHeader(auth.APIKeyHeader))
+}
+
+// loadOrderFromDB загружает заказ из БД при промахе кэша
+func (a *App) loadOrderFromDB(orderUID string) (*models.Order, error) {
 	log.Printf("Заказ %s не найден в кэше, ищем в БД...", orderUID)
 
-	// Проверяем, что БД инициализирована
-	if database.DB == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "database not initialized"})
-		return
+	// Проверяем, что БД подключена
+	if a.repo == nil {
+		return nil, errDatabaseNotInitialized
 	}
 
-	var order models.Order
-	result := database.DB.
-		Preload("Delivery").
-		Preload("Payment").
-		Preload("Items").
-		First(&order, "order_uid = ?", orderUID)
-
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "record not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+	order, err := a.repo.GetOrder(orderUID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, interfaces.ErrNotFound
 		}
-		return
+		log.Printf("Ошибка загрузки заказа %s из БД: %v", orderUID, err)
+		return nil, err
 	}
 
-	// --- Новое: добавляем в кэш то, что нашли в БД ---
 	log.Printf("Заказ %s найден в БД, добавляем в кэш...", orderUID)
-	kafka.OrderCache.Set(order.OrderUID, &order)
-
-	// Отправляем найденный заказ в виде JSON
-	c.JSON(http.StatusOK, order)
+	return order, nil
 }
 
 func main() {
@@ -70,80 +86,48 @@ func main() {
 	// Загружаем конфигурацию
 	cfg := config.Load()
 
-	// Инициализируем подключение к базе данных
-	database.Init(cfg)
-
-	// Инициализируем кэш
-	kafka.InitCache(cfg)
-
-	// Создаем репозиторий для работы с базой данных
-	dbRepo := repository.NewGormDatabase(database.DB)
-
-	// Загружаем кэш из базы данных при старте
-	if err := kafka.LoadCacheFromDB(dbRepo); err != nil {
-		log.Printf("Ошибка загрузки кэша: %v", err)
+	// Подкоманда migrate управляет схемой БД и не запускает сервис
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(cfg, os.Args[2:]); err != nil {
+			log.Fatalf("Ошибка миграции: %v", err)
+		}
+		return
 	}
 
-	// Создаем контекст для graceful shutdown
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	// Запускаем Kafka Consumer в отдельной горутине
-	go kafka.StartConsumer(cfg, ctx)
+	// Сигнал завершения прерывает и ожидание базы данных при запуске
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	r := gin.Default()
-
-	// Добавляем маршрут для получения заказа
-	r.GET("/order/:order_uid", getOrder)
-
-	// Добавляем health check endpoint
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "ok"})
-	})
+	// Подключаемся к базе данных, дожидаясь ее готовности
+	db, err := database.Open(ctx, cfg)
+	if err != nil {
+		log.Fatalf("Ошибка подключения к базе данных: %v", err)
+	}
 
-	// Добавляем маршрут для отдачи нашей веб-страницы
-	r.StaticFile("/", "./web/index.html")
+	// Проверяем, что схема БД соответствует версии приложения
+	if err := checkSchema(cfg, db); err != nil {
+		log.Fatalf("Схема базы данных не готова: %v", err)
+	}
 
-	// Создаем HTTP сервер
-	serverAddr := cfg.Server.Host + ":" + cfg.Server.Port
-	srv := &http.Server{
-		Addr:    serverAddr,
-		Handler: r,
+	app, err := NewApp(cfg, db)
+	if err != nil {
+		log.Fatalf("Ошибка инициализации сервиса: %v", err)
 	}
 
-	// Запускаем сервер в отдельной горутине
-	go func() {
-		log.Printf("Сервер запускается на %s", serverAddr)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Ошибка запуска сервера: %v", err)
-		}
-	}()
+	if err := app.Start(context.Background()); err != nil {
+		log.Fatalf("Ошибка запуска сервиса: %v", err)
+	}
 
-	// Создаем канал для получения сигналов ОС
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	<-ctx.Done()
 
 	log.Println("Получен сигнал завершения, начинается graceful shutdown...")
 
-	// Отменяем контекст для остановки consumer
-	cancel()
-
-	// Создаем контекст с таймаутом для graceful shutdown сервера
-	ctx, cancel = context.WithTimeout(context.Background(), 30*time.Second)
+	// Создаем контекст с таймаутом для graceful shutdown
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	// Останавливаем HTTP сервер
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Printf("Ошибка graceful shutdown сервера: %v", err)
-	} else {
-		log.Println("HTTP сервер успешно остановлен")
-	}
-
-	// Закрываем соединение с базой данных
-	if sqlDB, err := database.DB.DB(); err == nil {
-		sqlDB.Close()
-		log.Println("Соединение с базой данных закрыто")
+	if err := app.Stop(shutdownCtx); err != nil {
+		log.Printf("Ошибки при остановке сервиса: %v", err)
 	}
 
 	log.Println("Сервис успешно завершен")
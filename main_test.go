@@ -1,14 +1,17 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"sync"
 	"testing"
 	"time"
-	"wb-service/database"
-	"wb-service/internal/cache"
-	"wb-service/kafka"
+	"wb-service/config"
+	"wb-service/internal/auth"
+	"wb-service/internal/pii"
 	"wb-service/models"
 
 	"github.com/gin-gonic/gin"
@@ -16,32 +19,49 @@ import (
 	"gorm.io/gorm"
 )
 
-func setupTestRouter() *gin.Engine {
+func TestMain(m *testing.M) {
 	gin.SetMode(gin.TestMode)
-	r := gin.Default()
-	r.GET("/order/:order_uid", getOrder)
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "ok"})
-	})
-	return r
+	os.Exit(m.Run())
 }
 
-func setupTestCache() {
-	kafka.OrderCache = cache.NewLRUCache(100, time.Hour)
+// testConfig возвращает конфигурацию с кэшем в памяти
+func testConfig() *config.Config {
+	return &config.Config{
+		Cache: config.CacheConfig{MaxSize: 100, TTL: 3600},
+		PII:   config.PIIConfig{DefaultPolicy: "plain"},
+	}
 }
 
-func setupTestDatabase() {
+// setupTestDatabase создает отдельную SQLite базу в памяти для теста
+func setupTestDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	if err != nil {
-		panic("failed to connect database")
+		t.Fatalf("Failed to open database: %v", err)
 	}
 
-	// Migrate the schema
-	db.AutoMigrate(&models.Order{}, &models.Delivery{}, &models.Payment{}, &models.Item{})
+	// Каждое соединение с :memory: открывает новую пустую базу
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
 
-	// Set global database for tests
-	// Note: In real app, we should inject this dependency
-	database.DB = db
+	if err := db.AutoMigrate(&models.Order{}, &models.Delivery{}, &models.Payment{}, &models.Item{}, &models.AuditRecord{}); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	return db
+}
+
+// newTestApp создает приложение для теста и останавливает его по завершении.
+// db может быть nil.
+func newTestApp(t *testing.T, cfg *config.Config, db *gorm.DB) *App {
+	t.Helper()
+
+	app, err := NewApp(cfg, db)
+	if err != nil {
+		t.Fatalf("NewApp failed: %v", err)
+	}
+	t.Cleanup(func() { app.Stop(context.Background()) })
+	return app
 }
 
 func createTestOrderForCache() *models.Order {
@@ -149,12 +169,13 @@ func createTestOrderForDB() *models.Order {
 }
 
 func TestGetOrderFromCache(t *testing.T) {
-	setupTestCache()
-	router := setupTestRouter()
+	t.Parallel()
+	app := newTestApp(t, testConfig(), nil)
+	router := app.router
 
 	// Add order to cache
 	order := createTestOrderForCache()
-	kafka.OrderCache.Set(order.OrderUID, order)
+	app.cache.Set(order.OrderUID, order)
 
 	t.Run("get order from cache successfully", func(t *testing.T) {
 		req, _ := http.NewRequest("GET", "/order/"+order.OrderUID, nil)
@@ -185,21 +206,90 @@ func TestGetOrderFromCache(t *testing.T) {
 		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
 
-		// Should return 404 since we don't have DB setup in this test
-		if w.Code != http.StatusNotFound && w.Code != http.StatusInternalServerError {
-			t.Errorf("Expected status 404 or 500, got %d", w.Code)
+		// Without a database the miss cannot be resolved
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, w.Code)
 		}
 	})
 }
 
+func TestGetOrderMasksPII(t *testing.T) {
+	t.Parallel()
+	cfg := testConfig()
+	cfg.PII = config.PIIConfig{
+		DefaultPolicy: "partial",
+		RolePolicies:  []string{"admin=plain"},
+		KeyPolicies:   []string{"ops-key=plain", "partner-key=redacted"},
+	}
+	app := newTestApp(t, cfg, nil)
+
+	order := createTestOrderForCache()
+	app.cache.Set(order.OrderUID, order)
+
+	tests := []struct {
+		name     string
+		apiKey   string
+		expected string
+	}{
+		{"default policy", "", "C*** U***"},
+		{"plain key", "ops-key", order.Delivery.Name},
+		{"redacted key", "partner-key", "***"},
+		{"unknown key", "other-key", "C*** U***"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/order/"+order.OrderUID, nil)
+			if tt.apiKey != "" {
+				req.Header.Set("X-API-Key", tt.apiKey)
+			}
+			w := httptest.NewRecorder()
+			app.router.ServeHTTP(w, req)
+
+			var response models.Order
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if response.Delivery.Name != tt.expected {
+				t.Errorf("Expected delivery name %q, got %q", tt.expected, response.Delivery.Name)
+			}
+		})
+	}
+
+	t.Run("role policy", func(t *testing.T) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("GET", "/order/"+order.OrderUID, nil)
+		c.Set(auth.RoleContextKey, "admin")
+
+		if policy := app.piiPolicy(c); policy != pii.PolicyPlain {
+			t.Errorf("Expected plain policy for admin, got %q", policy)
+		}
+	})
+
+	// Маскируется только ответ, заказ в кэше остается полным
+	if cached, _ := app.cache.Get(order.OrderUID); cached.Delivery.Name != order.Delivery.Name {
+		t.Errorf("Expected cached order to stay unmasked, got %q", cached.Delivery.Name)
+	}
+}
+
+func TestNewAppInvalidPIIConfig(t *testing.T) {
+	cfg := testConfig()
+	cfg.PII.RolePolicies = []string{"admin=everything"}
+
+	if _, err := NewApp(cfg, nil); err == nil {
+		t.Error("Expected NewApp to fail for unknown PII policy")
+	}
+}
+
 func TestGetOrderFromDatabase(t *testing.T) {
-	setupTestCache() // Empty cache
-	setupTestDatabase()
-	router := setupTestRouter()
+	t.Parallel()
+	db := setupTestDatabase(t)
+	app := newTestApp(t, testConfig(), db)
+	router := app.router
 
 	// Add order to database
 	order := createTestOrderForDB()
-	err := database.DB.Create(order).Error
+	err := db.Create(order).Error
 	if err != nil {
 		t.Fatalf("Failed to create test order in DB: %v", err)
 	}
@@ -224,7 +314,7 @@ func TestGetOrderFromDatabase(t *testing.T) {
 		}
 
 		// Verify order is now in cache
-		cachedOrder, found := kafka.OrderCache.Get(order.OrderUID)
+		cachedOrder, found := app.cache.Get(order.OrderUID)
 		if !found {
 			t.Error("Expected order to be cached after DB retrieval")
 		} else if cachedOrder.OrderUID != order.OrderUID {
@@ -234,9 +324,10 @@ func TestGetOrderFromDatabase(t *testing.T) {
 }
 
 func TestGetOrderNotFound(t *testing.T) {
-	setupTestCache() // Empty cache
-	setupTestDatabase()
-	router := setupTestRouter()
+	t.Parallel()
+	db := setupTestDatabase(t)
+	app := newTestApp(t, testConfig(), db)
+	router := app.router
 
 	t.Run("get non-existing order returns 404", func(t *testing.T) {
 		req, _ := http.NewRequest("GET", "/order/definitely_not_existing", nil)
@@ -270,8 +361,45 @@ func TestGetOrderNotFound(t *testing.T) {
 	})
 }
 
+func TestGetOrderNegativeCaching(t *testing.T) {
+	t.Parallel()
+	cfg := testConfig()
+	cfg.Cache.NegativeTTL = 60
+	app := newTestApp(t, cfg, setupTestDatabase(t))
+	router := app.router
+
+	get := func() int {
+		req, _ := http.NewRequest("GET", "/order/negative_order", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Повторный запрос ненайденного заказа не доходит до БД
+	for i := 0; i < 2; i++ {
+		if code := get(); code != http.StatusNotFound {
+			t.Fatalf("Expected status %d, got %d", http.StatusNotFound, code)
+		}
+	}
+
+	stats := app.cache.Stats()
+	if stats.Loads != 1 || stats.NegativeHits != 1 {
+		t.Errorf("Expected 1 DB load and 1 negative hit, got %+v", stats)
+	}
+
+	// Заказ, пришедший позже через Kafka, сразу становится доступен
+	order := createTestOrderForCache()
+	order.OrderUID = "negative_order"
+	app.cache.Set(order.OrderUID, order)
+
+	if code := get(); code != http.StatusOK {
+		t.Errorf("Expected status %d after order arrived, got %d", http.StatusOK, code)
+	}
+}
+
 func TestHealthEndpoint(t *testing.T) {
-	router := setupTestRouter()
+	t.Parallel()
+	router := newTestApp(t, testConfig(), nil).router
 
 	t.Run("health check returns ok", func(t *testing.T) {
 		req, _ := http.NewRequest("GET", "/health", nil)
@@ -295,14 +423,15 @@ func TestHealthEndpoint(t *testing.T) {
 }
 
 func TestCacheAndDatabaseIntegration(t *testing.T) {
-	setupTestCache()
-	setupTestDatabase()
-	router := setupTestRouter()
+	t.Parallel()
+	db := setupTestDatabase(t)
+	app := newTestApp(t, testConfig(), db)
+	router := app.router
 
 	// Create order in database only
 	order := createTestOrderForDB()
 	order.OrderUID = "integration_test_order"
-	err := database.DB.Create(order).Error
+	err := db.Create(order).Error
 	if err != nil {
 		t.Fatalf("Failed to create test order: %v", err)
 	}
@@ -318,7 +447,7 @@ func TestCacheAndDatabaseIntegration(t *testing.T) {
 		}
 
 		// Verify it's now in cache
-		_, found := kafka.OrderCache.Get(order.OrderUID)
+		_, found := app.cache.Get(order.OrderUID)
 		if !found {
 			t.Error("Expected order to be in cache after first request")
 		}
@@ -347,7 +476,8 @@ func TestCacheAndDatabaseIntegration(t *testing.T) {
 }
 
 func TestOrderParameterExtraction(t *testing.T) {
-	router := setupTestRouter()
+	t.Parallel()
+	router := newTestApp(t, testConfig(), setupTestDatabase(t)).router
 
 	testCases := []struct {
 		name           string
@@ -377,12 +507,13 @@ func TestOrderParameterExtraction(t *testing.T) {
 }
 
 func TestJSONResponseFormat(t *testing.T) {
-	setupTestCache()
-	router := setupTestRouter()
+	t.Parallel()
+	app := newTestApp(t, testConfig(), nil)
+	router := app.router
 
 	// Add order to cache
 	order := createTestOrderForCache()
-	kafka.OrderCache.Set(order.OrderUID, order)
+	app.cache.Set(order.OrderUID, order)
 
 	t.Run("response has correct JSON structure", func(t *testing.T) {
 		req, _ := http.NewRequest("GET", "/order/"+order.OrderUID, nil)
@@ -432,4 +563,72 @@ func TestJSONResponseFormat(t *testing.T) {
 			t.Error("Items should be an array")
 		}
 	})
-}
\ No newline at end of file
+}
+
+func TestGetOrderConcurrentMutations(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t, testConfig(), nil)
+	router := app.router
+
+	// Обработчик, который изменяет полученный из кэша заказ
+	router.GET("/mutate/:order_uid", func(c *gin.Context) {
+		order, found := app.cache.Get(c.Param("order_uid"))
+		if !found {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		order.TrackNumber = "mutated"
+		order.Items = append(order.Items[:0], models.Item{Name: "mutated"})
+		c.Status(http.StatusNoContent)
+	})
+
+	order := createTestOrderForCache()
+	app.cache.Set(order.OrderUID, order)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(3)
+
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest("GET", "/mutate/"+order.OrderUID, nil)
+				router.ServeHTTP(w, req)
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest("GET", "/order/"+order.OrderUID, nil)
+				router.ServeHTTP(w, req)
+
+				var response models.Order
+				if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+					t.Errorf("Failed to unmarshal response: %v", err)
+					return
+				}
+				if response.TrackNumber != order.TrackNumber {
+					t.Errorf("Expected TrackNumber %s, got %s", order.TrackNumber, response.TrackNumber)
+					return
+				}
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+			update := createTestOrderForCache()
+			for j := 0; j < 50; j++ {
+				app.cache.Set(update.OrderUID, update)
+			}
+		}()
+	}
+	wg.Wait()
+
+	cached, _ := app.cache.Get(order.OrderUID)
+	if cached.TrackNumber != order.TrackNumber || len(cached.Items) != len(order.Items) {
+		t.Errorf("Cached order was corrupted by handler mutations: %+v", cached)
+	}
+}
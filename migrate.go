@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"text/tabwriter"
+	"wb-service/config"
+	"wb-service/database"
+	"wb-service/internal/migrate"
+	"wb-service/migrations"
+
+	"gorm.io/gorm"
+)
+
+// migrateUsage описывает подкоманду migrate
+const migrateUsage = `usage: wb-service migrate <command>
+
+commands:
+  up            apply all pending migrations
+  down [n]      revert the last n migrations (default 1)
+  to <version>  migrate up or down to the given version (0 reverts everything)
+  status        show applied and pending migrations
+  version       show the current schema version`
+
+// newMigrator создает Migrator с миграциями для диалекта подключенной базы данных
+func newMigrator(db *gorm.DB) (*migrate.Migrator, error) {
+	list, err := migrations.For(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	m := migrate.New(sqlDB, list)
+	if db.Dialector.Name() == "postgres" {
+		// Реплики с DB_AUTO_MIGRATE=true могут запуститься одновременно
+		m.WithAdvisoryLock()
+	}
+	return m, nil
+}
+
+// runMigrate подключается к базе данных и выполняет подкоманду migrate
+func runMigrate(cfg *config.Config, args []string) error {
+	ctx := context.Background()
+
+	db, err := database.Open(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	m, err := newMigrator(db)
+	if err != nil {
+		return err
+	}
+	return runMigrateCommand(ctx, m, args, log.Writer())
+}
+
+// runMigrateCommand выполняет команду migrate и пишет результат в out
+func runMigrateCommand(ctx context.Context, m *migrate.Migrator, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	var (
+		done []migrate.Migration
+		err  error
+	)
+
+	switch args[0] {
+	case "up":
+		done, err = m.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		done, err = m.Down(ctx, steps)
+	case "to":
+		if len(args) < 2 {
+			return errors.New(migrateUsage)
+		}
+		target, parseErr := strconv.ParseInt(args[1], 10, 64)
+		if parseErr != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		done, err = m.To(ctx, target)
+	case "status":
+		return printMigrationStatus(ctx, m, out)
+	case "version":
+		version, err := m.Version(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Версия схемы: %d (последняя: %d)\n", version, m.Latest())
+		return nil
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	if err != nil {
+		return err
+	}
+	if len(done) == 0 {
+		fmt.Fprintln(out, "Схема уже в нужной версии")
+		return nil
+	}
+
+	version, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Выполнено миграций: %d, версия схемы: %d\n", len(done), version)
+	return nil
+}
+
+// printMigrationStatus выводит таблицу примененных и ожидающих миграций
+func printMigrationStatus(ctx context.Context, m *migrate.Migrator, out io.Writer) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, status := range statuses {
+		state, appliedAt := "pending", ""
+		if status.Applied {
+			state, appliedAt = "applied", status.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+	}
+	return w.Flush()
+}
+
+// checkSchema проверяет при запуске, что схема БД в ожидаемой версии.
+// С DB_AUTO_MIGRATE=true недостающие миграции применяются автоматически.
+func checkSchema(cfg *config.Config, db *gorm.DB) error {
+	m, err := newMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if cfg.Database.AutoMigrate {
+		if _, err := m.Up(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err := m.Check(ctx); err != nil {
+		if errors.Is(err, migrate.ErrSchemaOutdated) {
+			return fmt.Errorf("%w (выполните `wb-service migrate up` или задайте DB_AUTO_MIGRATE=true)", err)
+		}
+		return err
+	}
+
+	log.Printf("Схема базы данных в версии %d", m.Latest())
+	return nil
+}
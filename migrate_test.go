@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"wb-service/config"
+	"wb-service/internal/migrate"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupEmptyDatabase подключает пустую SQLite базу без схемы
+func setupEmptyDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+func TestRunMigrateCommand(t *testing.T) {
+	t.Parallel()
+	db := setupEmptyDatabase(t)
+	ctx := context.Background()
+
+	m, err := newMigrator(db)
+	if err != nil {
+		t.Fatalf("newMigrator failed: %v", err)
+	}
+
+	run := func(args ...string) string {
+		t.Helper()
+		var out bytes.Buffer
+		if err := runMigrateCommand(ctx, m, args, &out); err != nil {
+			t.Fatalf("migrate %v failed: %v", args, err)
+		}
+		return out.String()
+	}
+
+	if out := run("status"); !strings.Contains(out, "pending") || strings.Contains(out, "applied") {
+		t.Errorf("Expected only pending migrations, got:\n%s", out)
+	}
+
+	run("up")
+	if version, _ := m.Version(ctx); version != m.Latest() {
+		t.Errorf("Expected version %d after up, got %d", m.Latest(), version)
+	}
+	if out := run("up"); !strings.Contains(out, "уже") {
+		t.Errorf("Expected no-op message, got %q", out)
+	}
+	if out := run("status"); strings.Contains(out, "pending") {
+		t.Errorf("Expected all migrations applied, got:\n%s", out)
+	}
+
+	run("down")
+	if version, _ := m.Version(ctx); version != m.Latest()-1 {
+		t.Errorf("Expected version %d after down, got %d", m.Latest()-1, version)
+	}
+
+	run("to", "0")
+	if out := run("version"); !strings.Contains(out, "Версия схемы: 0") {
+		t.Errorf("Expected version 0, got %q", out)
+	}
+
+	for _, args := range [][]string{nil, {"sideways"}, {"down", "zero"}, {"to"}, {"to", "x"}} {
+		if err := runMigrateCommand(ctx, m, args, &bytes.Buffer{}); err == nil {
+			t.Errorf("Expected error for migrate %v", args)
+		}
+	}
+	if err := runMigrateCommand(ctx, m, []string{"to", "999"}, &bytes.Buffer{}); !errors.Is(err, migrate.ErrUnknownVersion) {
+		t.Errorf("Expected ErrUnknownVersion, got %v", err)
+	}
+}
+
+func TestCheckSchema(t *testing.T) {
+	t.Run("outdated schema fails startup", func(t *testing.T) {
+		db := setupEmptyDatabase(t)
+
+		err := checkSchema(&config.Config{}, db)
+		if !errors.Is(err, migrate.ErrSchemaOutdated) {
+			t.Errorf("Expected ErrSchemaOutdated, got %v", err)
+		}
+	})
+
+	t.Run("auto migrate applies pending migrations", func(t *testing.T) {
+		db := setupEmptyDatabase(t)
+
+		cfg := &config.Config{Database: config.DatabaseConfig{AutoMigrate: true}}
+		if err := checkSchema(cfg, db); err != nil {
+			t.Fatalf("Expected schema to be migrated, got %v", err)
+		}
+
+		// После миграции схема совпадает с тем, что ожидает репозиторий
+		order := createTestOrderForDB()
+		if err := db.Create(order).Error; err != nil {
+			t.Errorf("Failed to create order in migrated schema: %v", err)
+		}
+
+		if err := checkSchema(&config.Config{}, db); err != nil {
+			t.Errorf("Expected up-to-date schema to pass the check, got %v", err)
+		}
+	})
+}
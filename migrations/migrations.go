@@ -0,0 +1,35 @@
+// Package migrations содержит версионированные миграции схемы базы данных,
+// встроенные в бинарный файл сервиса
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"wb-service/internal/migrate"
+)
+
+//go:embed postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+// Postgres возвращает миграции схемы PostgreSQL по возрастанию версий
+func Postgres() ([]migrate.Migration, error) {
+	return migrate.Load(files, "postgres")
+}
+
+// SQLite возвращает миграции схемы SQLite по возрастанию версий.
+// Версии и названия совпадают с миграциями PostgreSQL.
+func SQLite() ([]migrate.Migration, error) {
+	return migrate.Load(files, "sqlite")
+}
+
+// For возвращает миграции для диалекта GORM (gorm.Dialector.Name())
+func For(dialect string) ([]migrate.Migration, error) {
+	switch dialect {
+	case "postgres":
+		return Postgres()
+	case "sqlite":
+		return SQLite()
+	default:
+		return nil, fmt.Errorf("no migrations for database dialect %q", dialect)
+	}
+}
@@ -0,0 +1,210 @@
+package migrations
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"wb-service/internal/migrate"
+	"wb-service/models"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	createTable = regexp.MustCompile(`(?is)CREATE TABLE(?: IF NOT EXISTS)?\s+(\w+)\s*\((.*?)\n\);`)
+	addColumn   = regexp.MustCompile(`(?i)ALTER TABLE\s+(\w+)\s+ADD COLUMN(?: IF NOT EXISTS)?\s+(\w+)`)
+	dropColumn  = regexp.MustCompile(`(?i)ALTER TABLE\s+(\w+)\s+DROP COLUMN(?: IF EXISTS)?\s+(\w+)`)
+)
+
+// dialects - миграции всех поддерживаемых баз данных
+var dialects = []string{"postgres", "sqlite"}
+
+// schemaColumns собирает столбцы таблиц после применения всех up миграций
+func schemaColumns(t *testing.T, dialect string) map[string]map[string]bool {
+	t.Helper()
+
+	migrations, err := For(dialect)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+
+	tables := make(map[string]map[string]bool)
+	for _, m := range migrations {
+		for _, match := range createTable.FindAllStringSubmatch(m.Up, -1) {
+			columns := make(map[string]bool)
+			for _, line := range strings.Split(match[2], "\n") {
+				fields := strings.Fields(strings.TrimSpace(line))
+				if len(fields) == 0 || strings.HasPrefix(fields[0], "--") {
+					continue
+				}
+				switch strings.ToUpper(fields[0]) {
+				case "PRIMARY", "FOREIGN", "UNIQUE", "CONSTRAINT", "CHECK":
+					continue
+				}
+				columns[strings.ToLower(strings.Trim(fields[0], `"`))] = true
+			}
+			tables[strings.ToLower(match[1])] = columns
+		}
+		for _, match := range addColumn.FindAllStringSubmatch(m.Up, -1) {
+			tables[strings.ToLower(match[1])][strings.ToLower(match[2])] = true
+		}
+		for _, match := range dropColumn.FindAllStringSubmatch(m.Up, -1) {
+			delete(tables[strings.ToLower(match[1])], strings.ToLower(match[2]))
+		}
+	}
+	return tables
+}
+
+func TestMigrations(t *testing.T) {
+	for _, dialect := range dialects {
+		migrations, err := For(dialect)
+		if err != nil {
+			t.Fatalf("Failed to load %s migrations: %v", dialect, err)
+		}
+		if len(migrations) == 0 {
+			t.Fatalf("Expected embedded %s migrations", dialect)
+		}
+
+		for i, m := range migrations {
+			if m.Version != int64(i+1) {
+				t.Errorf("%s: expected contiguous versions, migration %d has version %d", dialect, i, m.Version)
+			}
+		}
+	}
+
+	if _, err := For("mysql"); err == nil {
+		t.Error("Expected error for unsupported dialect")
+	}
+}
+
+// TestDialectsInSync проверяет, что у всех диалектов одинаковый набор версий,
+// иначе версия схемы значила бы разное для разных баз
+func TestDialectsInSync(t *testing.T) {
+	postgres, err := Postgres()
+	if err != nil {
+		t.Fatalf("Failed to load postgres migrations: %v", err)
+	}
+	sqlite, err := SQLite()
+	if err != nil {
+		t.Fatalf("Failed to load sqlite migrations: %v", err)
+	}
+
+	if len(postgres) != len(sqlite) {
+		t.Fatalf("Expected the same number of migrations, postgres has %d, sqlite has %d", len(postgres), len(sqlite))
+	}
+	for i := range postgres {
+		if postgres[i].Version != sqlite[i].Version || postgres[i].Name != sqlite[i].Name {
+			t.Errorf("Migration %d differs: postgres %d_%s, sqlite %d_%s", i,
+				postgres[i].Version, postgres[i].Name, sqlite[i].Version, sqlite[i].Name)
+		}
+	}
+}
+
+// TestMigrationsMatchModels проверяет, что схема из миграций и GORM модели
+// описывают одни и те же столбцы
+func TestMigrationsMatchModels(t *testing.T) {
+	for _, dialect := range dialects {
+		t.Run(dialect, func(t *testing.T) {
+			testMigrationsMatchModels(t, schemaColumns(t, dialect))
+		})
+	}
+}
+
+func testMigrationsMatchModels(t *testing.T, tables map[string]map[string]bool) {
+	cache := &sync.Map{}
+
+	for _, model := range []any{&models.Order{}, &models.Delivery{}, &models.Payment{}, &models.Item{}, &models.AuditRecord{}} {
+		s, err := schema.Parse(model, cache, schema.NamingStrategy{})
+		if err != nil {
+			t.Fatalf("Failed to parse model %T: %v", model, err)
+		}
+
+		columns, ok := tables[s.Table]
+		if !ok {
+			t.Errorf("Table %s of model %T is not created by migrations", s.Table, model)
+			continue
+		}
+
+		modelColumns := make(map[string]bool)
+		for _, field := range s.Fields {
+			if field.DBName != "" {
+				modelColumns[field.DBName] = true
+			}
+		}
+
+		var missing, extra []string
+		for column := range modelColumns {
+			if !columns[column] {
+				missing = append(missing, column)
+			}
+		}
+		for column := range columns {
+			if !modelColumns[column] {
+				extra = append(extra, column)
+			}
+		}
+		sort.Strings(missing)
+		sort.Strings(extra)
+
+		if len(missing) > 0 {
+			t.Errorf("Table %s: columns of model %T missing in migrations: %v", s.Table, model, missing)
+		}
+		if len(extra) > 0 {
+			t.Errorf("Table %s: columns in migrations missing in model %T: %v", s.Table, model, extra)
+		}
+	}
+}
+
+// TestSQLiteMigrationsApply применяет и откатывает миграции SQLite
+// и проверяет, что в схеме работают модели
+func TestSQLiteMigrationsApply(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	defer sqlDB.Close()
+
+	list, err := SQLite()
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	m := migrate.New(sqlDB, list)
+	ctx := context.Background()
+
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	order := &models.Order{
+		OrderUID:    "sqlite_order",
+		DateCreated: time.Now(),
+		Delivery:    models.Delivery{Name: "SQLite User"},
+		Payment:     models.Payment{Transaction: "sqlite_txn", Amount: 100},
+		Items:       []models.Item{{ChrtID: 1, Name: "Item"}},
+	}
+	if err := db.Create(order).Error; err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+
+	var loaded models.Order
+	err = db.Preload("Delivery").Preload("Payment").Preload("Items").
+		First(&loaded, "order_uid = ?", order.OrderUID).Error
+	if err != nil {
+		t.Fatalf("Failed to load order: %v", err)
+	}
+	if loaded.Payment.Transaction != "sqlite_txn" || len(loaded.Items) != 1 || loaded.DateCreated.IsZero() {
+		t.Errorf("Unexpected order: %+v", loaded)
+	}
+
+	if _, err := m.To(ctx, 0); err != nil {
+		t.Fatalf("Reverting all migrations failed: %v", err)
+	}
+}
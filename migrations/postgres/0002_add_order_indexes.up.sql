@@ -0,0 +1,7 @@
+-- Связанные таблицы читаются по order_uid при каждой загрузке заказа
+CREATE INDEX IF NOT EXISTS idx_deliveries_order_uid ON deliveries (order_uid);
+CREATE INDEX IF NOT EXISTS idx_payments_order_uid ON payments (order_uid);
+CREATE INDEX IF NOT EXISTS idx_items_order_uid ON items (order_uid);
+
+-- Прогрев кэша читает самые свежие заказы (GetRecentOrders)
+CREATE INDEX IF NOT EXISTS idx_orders_date_created ON orders (date_created DESC, order_uid);
@@ -0,0 +1,64 @@
+-- Начальная схема для SQLite: те же таблицы и столбцы, что и в PostgreSQL,
+-- с типами SQLite. Даты хранятся в столбцах TIMESTAMP, чтобы драйвер
+-- возвращал их как time.Time.
+
+-- Таблица для хранения информации о заказах
+CREATE TABLE IF NOT EXISTS orders (
+    order_uid TEXT PRIMARY KEY,
+    track_number TEXT,
+    entry TEXT,
+    locale TEXT,
+    internal_signature TEXT,
+    customer_id TEXT,
+    delivery_service TEXT,
+    shardkey TEXT,
+    sm_id INTEGER,
+    date_created TIMESTAMP,
+    oof_shard TEXT
+);
+
+-- Таблица для информации о доставке, связанная с заказом
+CREATE TABLE IF NOT EXISTS deliveries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_uid TEXT REFERENCES orders(order_uid) ON DELETE CASCADE,
+    name TEXT,
+    phone TEXT,
+    zip TEXT,
+    city TEXT,
+    address TEXT,
+    region TEXT,
+    email TEXT
+);
+
+-- Таблица для информации об оплате, связанная с заказом
+CREATE TABLE IF NOT EXISTS payments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_uid TEXT REFERENCES orders(order_uid) ON DELETE CASCADE,
+    "transaction" TEXT,
+    request_id TEXT,
+    currency TEXT,
+    provider TEXT,
+    amount INTEGER,
+    payment_dt INTEGER,
+    bank TEXT,
+    delivery_cost INTEGER,
+    goods_total INTEGER,
+    custom_fee INTEGER
+);
+
+-- Таблица для товаров в заказе, связанная с заказом
+CREATE TABLE IF NOT EXISTS items (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_uid TEXT REFERENCES orders(order_uid) ON DELETE CASCADE,
+    chrt_id INTEGER,
+    track_number TEXT,
+    price INTEGER,
+    rid TEXT,
+    name TEXT,
+    sale INTEGER,
+    size TEXT,
+    total_price INTEGER,
+    nm_id INTEGER,
+    brand TEXT,
+    status INTEGER
+);
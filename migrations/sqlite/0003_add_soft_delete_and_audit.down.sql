@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS audit_records;
+DROP INDEX IF EXISTS idx_orders_customer_id;
+DROP INDEX IF EXISTS idx_orders_deleted_at;
+ALTER TABLE orders DROP COLUMN deleted_at;
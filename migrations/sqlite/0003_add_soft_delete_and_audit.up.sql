@@ -0,0 +1,16 @@
+-- Мягкое удаление заказов: удаленные заказы не отдаются, но хранятся
+-- до очистки по сроку хранения
+ALTER TABLE orders ADD COLUMN deleted_at TIMESTAMP;
+CREATE INDEX IF NOT EXISTS idx_orders_deleted_at ON orders (deleted_at);
+
+-- Обезличивание данных клиента ищет все его заказы
+CREATE INDEX IF NOT EXISTS idx_orders_customer_id ON orders (customer_id);
+
+-- Журнал удаления заказов и обезличивания данных клиентов
+CREATE TABLE IF NOT EXISTS audit_records (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    action TEXT NOT NULL,
+    subject TEXT,
+    orders INTEGER,
+    created_at TIMESTAMP
+);
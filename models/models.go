@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 // Order представляет главную структуру заказа
 type Order struct {
@@ -18,6 +22,10 @@ type Order struct {
 	SmID              int       `json:"sm_id"`
 	DateCreated       time.Time `json:"date_created"`
 	OofShard          string    `json:"oof_shard"`
+
+	// DeletedAt - время мягкого удаления; удаленные заказы GORM
+	// не возвращает, но данные остаются в БД до очистки по сроку хранения
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // Delivery информация о доставке
@@ -65,3 +73,27 @@ type Item struct {
 	Brand       string `json:"brand"`
 	Status      int    `json:"status"`
 }
+
+// AuditRecord запись журнала удаления заказов и обезличивания данных клиентов
+type AuditRecord struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Action    string    `json:"action"`  // soft_delete, erase_customer или purge
+	Subject   string    `json:"subject"` // UID заказа или псевдоним клиента
+	Orders    int       `json:"orders"`  // количество затронутых заказов
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Clone возвращает глубокую копию заказа: изменение копии, включая
+// элементы Items, не затрагивает исходный заказ
+func (o *Order) Clone() *Order {
+	if o == nil {
+		return nil
+	}
+
+	clone := *o
+	if o.Items != nil {
+		clone.Items = make([]Item, len(o.Items))
+		copy(clone.Items, o.Items)
+	}
+	return &clone
+}
@@ -282,4 +282,49 @@ func TestModelFieldTags(t *testing.T) {
 			// OK - поле инициализировано нулем
 		}
 	})
-}
\ No newline at end of file
+}
+
+func TestOrderClone(t *testing.T) {
+	t.Run("nil order", func(t *testing.T) {
+		var order *Order
+		if order.Clone() != nil {
+			t.Error("Expected nil clone of nil order")
+		}
+	})
+
+	t.Run("clone is independent of original", func(t *testing.T) {
+		order := &Order{
+			OrderUID: "clone_order",
+			Delivery: Delivery{Name: "Original"},
+			Payment:  Payment{Amount: 100},
+			Items:    []Item{{ChrtID: 1, Name: "Item"}},
+		}
+
+		clone := order.Clone()
+		clone.OrderUID = "changed"
+		clone.Delivery.Name = "Changed"
+		clone.Payment.Amount = 200
+		clone.Items[0].Name = "Changed"
+		clone.Items = append(clone.Items, Item{ChrtID: 2})
+
+		if order.OrderUID != "clone_order" {
+			t.Errorf("Expected OrderUID to stay 'clone_order', got '%s'", order.OrderUID)
+		}
+		if order.Delivery.Name != "Original" {
+			t.Errorf("Expected delivery name to stay 'Original', got '%s'", order.Delivery.Name)
+		}
+		if order.Payment.Amount != 100 {
+			t.Errorf("Expected payment amount to stay 100, got %d", order.Payment.Amount)
+		}
+		if len(order.Items) != 1 || order.Items[0].Name != "Item" {
+			t.Errorf("Expected items to stay unchanged, got %+v", order.Items)
+		}
+	})
+
+	t.Run("nil items stay nil", func(t *testing.T) {
+		order := &Order{OrderUID: "no_items"}
+		if clone := order.Clone(); clone.Items != nil {
+			t.Errorf("Expected nil items, got %+v", clone.Items)
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+	"wb-service/internal/apierror"
+	"wb-service/internal/auth"
+	"wb-service/internal/interfaces"
+	"wb-service/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// registerPrivacyRoutes добавляет маршруты удаления заказов и обезличивания
+// данных клиентов. Удалить заказ может support, обезличить клиента - admin.
+func (a *App) registerPrivacyRoutes(r *gin.RouterGroup) {
+	r.DELETE("/orders/:order_uid", a.require(auth.RoleSupport), a.rateLimit(ratelimit.GroupAdmin), a.deleteOrder)
+	r.POST("/admin/customers/:customer_id/erase", a.require(auth.RoleAdmin), a.rateLimit(ratelimit.GroupAdmin), a.eraseCustomer)
+}
+
+// eraser возвращает репозиторий с поддержкой удаления или отвечает ошибкой
+func (a *App) eraser(c *gin.Context) (interfaces.OrderEraser, bool) {
+	if a.repo == nil {
+		apierror.Abort(c, http.StatusInternalServerError, apierror.CodeDatabaseUnavailable, "database not initialized")
+		return nil, false
+	}
+
+	eraser, ok := a.repo.(interfaces.OrderEraser)
+	if !ok {
+		apierror.Abort(c, http.StatusNotImplemented, apierror.CodeNotImplemented, "order deletion not supported")
+		return nil, false
+	}
+	return eraser, true
+}
+
+// deleteOrder мягко удаляет заказ и убирает его из кэша
+func (a *App) deleteOrder(c *gin.Context) {
+	eraser, ok := a.eraser(c)
+	if !ok {
+		return
+	}
+
+	orderUID := c.Param("order_uid")
+	if err := eraser.DeleteOrder(orderUID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			apierror.AbortWithDetails(c, http.StatusNotFound, apierror.CodeNotFound, "record not found",
+				gin.H{"order_uid": orderUID})
+			return
+		}
+		log.Printf("Ошибка удаления заказа %s: %v", orderUID, err)
+		apierror.Abort(c, http.StatusInternalServerError, apierror.CodeDatabaseError, "database error")
+		return
+	}
+
+	// Загрузка из БД, прочитавшая заказ до удаления, не вернет его в кэш:
+	// Delete отменяет запись ее результата
+	a.cache.Delete(orderUID)
+
+	log.Printf("Заказ %s удален", orderUID)
+	c.JSON(http.StatusOK, gin.H{"status": "deleted", "order_uid": orderUID})
+}
+
+// eraseCustomer обезличивает данные клиента во всех его заказах и убирает
+// эти заказы из кэша. Идентификатор клиента в лог не пишется.
+func (a *App) eraseCustomer(c *gin.Context) {
+	eraser, ok := a.eraser(c)
+	if !ok {
+		return
+	}
+
+	uids, err := eraser.EraseCustomer(c.Param("customer_id"))
+	if err != nil {
+		log.Printf("Ошибка обезличивания данных клиента: %v", err)
+		apierror.Abort(c, http.StatusInternalServerError, apierror.CodeDatabaseError, "database error")
+		return
+	}
+
+	for _, uid := range uids {
+		a.cache.Delete(uid)
+	}
+
+	log.Printf("Данные клиента обезличены в %d заказах", len(uids))
+	c.JSON(http.StatusOK, gin.H{"status": "erased", "orders": len(uids)})
+}
+
+// startRetention запускает периодическую очистку заказов старше
+// DB_RETENTION_DAYS. Первая очистка выполняется сразу. Возвращает канал,
+// который закрывается после остановки очистки отменой ctx, или nil,
+// если очистка не нужна.
+func (a *App) startRetention(ctx context.Context) <-chan struct{} {
+	db := a.cfg.Database
+	interval := time.Duration(db.RetentionInterval) * time.Second
+	if db.RetentionDays <= 0 || interval <= 0 {
+		return nil
+	}
+
+	eraser, ok := a.repo.(interfaces.OrderEraser)
+	if !ok {
+		log.Println("Репозиторий не поддерживает удаление заказов, очистка по сроку хранения отключена")
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			a.purgeExpiredOrders(ctx, eraser)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return done
+}
+
+// purgeExpiredOrders удаляет заказы старше срока хранения пачками по
+// DB_RETENTION_BATCH_SIZE, чтобы не держать долгих блокировок, и убирает
+// их из кэша
+func (a *App) purgeExpiredOrders(ctx context.Context, eraser interfaces.OrderEraser) {
+	before := time.Now().AddDate(0, 0, -a.cfg.Database.RetentionDays)
+	batch := a.cfg.Database.RetentionBatchSize
+
+	total := 0
+	for ctx.Err() == nil {
+		uids, err := eraser.PurgeOrders(before, batch)
+		if err != nil {
+			log.Printf("Ошибка очистки устаревших заказов: %v", err)
+			break
+		}
+
+		for _, uid := range uids {
+			a.cache.Delete(uid)
+		}
+		total += len(uids)
+
+		if batch <= 0 || len(uids) < batch {
+			break
+		}
+	}
+
+	if total > 0 {
+		log.Printf("Удалено %d заказов старше %d дней", total, a.cfg.Database.RetentionDays)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"wb-service/internal/repository"
+	"wb-service/models"
+)
+
+func TestDeleteOrder(t *testing.T) {
+	t.Parallel()
+	db := setupTestDatabase(t)
+	app := newTestApp(t, testConfig(), db)
+
+	order := createTestOrderForDB()
+	if err := db.Create(order).Error; err != nil {
+		t.Fatalf("Failed to create test order: %v", err)
+	}
+	app.cache.Set(order.OrderUID, order)
+
+	t.Run("delete existing order", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("DELETE", "/orders/"+order.OrderUID, nil)
+		app.router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		if _, found := app.cache.Get(order.OrderUID); found {
+			t.Error("Expected order to be removed from cache")
+		}
+
+		// Удаленный заказ больше не отдается
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/order/"+order.OrderUID, nil)
+		app.router.ServeHTTP(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d for deleted order, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+
+	t.Run("delete missing order", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("DELETE", "/orders/"+order.OrderUID, nil)
+		app.router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+}
+
+func TestDeleteOrderWithoutDatabase(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t, testConfig(), nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/orders/any", nil)
+	app.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+}
+
+func TestEraseCustomer(t *testing.T) {
+	t.Parallel()
+	db := setupTestDatabase(t)
+	app := newTestApp(t, testConfig(), db)
+
+	order := createTestOrderForDB()
+	if err := db.Create(order).Error; err != nil {
+		t.Fatalf("Failed to create test order: %v", err)
+	}
+	app.cache.Set(order.OrderUID, order)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/admin/customers/"+order.CustomerID+"/erase", nil)
+	app.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var response struct {
+		Orders int `json:"orders"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || response.Orders != 1 {
+		t.Errorf("Expected 1 erased order, got %s", w.Body.String())
+	}
+
+	// Закэшированная копия с персональными данными удалена
+	if _, found := app.cache.Get(order.OrderUID); found {
+		t.Error("Expected order to be removed from cache")
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/order/"+order.OrderUID, nil)
+	app.router.ServeHTTP(w, req)
+
+	var erased models.Order
+	if err := json.Unmarshal(w.Body.Bytes(), &erased); err != nil {
+		t.Fatalf("Failed to unmarshal order: %v", err)
+	}
+	if erased.Delivery.Name != repository.ErasedValue || erased.CustomerID == order.CustomerID {
+		t.Errorf("Expected anonymized order, got %+v", erased)
+	}
+
+	var audit models.AuditRecord
+	if err := db.First(&audit).Error; err != nil || audit.Action != repository.AuditEraseCustomer {
+		t.Errorf("Expected audit record, got %+v, %v", audit, err)
+	}
+}
+
+func TestPurgeExpiredOrders(t *testing.T) {
+	t.Parallel()
+	db := setupTestDatabase(t)
+
+	cfg := testConfig()
+	cfg.Database.RetentionDays = 1
+	cfg.Database.RetentionInterval = 3600
+	cfg.Database.RetentionBatchSize = 2
+	app := newTestApp(t, cfg, db)
+
+	// Пять устаревших заказов удаляются за три пачки, свежий остается
+	for i, age := range []time.Duration{48, 49, 50, 51, 52, 1} {
+		order := createTestOrderForDB()
+		order.OrderUID = "purge_order_" + string(rune('a'+i))
+		order.DateCreated = time.Now().Add(-age * time.Hour)
+		if err := db.Create(order).Error; err != nil {
+			t.Fatalf("Failed to create test order: %v", err)
+		}
+		app.cache.Set(order.OrderUID, order)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := app.startRetention(ctx)
+	if done == nil {
+		t.Fatal("Expected retention to start")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for app.cache.Size() != 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	var count int64
+	db.Unscoped().Model(&models.Order{}).Count(&count)
+	if count != 1 {
+		t.Errorf("Expected only the recent order to stay, got %d", count)
+	}
+	if _, found := app.cache.Get("purge_order_f"); !found {
+		t.Error("Expected recent order to stay in cache")
+	}
+
+	var batches int64
+	db.Model(&models.AuditRecord{}).Where("action = ?", repository.AuditPurge).Count(&batches)
+	if batches != 3 {
+		t.Errorf("Expected 3 purge batches, got %d", batches)
+	}
+}
+
+func TestRetentionDisabled(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t, testConfig(), setupTestDatabase(t))
+
+	if done := app.startRetention(context.Background()); done != nil {
+		t.Error("Expected retention to be disabled by default")
+	}
+}
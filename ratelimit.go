@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"wb-service/config"
+	"wb-service/internal/auth"
+	"wb-service/internal/interfaces"
+	"wb-service/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// Хранилища корзин ограничителя запросов (RATE_LIMIT_BACKEND)
+const (
+	rateLimitMemory = "memory"
+	rateLimitRedis  = "redis"
+)
+
+// newRateLimiter создает ограничитель запросов по конфигурации. Если Redis
+// недоступен, корзины хранятся в памяти процесса.
+func newRateLimiter(cfg *config.Config) *ratelimit.Middleware {
+	switch strings.ToLower(cfg.RateLimit.Backend) {
+	case rateLimitRedis:
+		client := redis.NewClient(&redis.Options{
+			Addr:     cfg.Redis.Addr,
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := client.Ping(ctx).Err(); err != nil {
+			client.Close()
+			log.Printf("Redis недоступен (%v), лимиты запросов хранятся в памяти", err)
+			break
+		}
+
+		log.Printf("Лимиты запросов хранятся в Redis %s", cfg.Redis.Addr)
+		limiter := ratelimit.NewRedisLimiter(client, ratelimit.RedisOptions{Prefix: cfg.RateLimit.KeyPrefix})
+		return ratelimit.NewMiddleware(limiter, rateLimitRedis)
+	case rateLimitMemory, "":
+	default:
+		log.Printf("Неизвестное хранилище лимитов %q, используется память", cfg.RateLimit.Backend)
+	}
+
+	return ratelimit.NewMiddleware(ratelimit.NewMemoryLimiter(context.Background()), rateLimitMemory)
+}
+
+// rateLimitFor переводит лимит из конфигурации в параметры корзины
+func rateLimitFor(limit config.RateLimit) interfaces.RateLimit {
+	return interfaces.RateLimit{Rate: float64(limit.PerMinute) / 60, Burst: limit.Burst}
+}
+
+// rateLimit возвращает middleware ограничения группы маршрутов. Если
+// ограничение отключено (RATE_LIMIT_ENABLED=false), пропускает все запросы.
+func (a *App) rateLimit(group string) gin.HandlerFunc {
+	if a.limits == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	limit := a.cfg.RateLimit.Orders
+	switch group {
+	case ratelimit.GroupAdmin:
+		limit = a.cfg.RateLimit.Admin
+	case ratelimit.GroupAuth:
+		limit = a.cfg.RateLimit.Auth
+	}
+	return a.limits.Limit(group, rateLimitFor(limit))
+}
+
+// authRateLimit возвращает middleware, которое ограничивает запросы с
+// одного IP до проверки учетных данных. Лимиты остальных групп стоят после
+// аутентификации и не видят ответов 401 и 403, поэтому без него подбор API
+// ключей не ограничен. Если аутентификация отключена, все корзины и так
+// считаются по IP и middleware пропускает все запросы.
+func (a *App) authRateLimit() gin.HandlerFunc {
+	if a.authn == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return a.rateLimit(ratelimit.GroupAuth)
+}
+
+// registerRateLimitRoutes добавляет маршрут статистики ограничителя запросов
+func (a *App) registerRateLimitRoutes(r *gin.RouterGroup) {
+	r.GET("/admin/ratelimit/stats", a.require(auth.RoleSupport), a.rateLimit(ratelimit.GroupAdmin), a.getRateLimitStats)
+}
+
+// getRateLimitStats возвращает счетчики разрешенных и отклоненных
+// запросов по группам маршрутов
+func (a *App) getRateLimitStats(c *gin.Context) {
+	if a.limits == nil {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled": true,
+		"backend": a.limits.Backend(),
+		"groups":  a.limits.Stats(),
+	})
+}
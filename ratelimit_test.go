@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wb-service/config"
+	"wb-service/internal/ratelimit"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func rateLimitConfig() *config.Config {
+	cfg := testConfig()
+	cfg.RateLimit = config.RateLimitConfig{
+		Enabled: true,
+		Backend: "memory",
+		Orders:  config.RateLimit{PerMinute: 60, Burst: 2},
+		Admin:   config.RateLimit{PerMinute: 60, Burst: 5},
+	}
+	return cfg
+}
+
+func TestGetOrderRateLimited(t *testing.T) {
+	app := newTestApp(t, rateLimitConfig(), nil)
+	order := createTestOrderForCache()
+	app.cache.Set(order.OrderUID, order)
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("GET", "/order/"+order.OrderUID, nil)
+		w := httptest.NewRecorder()
+		app.router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected 200, got %d", i+1, w.Code)
+		}
+	}
+
+	req, _ := http.NewRequest("GET", "/order/"+order.OrderUID, nil)
+	w := httptest.NewRecorder()
+	app.router.ServeHTTP(w, req)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "1" {
+		t.Errorf("Expected Retry-After 1, got %q", w.Header().Get("Retry-After"))
+	}
+
+	// Статистика считается отдельно, лимит служебных маршрутов свой
+	req, _ = http.NewRequest("GET", "/admin/ratelimit/stats", nil)
+	w = httptest.NewRecorder()
+	app.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected stats status 200, got %d", w.Code)
+	}
+
+	var response struct {
+		Backend string                     `json:"backend"`
+		Groups  map[string]ratelimit.Stats `json:"groups"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal stats: %v", err)
+	}
+	if response.Backend != "memory" {
+		t.Errorf("Expected memory backend, got %q", response.Backend)
+	}
+	if orders := response.Groups[ratelimit.GroupOrders]; orders.Allowed != 2 || orders.Limited != 1 {
+		t.Errorf("Unexpected orders stats: %+v", orders)
+	}
+	if admin := response.Groups[ratelimit.GroupAdmin]; admin.Burst != 5 || admin.Allowed != 1 {
+		t.Errorf("Unexpected admin stats: %+v", admin)
+	}
+}
+
+func TestAuthFailuresRateLimited(t *testing.T) {
+	cfg := rateLimitConfig()
+	cfg.Auth = config.AuthConfig{Enabled: true, APIKeys: []string{"good-key=viewer"}}
+	cfg.RateLimit.Auth = config.RateLimit{PerMinute: 60, Burst: 3}
+	app := newTestApp(t, cfg, nil)
+	order := createTestOrderForCache()
+	app.cache.Set(order.OrderUID, order)
+
+	request := func(key, remoteAddr string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/api/v1/orders/"+order.OrderUID, nil)
+		req.Header.Set("X-API-Key", key)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		app.router.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < 3; i++ {
+		if w := request("bad-key", "192.0.2.1:1234"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("Request %d: expected 401, got %d", i+1, w.Code)
+		}
+	}
+
+	w := request("bad-key", "192.0.2.1:1234")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 after repeated bad keys, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("Expected Retry-After header")
+	}
+
+	// Корзина своя у каждого IP
+	if w := request("good-key", "192.0.2.2:1234"); w.Code != http.StatusOK {
+		t.Errorf("Expected 200 for valid key from another IP, got %d", w.Code)
+	}
+}
+
+func TestRateLimitStatsDisabled(t *testing.T) {
+	app := newTestApp(t, testConfig(), nil)
+
+	req, _ := http.NewRequest("GET", "/admin/ratelimit/stats", nil)
+	w := httptest.NewRecorder()
+	app.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || w.Body.String() != `{"enabled":false}` {
+		t.Errorf("Unexpected response: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestNewRateLimiter(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	tests := []struct {
+		name     string
+		backend  string
+		addr     string
+		expected string
+	}{
+		{"memory", "memory", server.Addr(), "memory"},
+		{"redis", "redis", server.Addr(), "redis"},
+		{"redis unavailable", "redis", "127.0.0.1:1", "memory"},
+		{"unknown", "memcached", server.Addr(), "memory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := rateLimitConfig()
+			cfg.RateLimit.Backend = tt.backend
+			cfg.Redis.Addr = tt.addr
+
+			limits := newRateLimiter(cfg)
+			defer limits.Close()
+
+			if limits.Backend() != tt.expected {
+				t.Errorf("Expected backend %q, got %q", tt.expected, limits.Backend())
+			}
+		})
+	}
+}
+
+func TestNewAppInvalidTrustedProxies(t *testing.T) {
+	cfg := testConfig()
+	cfg.Server.TrustedProxies = []string{"not-an-address"}
+
+	if _, err := NewApp(cfg, nil); err == nil {
+		t.Error("Expected NewApp to fail for invalid trusted proxy")
+	}
+}